@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"green-api/internal/auth"
+)
+
+// apikey generates a bootstrap API key. The token is printed once to stderr;
+// stdout receives the auth.bootstrap_keys entry to paste into config.
+func main() {
+	name := flag.String("name", "admin", "key name")
+	scopes := flag.String("scopes", string(auth.ScopeAdminKeys), "comma-separated scopes")
+	flag.Parse()
+
+	parsed, err := auth.ParseScopes(strings.Split(*scopes, ","))
+	if err != nil {
+		log.Fatalf("scopes: %v", err)
+	}
+
+	id, token, hash, err := auth.NewToken()
+	if err != nil {
+		log.Fatalf("generate key: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "token (shown once): %s\n", token)
+	fmt.Printf("- id: %q\n  name: %q\n  hash: %q\n  scopes:\n", id, *name, hash)
+	for _, scope := range parsed {
+		fmt.Printf("    - %s\n", scope)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"green-api/internal/app"
+	"green-api/internal/config"
+	"green-api/internal/credstore"
+	"green-api/internal/instance"
+)
+
+const usage = `usage: credstore <command> [flags]
+
+commands:
+  generate-key            print a new base64 master key
+  list                    list stored instances (names and idInstance only)
+  put -name N -id ID      store credentials; the token is read from stdin
+  delete -name N          remove stored credentials
+  rotate-key              re-encrypt all records with the primary master key
+
+The store and master keys are taken from instances.store in $APP_CONFIG
+(default config/config.yaml).`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	if command == "generate-key" {
+		key, err := credstore.GenerateKey()
+		if err != nil {
+			log.Fatalf("generate key: %v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	store, err := openStore()
+	if err != nil {
+		log.Fatalf("open store: %v", err)
+	}
+
+	switch command {
+	case "list":
+		list(store)
+	case "put":
+		put(store, args)
+	case "delete":
+		remove(store, args)
+	case "rotate-key":
+		rotated, err := store.Rotate()
+		if err != nil {
+			log.Fatalf("rotate key: %v", err)
+		}
+		fmt.Printf("re-encrypted %d records\n", rotated)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func openStore() (*credstore.Store, error) {
+	configPath := os.Getenv("APP_CONFIG")
+	if configPath == "" {
+		configPath = "config/config.yaml"
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Instances.Store.Path == "" {
+		return nil, fmt.Errorf("instances.store.path is not configured")
+	}
+	return app.OpenCredentialStore(cfg.Instances.Store)
+}
+
+func list(store *credstore.Store) {
+	summaries, err := store.List(context.Background())
+	if err != nil {
+		log.Fatalf("list: %v", err)
+	}
+	for _, summary := range summaries {
+		fmt.Printf("%s\t%s\n", summary.Name, summary.IDInstance)
+	}
+}
+
+func put(store *credstore.Store, args []string) {
+	flags := flag.NewFlagSet("put", flag.ExitOnError)
+	name := flags.String("name", "", "instance name")
+	idInstance := flags.String("id", "", "idInstance")
+	_ = flags.Parse(args)
+
+	fmt.Fprint(os.Stderr, "apiTokenInstance: ")
+	token, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && token == "" {
+		log.Fatalf("read token: %v", err)
+	}
+
+	err = store.Put(*name, instance.Credentials{
+		IDInstance:       strings.TrimSpace(*idInstance),
+		APITokenInstance: strings.TrimSpace(token),
+	})
+	if err != nil {
+		log.Fatalf("put: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "stored %s\n", *name)
+}
+
+func remove(store *credstore.Store, args []string) {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	name := flags.String("name", "", "instance name")
+	_ = flags.Parse(args)
+
+	if err := store.Delete(*name); err != nil {
+		log.Fatalf("delete: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "deleted %s\n", *name)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"green-api/internal/greenapi/fake"
+)
+
+// fakegreenapi serves an in-memory GREEN-API, so the service can run without
+// network access. Point green_api.base_url and green_api.media_url at it.
+// Faults can be injected at start with -faults or later through
+// POST /__fake/faults.
+func main() {
+	addr := flag.String("addr", "127.0.0.1:7780", "listen address")
+	instances := flag.String("instances", "", "comma-separated idInstance:apiTokenInstance pairs; empty accepts any instance")
+	faultsFile := flag.String("faults", "", "JSON file with a list of faults to inject at start")
+	flag.Parse()
+
+	var opts []fake.Option
+	if *instances == "" {
+		opts = append(opts, fake.WithAutoRegister())
+	}
+	server := fake.New(opts...)
+
+	for _, pair := range strings.Split(*instances, ",") {
+		if pair == "" {
+			continue
+		}
+		idInstance, token, ok := strings.Cut(pair, ":")
+		if !ok || idInstance == "" || token == "" {
+			log.Fatalf("instances: %q is not idInstance:apiTokenInstance", pair)
+		}
+		server.AddInstance(idInstance, token)
+	}
+
+	if *faultsFile != "" {
+		faults, err := readFaults(*faultsFile)
+		if err != nil {
+			log.Fatalf("faults: %v", err)
+		}
+		for _, fault := range faults {
+			server.Inject(fault)
+		}
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("fake GREEN-API listening on http://%s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("serve: %v", err)
+	}
+}
+
+func readFaults(path string) ([]fake.Fault, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var faults []fake.Fault
+	if err := json.Unmarshal(data, &faults); err != nil {
+		return nil, err
+	}
+	return faults, nil
+}
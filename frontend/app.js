@@ -7,6 +7,8 @@
   const statusBadge = document.getElementById("statusBadge");
 
   const controls = {
+    apiKey: document.getElementById("apiKey"),
+    instance: document.getElementById("instance"),
     idInstance: document.getElementById("idInstance"),
     apiTokenInstance: document.getElementById("apiTokenInstance"),
     toggleApiTokenVisibility: document.getElementById("toggleApiTokenVisibility"),
@@ -14,10 +16,17 @@
     smMessage: document.getElementById("smMessage"),
     sfChatId: document.getElementById("sfChatId"),
     sfUrl: document.getElementById("sfUrl"),
+    acPhoneNumber: document.getElementById("acPhoneNumber"),
+    qrImage: document.getElementById("qrImage"),
     btnGetSettings: document.getElementById("btnGetSettings"),
     btnGetStateInstance: document.getElementById("btnGetStateInstance"),
     btnSendMessage: document.getElementById("btnSendMessage"),
-    btnSendFileByUrl: document.getElementById("btnSendFileByUrl")
+    btnSendFileByUrl: document.getElementById("btnSendFileByUrl"),
+    btnQr: document.getElementById("btnQr"),
+    btnAuthorizationCode: document.getElementById("btnAuthorizationCode"),
+    btnWaitAuthorized: document.getElementById("btnWaitAuthorized"),
+    btnReboot: document.getElementById("btnReboot"),
+    btnLogout: document.getElementById("btnLogout")
   };
 
   function setStatus(state, text) {
@@ -32,6 +41,10 @@
   }
 
   function credentials() {
+    const instance = controls.instance.value;
+    if (instance) {
+      return { instance };
+    }
     return {
       idInstance: controls.idInstance.value.trim(),
       apiTokenInstance: controls.apiTokenInstance.value.trim()
@@ -40,17 +53,35 @@
 
   function assertCredentials() {
     const creds = credentials();
+    if (creds.instance) {
+      return creds;
+    }
     if (!creds.idInstance || !creds.apiTokenInstance) {
       throw new Error("Заполните idInstance и ApiTokenInstance");
     }
     return creds;
   }
 
+  // Authorization header for deployments with auth.enabled; empty when no key is set.
+  function authHeaders() {
+    const apiKey = controls.apiKey.value.trim();
+    return apiKey ? { Authorization: `Bearer ${apiKey}` } : {};
+  }
+
   function setButtonsDisabled(disabled) {
-    controls.btnGetSettings.disabled = disabled;
-    controls.btnGetStateInstance.disabled = disabled;
-    controls.btnSendMessage.disabled = disabled;
-    controls.btnSendFileByUrl.disabled = disabled;
+    [
+      controls.btnGetSettings,
+      controls.btnGetStateInstance,
+      controls.btnSendMessage,
+      controls.btnSendFileByUrl,
+      controls.btnQr,
+      controls.btnAuthorizationCode,
+      controls.btnWaitAuthorized,
+      controls.btnReboot,
+      controls.btnLogout
+    ].forEach(function (button) {
+      button.disabled = disabled;
+    });
   }
 
   function setTokenVisibility(isVisible) {
@@ -61,6 +92,7 @@
     controls.toggleApiTokenVisibility.title = isVisible ? "Скрыть токен" : "Показать токен";
   }
 
+  // callApi shows the response and returns the parsed body of a 2xx answer.
   async function callApi(path, payload) {
     setButtonsDisabled(true);
     setStatus("idle", "loading");
@@ -68,9 +100,7 @@
     try {
       const res = await fetch(`${API_BASE}${path}`, {
         method: "POST",
-        headers: {
-          "Content-Type": "application/json"
-        },
+        headers: Object.assign({ "Content-Type": "application/json" }, authHeaders()),
         body: JSON.stringify(payload)
       });
 
@@ -90,6 +120,7 @@
 
       if (res.ok) {
         setStatus("success", `success ${res.status}`);
+        return body;
       } else {
         setStatus("error", `error ${res.status}`);
       }
@@ -165,10 +196,110 @@
     }
   });
 
+  controls.btnQr.addEventListener("click", async function () {
+    try {
+      controls.qrImage.hidden = true;
+      const body = await callApi("/qr", assertCredentials());
+      if (body && body.type === "qrCode") {
+        controls.qrImage.src = `data:image/png;base64,${body.message}`;
+        controls.qrImage.hidden = false;
+      }
+    } catch (error) {
+      setStatus("error", "validation error");
+      setResponse({ error: { message: error.message } });
+    }
+  });
+
+  controls.btnAuthorizationCode.addEventListener("click", async function () {
+    try {
+      const creds = assertCredentials();
+      const phoneNumber = controls.acPhoneNumber.value.trim();
+
+      if (!phoneNumber) {
+        throw new Error("Для getAuthorizationCode укажите номер телефона");
+      }
+
+      await callApi("/authorization-code", {
+        ...creds,
+        phoneNumber
+      });
+    } catch (error) {
+      setStatus("error", "validation error");
+      setResponse({ error: { message: error.message } });
+    }
+  });
+
+  controls.btnWaitAuthorized.addEventListener("click", async function () {
+    try {
+      const body = await callApi("/wait-authorized", assertCredentials());
+      if (body) {
+        controls.qrImage.hidden = true;
+      }
+    } catch (error) {
+      setStatus("error", "validation error");
+      setResponse({ error: { message: error.message } });
+    }
+  });
+
+  controls.btnReboot.addEventListener("click", async function () {
+    try {
+      await callApi("/reboot", assertCredentials());
+    } catch (error) {
+      setStatus("error", "validation error");
+      setResponse({ error: { message: error.message } });
+    }
+  });
+
+  controls.btnLogout.addEventListener("click", async function () {
+    try {
+      const creds = assertCredentials();
+      if (!window.confirm("Отвязать телефон от инстанса?")) {
+        return;
+      }
+      await callApi("/logout", creds);
+    } catch (error) {
+      setStatus("error", "validation error");
+      setResponse({ error: { message: error.message } });
+    }
+  });
+
   controls.toggleApiTokenVisibility.addEventListener("click", function () {
     setTokenVisibility(controls.apiTokenInstance.type === "password");
   });
 
+  function setManualCredentialsDisabled(disabled) {
+    controls.idInstance.disabled = disabled;
+    controls.apiTokenInstance.disabled = disabled;
+    controls.toggleApiTokenVisibility.disabled = disabled;
+  }
+
+  controls.instance.addEventListener("change", function () {
+    setManualCredentialsDisabled(controls.instance.value !== "");
+  });
+
+  async function loadInstances() {
+    try {
+      const res = await fetch(`${API_BASE}/instances`, { headers: authHeaders() });
+      if (!res.ok) return;
+      const body = await res.json();
+      while (controls.instance.options.length > 1) {
+        controls.instance.remove(1);
+      }
+      (body.instances || []).forEach(function (instance) {
+        const option = document.createElement("option");
+        option.value = instance.name;
+        option.textContent = `${instance.name} (${instance.idInstance})`;
+        controls.instance.appendChild(option);
+      });
+    } catch {
+      // Registry is optional: manual credentials stay available.
+    }
+  }
+
+  controls.apiKey.addEventListener("change", loadInstances);
+
+  loadInstances();
+
   setTokenVisibility(false);
 
   setResponse({
@@ -2,25 +2,46 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"go.uber.org/zap"
 
+	"green-api/internal/auth"
 	"green-api/internal/config"
+	"green-api/internal/credstore"
 	"green-api/internal/greenapi"
+	"green-api/internal/greenapi/cassette"
+	"green-api/internal/health"
 	"green-api/internal/http/router"
+	"green-api/internal/instance"
 	"green-api/internal/logging"
+	"green-api/internal/metrics"
+	"green-api/internal/notification"
+	"green-api/internal/queue"
+	"green-api/internal/scheduler"
 	"green-api/internal/service"
+	"green-api/internal/tracing"
+	"green-api/internal/urlcheck"
 )
 
+// worker is a background loop that runs until its context is cancelled.
+type worker interface {
+	Run(ctx context.Context)
+}
+
 type Server struct {
-	cfg    config.Config
-	logger *zap.Logger
-	http   *http.Server
+	cfg     config.Config
+	logger  *zap.Logger
+	http    *http.Server
+	workers []worker
+	closers []io.Closer
 }
 
 func New(configPath string) (*Server, error) {
@@ -34,9 +55,88 @@ func New(configPath string) (*Server, error) {
 		return nil, err
 	}
 
-	client := greenapi.NewClient(cfg.GreenAPI, logger)
-	svc := service.New(client)
-	engine := router.New(cfg, logger, svc)
+	registry, err := newInstanceResolver(cfg.Instances)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientOpts []greenapi.ClientOption
+	routerOpts := []router.Option{}
+	serviceOpts := []service.Option{
+		service.WithInstanceResolver(registry),
+		service.WithUploadLimits(cfg.Upload.MaxBytes(), cfg.Upload.AllowedTypes),
+		service.WithDecodeMode(greenapi.DecodeMode(cfg.GreenAPI.Decoding())),
+		service.WithWhatsappCache(cfg.Recipients.CacheTTL(), cfg.Recipients.Capacity()),
+	}
+	if cfg.Recipients.Enabled {
+		serviceOpts = append(serviceOpts, service.WithRecipientCheck())
+	}
+	if cfg.URLPreflight.Enabled {
+		serviceOpts = append(serviceOpts, service.WithURLPreflight(urlcheck.New(cfg.URLPreflight.Timeout())))
+	}
+	if cfg.Metrics.Enabled {
+		m := metrics.New()
+		clientOpts = append(clientOpts, greenapi.WithObserver(m))
+		routerOpts = append(routerOpts, router.WithMetrics(m))
+	}
+	var tracer *tracing.Provider
+	if cfg.Tracing.Enabled {
+		tracer, err = tracing.New(cfg.Tracing, logger)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts = append(clientOpts, greenapi.WithTracerProvider(tracer))
+		serviceOpts = append(serviceOpts, service.WithTracerProvider(tracer))
+		routerOpts = append(routerOpts, router.WithTracerProvider(tracer))
+	}
+
+	if cassetteCfg := cfg.GreenAPI.Cassette; cassetteCfg.Enabled() {
+		transport, err := cassette.New(cassette.Mode(cassetteCfg.Mode), cassetteCfg.Path, http.DefaultTransport)
+		if err != nil {
+			return nil, err
+		}
+		logger.Warn("green-api cassette is active", zap.String("mode", cassetteCfg.Mode), zap.String("path", cassetteCfg.Path))
+		clientOpts = append(clientOpts, greenapi.WithTransport(transport))
+	}
+
+	client := greenapi.NewClient(cfg.GreenAPI, logger, clientOpts...)
+	if cfg.Instances.RegisteredOnly {
+		serviceOpts = append(serviceOpts, service.WithRegisteredInstancesOnly())
+	}
+
+	var jobs *queue.Queue
+	if cfg.Queue.Enabled {
+		jobs, err = queue.Open(cfg.Queue.Path)
+		if err != nil {
+			return nil, err
+		}
+		serviceOpts = append(serviceOpts, service.WithQueue(jobs))
+	}
+	var schedules *scheduler.Store
+	if cfg.Scheduler.Enabled {
+		schedules, err = scheduler.Open(cfg.Scheduler.Path)
+		if err != nil {
+			return nil, err
+		}
+		serviceOpts = append(serviceOpts, service.WithScheduler(schedules))
+	}
+	svc := service.New(client, serviceOpts...)
+	dispatcher := notification.NewDispatcher()
+	dispatcher.OnAny(notification.LogHandler(logger))
+	routerOpts = append(routerOpts, router.WithNotificationDispatcher(dispatcher))
+	if cfg.Auth.Enabled {
+		authenticator, err := newAuthenticator(cfg.Auth)
+		if err != nil {
+			return nil, err
+		}
+		routerOpts = append(routerOpts, router.WithAuthenticator(authenticator))
+	}
+	checker, err := newHealthChecker(cfg.Health, client, registry, jobs)
+	if err != nil {
+		return nil, err
+	}
+	routerOpts = append(routerOpts, router.WithHealthChecker(checker))
+	engine := router.New(cfg, logger, svc, routerOpts...)
 
 	httpServer := &http.Server{
 		Addr:         cfg.Server.Address(),
@@ -45,12 +145,160 @@ func New(configPath string) (*Server, error) {
 		WriteTimeout: cfg.Server.WriteTimeout(),
 	}
 
-	return &Server{cfg: cfg, logger: logger, http: httpServer}, nil
+	var workers []worker
+	if cfg.Polling.Enabled {
+		instances, err := pollingCredentials(cfg.Polling.Instances, registry)
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, notification.NewPoller(client, dispatcher, instances, cfg.Polling.ReceiveTimeout(), cfg.Polling.ErrorDelay(), logger))
+	}
+
+	var closers []io.Closer
+	if jobs != nil {
+		workers = append(workers, queue.NewWorker(jobs, svc, cfg.Queue, logger))
+		closers = append(closers, jobs)
+	}
+	if schedules != nil {
+		workers = append(workers, scheduler.NewRunner(schedules, svc, cfg.Scheduler, logger))
+		closers = append(closers, schedules)
+	}
+	if tracer != nil {
+		// Closed last so that spans of the final jobs are flushed.
+		closers = append(closers, tracer)
+	}
+
+	return &Server{cfg: cfg, logger: logger, http: httpServer, workers: workers, closers: closers}, nil
 }
 
-func (s *Server) Run() error {
+func newInstanceResolver(cfg config.InstancesConfig) (instance.Resolver, error) {
+	instances := make([]instance.Instance, 0, len(cfg.Registry))
+	for _, inst := range cfg.Registry {
+		instances = append(instances, instance.Instance{
+			Name: inst.Name,
+			Credentials: instance.Credentials{
+				IDInstance:       inst.IDInstance,
+				APITokenInstance: inst.APITokenInstance,
+			},
+		})
+	}
+
+	registry, err := instance.NewRegistry(instances)
+	if err != nil {
+		return nil, fmt.Errorf("instance registry: %w", err)
+	}
+	if cfg.Store.Path == "" {
+		return registry, nil
+	}
+
+	store, err := OpenCredentialStore(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Chain{registry, store}, nil
+}
+
+// OpenCredentialStore opens the encrypted credential store described by cfg.
+func OpenCredentialStore(cfg config.CredentialStoreConfig) (*credstore.Store, error) {
+	keyring, err := credstore.LoadKeyring(cfg.MasterKeyEnv, cfg.MasterKeyFiles)
+	if err != nil {
+		return nil, fmt.Errorf("credential store keyring: %w", err)
+	}
+	store, err := credstore.Open(cfg.Path, keyring)
+	if err != nil {
+		return nil, fmt.Errorf("credential store: %w", err)
+	}
+	return store, nil
+}
+
+func newHealthChecker(cfg config.HealthConfig, client *greenapi.Client, resolver instance.Resolver, jobs *queue.Queue) (*health.Checker, error) {
+	var opts []health.Option
+	if jobs != nil {
+		opts = append(opts, health.WithQueue(jobs, cfg.MaxQueueDepth))
+	}
+	if len(cfg.ProbeInstances) > 0 {
+		for _, name := range cfg.ProbeInstances {
+			if _, err := resolver.Resolve(context.Background(), name); err != nil {
+				return nil, fmt.Errorf("health probe instance: %w", err)
+			}
+		}
+		opts = append(opts, health.WithProbe(client, resolver, cfg.ProbeInstances, cfg.ProbeInterval(), cfg.ProbeTimeout()))
+	}
+	return health.NewChecker(client, opts...), nil
+}
+
+func newAuthenticator(cfg config.AuthConfig) (*auth.Authenticator, error) {
+	store, err := auth.NewStore(cfg.StorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrap := make([]auth.Key, 0, len(cfg.BootstrapKeys))
+	for _, key := range cfg.BootstrapKeys {
+		scopes, err := auth.ParseScopes(key.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap api key %s: %w", key.ID, err)
+		}
+		bootstrap = append(bootstrap, auth.Key{ID: key.ID, Name: key.Name, Hash: key.Hash, Scopes: scopes})
+	}
+	return auth.NewAuthenticator(store, bootstrap), nil
+}
+
+func pollingCredentials(instances []config.PollingInstanceConfig, resolver instance.Resolver) ([]notification.Credentials, error) {
+	creds := make([]notification.Credentials, 0, len(instances))
+	for _, inst := range instances {
+		if inst.Instance == "" {
+			creds = append(creds, notification.Credentials{
+				IDInstance:       inst.IDInstance,
+				APITokenInstance: inst.APITokenInstance,
+			})
+			continue
+		}
+
+		resolved, err := resolver.Resolve(context.Background(), inst.Instance)
+		if err != nil {
+			return nil, fmt.Errorf("polling instance: %w", err)
+		}
+		creds = append(creds, notification.Credentials{
+			IDInstance:       resolved.IDInstance,
+			APITokenInstance: resolved.APITokenInstance,
+		})
+	}
+	return creds, nil
+}
+
+// Run serves until SIGINT or SIGTERM, or until the listener fails. Workers
+// are stopped and the stores and tracer closed on every exit path.
+func (s *Server) Run() (err error) {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workersWG sync.WaitGroup
+	for _, w := range s.workers {
+		workersWG.Add(1)
+		go func(w worker) {
+			defer workersWG.Done()
+			w.Run(workersCtx)
+		}(w)
+	}
+	defer func() {
+		stopWorkers()
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout())
+		defer cancel()
+		if waitErr := waitGroup(ctx, &workersWG); waitErr != nil {
+			err = errors.Join(err, fmt.Errorf("stop background workers: %w", waitErr))
+		}
+		// bbolt waits for transactions in flight, so the stores may be
+		// closed even when a worker did not stop in time.
+		for _, closer := range s.closers {
+			if closeErr := closer.Close(); closeErr != nil {
+				s.logger.Error("resource_close_failed", zap.Error(closeErr))
+			}
+		}
+		s.logger.Info("server_stopped")
+	}()
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -76,7 +324,20 @@ func (s *Server) Run() error {
 	if err := s.http.Shutdown(ctx); err != nil {
 		return fmt.Errorf("graceful shutdown: %w", err)
 	}
-
-	s.logger.Info("server_stopped")
 	return nil
 }
+
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+type workerFunc func(ctx context.Context)
+
+func (f workerFunc) Run(ctx context.Context) { f(ctx) }
+
+func TestServer_RunClosesResourcesWhenListenFails(t *testing.T) {
+	t.Parallel()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { busy.Close() })
+
+	var stopped, closed atomic.Bool
+	s := &Server{
+		cfg:    config.Config{Server: config.ServerConfig{ShutdownTimeoutSeconds: 1}},
+		logger: zap.NewNop(),
+		http:   &http.Server{Addr: busy.Addr().String()},
+		workers: []worker{workerFunc(func(ctx context.Context) {
+			<-ctx.Done()
+			stopped.Store(true)
+		})},
+		closers: []io.Closer{closerFunc(func() error {
+			closed.Store(true)
+			return nil
+		})},
+	}
+
+	require.ErrorContains(t, s.Run(), "listen and serve")
+	require.True(t, stopped.Load(), "workers are stopped")
+	require.True(t, closed.Load(), "stores and tracer are closed")
+}
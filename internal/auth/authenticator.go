@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Authenticator verifies bearer tokens and manages API keys. Bootstrap keys
+// come from config and cannot be revoked through the admin API.
+type Authenticator struct {
+	store     *Store
+	bootstrap map[string]Key
+	now       func() time.Time
+}
+
+func NewAuthenticator(store *Store, bootstrap []Key) *Authenticator {
+	keys := make(map[string]Key, len(bootstrap))
+	for _, key := range bootstrap {
+		keys[key.ID] = key
+	}
+	return &Authenticator{store: store, bootstrap: keys, now: time.Now}
+}
+
+// Authenticate returns the active key matching token.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (Key, error) {
+	id, secret, err := parseToken(strings.TrimSpace(token))
+	if err != nil {
+		return Key{}, err
+	}
+
+	key, ok := a.bootstrap[id]
+	if !ok {
+		key, err = a.store.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return Key{}, ErrInvalidKey
+		}
+		if err != nil {
+			return Key{}, err
+		}
+	}
+
+	if key.Revoked() || !hashMatches(secret, key.Hash) {
+		return Key{}, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Issue creates a key and returns it with the plaintext token, which is not
+// retrievable afterwards.
+func (a *Authenticator) Issue(ctx context.Context, name string, scopes []Scope) (Key, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Key{}, "", fmt.Errorf("name is required")
+	}
+
+	id, token, hash, err := NewToken()
+	if err != nil {
+		return Key{}, "", err
+	}
+	key := Key{ID: id, Name: name, Hash: hash, Scopes: scopes, CreatedAt: a.now().UTC()}
+	if err := a.store.Create(ctx, key); err != nil {
+		return Key{}, "", err
+	}
+	return key, token, nil
+}
+
+func (a *Authenticator) List(ctx context.Context) ([]Key, error) {
+	return a.store.List(ctx)
+}
+
+func (a *Authenticator) Revoke(ctx context.Context, id string) error {
+	if _, ok := a.bootstrap[id]; ok {
+		return fmt.Errorf("bootstrap key %s is managed in config", id)
+	}
+	return a.store.Revoke(ctx, id, a.now().UTC())
+}
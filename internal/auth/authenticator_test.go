@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_IssueAndAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore("")
+	require.NoError(t, err)
+	authenticator := NewAuthenticator(store, nil)
+
+	key, token, err := authenticator.Issue(context.Background(), "ops", []Scope{ScopeMessageSend})
+	require.NoError(t, err)
+	require.NotContains(t, key.Hash, token)
+
+	got, err := authenticator.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+	require.True(t, got.Has(ScopeMessageSend))
+	require.False(t, got.Has(ScopeAdminKeys))
+
+	_, err = authenticator.Authenticate(context.Background(), token+"x")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = authenticator.Authenticate(context.Background(), "not-a-key")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAuthenticator_RevokedKeyIsRejected(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore("")
+	require.NoError(t, err)
+	authenticator := NewAuthenticator(store, nil)
+
+	key, token, err := authenticator.Issue(context.Background(), "ops", []Scope{ScopeStateRead})
+	require.NoError(t, err)
+	require.NoError(t, authenticator.Revoke(context.Background(), key.ID))
+
+	_, err = authenticator.Authenticate(context.Background(), token)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	require.ErrorIs(t, authenticator.Revoke(context.Background(), "missing"), ErrNotFound)
+}
+
+func TestAuthenticator_BootstrapKey(t *testing.T) {
+	t.Parallel()
+
+	id, token, hash, err := NewToken()
+	require.NoError(t, err)
+
+	store, err := NewStore("")
+	require.NoError(t, err)
+	authenticator := NewAuthenticator(store, []Key{{ID: id, Name: "admin", Hash: hash, Scopes: []Scope{ScopeAdminKeys}}})
+
+	key, err := authenticator.Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	require.True(t, key.Has(ScopeAdminKeys))
+	require.Error(t, authenticator.Revoke(context.Background(), id))
+}
+
+func TestStore_PersistsKeys(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	key, token, err := NewAuthenticator(store, nil).Issue(context.Background(), "ops", []Scope{ScopeFileSend})
+	require.NoError(t, err)
+
+	reopened, err := NewStore(path)
+	require.NoError(t, err)
+	got, err := NewAuthenticator(reopened, nil).Authenticate(context.Background(), token)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, got.ID)
+}
+
+func TestParseScopes(t *testing.T) {
+	t.Parallel()
+
+	scopes, err := ParseScopes([]string{"message:send", " state:read "})
+	require.NoError(t, err)
+	require.Equal(t, []Scope{ScopeMessageSend, ScopeStateRead}, scopes)
+
+	_, err = ParseScopes([]string{"*"})
+	require.Error(t, err)
+	_, err = ParseScopes(nil)
+	require.Error(t, err)
+}
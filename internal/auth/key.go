@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scope grants access to a group of /api/v1 endpoints.
+type Scope string
+
+const (
+	ScopeInstancesRead  Scope = "instances:read"
+	ScopeStateRead      Scope = "state:read"
+	ScopeSettingsRead   Scope = "settings:read"
+	ScopeSettingsWrite  Scope = "settings:write"
+	ScopeMessageSend    Scope = "message:send"
+	ScopeFileSend       Scope = "file:send"
+	ScopeInstanceManage Scope = "instance:manage"
+	ScopeContactsRead   Scope = "contacts:read"
+	ScopeAdminKeys      Scope = "admin:keys"
+
+	// ScopeAll is held by the anonymous principal when authentication is disabled.
+	ScopeAll Scope = "*"
+)
+
+// KnownScopes lists the scopes that may be granted to an API key.
+var KnownScopes = []Scope{
+	ScopeInstancesRead,
+	ScopeStateRead,
+	ScopeSettingsRead,
+	ScopeSettingsWrite,
+	ScopeMessageSend,
+	ScopeFileSend,
+	ScopeInstanceManage,
+	ScopeContactsRead,
+	ScopeAdminKeys,
+}
+
+const tokenPrefix = "gak_"
+
+var (
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrNotFound   = errors.New("api key not found")
+)
+
+// Key is a stored API key. Only the SHA-256 hash of its secret is kept.
+type Key struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Has reports whether the key grants scope.
+func (k Key) Has(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+func (k Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Anonymous is the principal used when authentication is disabled.
+var Anonymous = Key{ID: "anonymous", Name: "anonymous", Scopes: []Scope{ScopeAll}}
+
+// NewToken returns a new key ID, the plaintext token handed to the client
+// ("gak_<id>_<secret>") and the hash of its secret for storage.
+func NewToken() (id, token, hash string, err error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", fmt.Errorf("generate key id: %w", err)
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("generate key secret: %w", err)
+	}
+
+	id = hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return id, tokenPrefix + id + "_" + secret, HashSecret(secret), nil
+}
+
+// HashSecret returns the storage form of a key secret.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseToken splits "gak_<id>_<secret>" into id and secret.
+func parseToken(token string) (string, string, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return "", "", ErrInvalidKey
+	}
+	id, secret, ok := strings.Cut(strings.TrimPrefix(token, tokenPrefix), "_")
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrInvalidKey
+	}
+	return id, secret, nil
+}
+
+func hashMatches(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hash)) == 1
+}
+
+// ParseScopes validates scope names.
+func ParseScopes(names []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(names))
+	for _, name := range names {
+		scope := Scope(strings.TrimSpace(name))
+		known := false
+		for _, s := range KnownScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown scope %q", name)
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
@@ -0,0 +1,179 @@
+package chatid
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind is the WhatsApp server part of a chat ID.
+type Kind string
+
+const (
+	Personal   Kind = "c.us"
+	Group      Kind = "g.us"
+	Newsletter Kind = "newsletter"
+)
+
+const (
+	// maxE164Digits is the longest international number allowed by E.164.
+	maxE164Digits = 15
+	// minNationalDigits applies to countries without a known length rule.
+	minNationalDigits = 4
+	// maxUserLength bounds group and newsletter IDs.
+	maxUserLength = 64
+)
+
+var (
+	digitsOnly   = regexp.MustCompile(`^\d+$`)
+	groupPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+)
+
+// ID is a validated WhatsApp chat ID such as 79991234567@c.us,
+// 120363043968066561@g.us or 120363043968066561@newsletter. The zero value is
+// not a valid ID.
+type ID struct {
+	kind Kind
+	user string
+}
+
+// Parse accepts a full chat ID or a phone number. Phone numbers may be
+// written in E.164 notation with formatting, e.g. "+7 (999) 123-45-67" or
+// "007 999 123 45 67", and become personal chat IDs. The country calling code
+// and the number length are checked for personal chats.
+func Parse(raw string) (ID, error) {
+	candidate := strings.TrimSpace(raw)
+	if candidate == "" {
+		return ID{}, errors.New("chatId is required")
+	}
+
+	at := strings.LastIndexByte(candidate, '@')
+	if at < 0 {
+		number, err := stripPhoneFormatting(candidate)
+		if err != nil {
+			return ID{}, err
+		}
+		if err := validatePhone(number); err != nil {
+			return ID{}, err
+		}
+		return ID{kind: Personal, user: number}, nil
+	}
+
+	user, kind := candidate[:at], Kind(candidate[at+1:])
+	switch kind {
+	case Personal:
+		if !digitsOnly.MatchString(user) {
+			return ID{}, errors.New("chatId must contain only digits before @c.us")
+		}
+		if err := validatePhone(user); err != nil {
+			return ID{}, err
+		}
+	case Group:
+		if len(user) > maxUserLength || !groupPattern.MatchString(user) {
+			return ID{}, errors.New("group chatId must look like 120363043968066561@g.us or 79991234567-1581234048@g.us")
+		}
+	case Newsletter:
+		if len(user) > maxUserLength || !digitsOnly.MatchString(user) {
+			return ID{}, errors.New("newsletter chatId must contain only digits before @newsletter")
+		}
+	default:
+		return ID{}, fmt.Errorf("chatId suffix must be @%s, @%s or @%s", Personal, Group, Newsletter)
+	}
+	return ID{kind: kind, user: user}, nil
+}
+
+// MustParse is Parse for constants in tests and examples.
+func MustParse(raw string) ID {
+	id, err := Parse(raw)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func (id ID) Kind() Kind {
+	return id.kind
+}
+
+// User is the part before @: a phone number in international format without
+// "+" for personal chats.
+func (id ID) User() string {
+	return id.user
+}
+
+func (id ID) IsZero() bool {
+	return id.kind == ""
+}
+
+// String returns the chat ID in the form GREEN-API expects.
+func (id ID) String() string {
+	if id.IsZero() {
+		return ""
+	}
+	return id.user + "@" + string(id.kind)
+}
+
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *ID) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// stripPhoneFormatting removes the "+" or "00" international prefix and the
+// separators people put into phone numbers.
+func stripPhoneFormatting(raw string) (string, error) {
+	number := raw
+	switch {
+	case strings.HasPrefix(number, "+"):
+		number = number[1:]
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.' || r == '\u00a0':
+		default:
+			return "", errors.New("chatId must be a phone number or a chat ID ending with @c.us, @g.us or @newsletter")
+		}
+	}
+	if digits.Len() == 0 {
+		return "", errors.New("chatId must contain digits")
+	}
+	return digits.String(), nil
+}
+
+func validatePhone(number string) error {
+	if len(number) > maxE164Digits {
+		return fmt.Errorf("phone number must have at most %d digits including the country code", maxE164Digits)
+	}
+
+	code, ok := countryCallingCode(number)
+	if !ok {
+		return errors.New("phone number must start with a valid country calling code")
+	}
+
+	national := len(number) - len(code)
+	rule, known := nationalLengths[code]
+	if !known {
+		rule = lengthRule{min: minNationalDigits, max: maxE164Digits - len(code)}
+	}
+	if national < rule.min || national > rule.max {
+		if rule.min == rule.max {
+			return fmt.Errorf("phone number with country code +%s must have %d digits after the code", code, rule.min)
+		}
+		return fmt.Errorf("phone number with country code +%s must have %d to %d digits after the code", code, rule.min, rule.max)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package chatid
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		raw  string
+		want string
+		kind Kind
+		err  string
+	}{
+		{name: "digits", raw: "77771234567", want: "77771234567@c.us", kind: Personal},
+		{name: "personal suffix", raw: "77771234567@c.us", want: "77771234567@c.us", kind: Personal},
+		{name: "e164 formatting", raw: "+7 (999) 123-45-67", want: "79991234567@c.us", kind: Personal},
+		{name: "international prefix", raw: "00 44 20 7946 0958", want: "442079460958@c.us", kind: Personal},
+		{name: "dots", raw: "+33.6.12.34.56.78", want: "33612345678@c.us", kind: Personal},
+		{name: "three digit code", raw: "+998 90 123 45 67", want: "998901234567@c.us", kind: Personal},
+		{name: "surrounding spaces", raw: "  79991234567  ", want: "79991234567@c.us", kind: Personal},
+		{name: "group", raw: "120363043968066561@g.us", want: "120363043968066561@g.us", kind: Group},
+		{name: "legacy group", raw: "79991234567-1581234048@g.us", want: "79991234567-1581234048@g.us", kind: Group},
+		{name: "newsletter", raw: "120363043968066561@newsletter", want: "120363043968066561@newsletter", kind: Newsletter},
+		{name: "empty", raw: "  ", err: "required"},
+		{name: "letters", raw: "abc", err: "phone number or a chat ID"},
+		{name: "formatting in suffixed id", raw: "+79991234567@c.us", err: "only digits before @c.us"},
+		{name: "unknown suffix", raw: "79991234567@s.whatsapp.net", err: "suffix must be"},
+		{name: "unknown country code", raw: "+999 1234 5678", err: "country calling code"},
+		{name: "short russian number", raw: "+7 999 123 45", err: "must have 10 digits after the code"},
+		{name: "long russian number", raw: "799912345678@c.us", err: "must have 10 digits after the code"},
+		{name: "longer than e164", raw: "4412345678901234", err: "at most 15 digits"},
+		{name: "short national number", raw: "+49 123", err: "4 to 13 digits"},
+		{name: "bad group", raw: "1203-6304-3968@g.us", err: "group chatId"},
+		{name: "bad newsletter", raw: "channel@newsletter", err: "newsletter chatId"},
+	}
+
+	for _, tc := range cases {
+		id, err := Parse(tc.raw)
+		if tc.err != "" {
+			require.ErrorContains(t, err, tc.err, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.want, id.String(), tc.name)
+		require.Equal(t, tc.kind, id.Kind(), tc.name)
+	}
+}
+
+func TestID_User(t *testing.T) {
+	t.Parallel()
+
+	id := MustParse("+7 999 123-45-67")
+	require.Equal(t, "79991234567", id.User())
+	require.False(t, id.IsZero())
+	require.True(t, ID{}.IsZero())
+	require.Empty(t, ID{}.String())
+}
+
+func TestID_JSON(t *testing.T) {
+	t.Parallel()
+
+	var payload struct {
+		ChatID ID `json:"chatId"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"chatId":"+7 999 123 45 67"}`), &payload))
+	require.Equal(t, Personal, payload.ChatID.Kind())
+
+	encoded, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"chatId":"79991234567@c.us"}`, string(encoded))
+
+	require.Error(t, json.Unmarshal([]byte(`{"chatId":"abc"}`), &payload))
+}
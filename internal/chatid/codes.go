@@ -0,0 +1,69 @@
+package chatid
+
+// countryCodes holds the ITU-T E.164 country calling codes in service. The set
+// is prefix-free, so a number matches at most one code.
+var countryCodes = map[string]struct{}{}
+
+func init() {
+	for _, code := range []string{
+		"1", "7",
+		"20", "27", "30", "31", "32", "33", "34", "36", "39", "40", "41", "43", "44", "45", "46", "47", "48", "49",
+		"51", "52", "53", "54", "55", "56", "57", "58", "60", "61", "62", "63", "64", "65", "66",
+		"81", "82", "84", "86", "90", "91", "92", "93", "94", "95", "98",
+		"211", "212", "213", "216", "218",
+		"220", "221", "222", "223", "224", "225", "226", "227", "228", "229",
+		"230", "231", "232", "233", "234", "235", "236", "237", "238", "239",
+		"240", "241", "242", "243", "244", "245", "246", "247", "248", "249",
+		"250", "251", "252", "253", "254", "255", "256", "257", "258",
+		"260", "261", "262", "263", "264", "265", "266", "267", "268", "269",
+		"290", "291", "297", "298", "299",
+		"350", "351", "352", "353", "354", "355", "356", "357", "358", "359",
+		"370", "371", "372", "373", "374", "375", "376", "377", "378", "379",
+		"380", "381", "382", "383", "385", "386", "387", "389",
+		"420", "421", "423",
+		"500", "501", "502", "503", "504", "505", "506", "507", "508", "509",
+		"590", "591", "592", "593", "594", "595", "596", "597", "598", "599",
+		"670", "672", "673", "674", "675", "676", "677", "678", "679",
+		"680", "681", "682", "683", "685", "686", "687", "688", "689", "690", "691", "692",
+		"800", "808", "850", "852", "853", "855", "856", "870", "878",
+		"880", "881", "882", "883", "886", "888",
+		"960", "961", "962", "963", "964", "965", "966", "967", "968",
+		"970", "971", "972", "973", "974", "975", "976", "977", "979",
+		"992", "993", "994", "995", "996", "998",
+	} {
+		countryCodes[code] = struct{}{}
+	}
+}
+
+type lengthRule struct {
+	min, max int
+}
+
+// nationalLengths are the national number lengths of numbering plans with a
+// fixed or narrow length. Other codes only get the generic E.164 bounds.
+var nationalLengths = map[string]lengthRule{
+	"1":   {min: 10, max: 10}, // NANP
+	"7":   {min: 10, max: 10}, // Russia, Kazakhstan
+	"33":  {min: 9, max: 9},   // France
+	"34":  {min: 9, max: 9},   // Spain
+	"86":  {min: 10, max: 11}, // China
+	"91":  {min: 10, max: 10}, // India
+	"374": {min: 8, max: 8},   // Armenia
+	"375": {min: 9, max: 9},   // Belarus
+	"380": {min: 9, max: 9},   // Ukraine
+	"992": {min: 9, max: 9},   // Tajikistan
+	"994": {min: 9, max: 9},   // Azerbaijan
+	"995": {min: 9, max: 9},   // Georgia
+	"996": {min: 9, max: 9},   // Kyrgyzstan
+	"998": {min: 9, max: 9},   // Uzbekistan
+}
+
+// countryCallingCode returns the country calling code number starts with.
+func countryCallingCode(number string) (string, bool) {
+	for size := 1; size <= 3 && size <= len(number); size++ {
+		if _, ok := countryCodes[number[:size]]; ok {
+			return number[:size], true
+		}
+	}
+	return "", false
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
@@ -10,11 +12,24 @@ import (
 )
 
 type Config struct {
-	Server    ServerConfig        `mapstructure:"server" validate:"required"`
-	CORS      CORSConfig          `mapstructure:"cors" validate:"required"`
-	GreenAPI  GreenAPIConfig      `mapstructure:"green_api" validate:"required"`
-	Logging   LoggingConfig       `mapstructure:"logging" validate:"required"`
-	Validator *validator.Validate `mapstructure:"-"`
+	Server       ServerConfig        `mapstructure:"server" validate:"required"`
+	CORS         CORSConfig          `mapstructure:"cors" validate:"required"`
+	GreenAPI     GreenAPIConfig      `mapstructure:"green_api" validate:"required"`
+	Logging      LoggingConfig       `mapstructure:"logging" validate:"required"`
+	Idempotency  IdempotencyConfig   `mapstructure:"idempotency"`
+	Webhooks     WebhooksConfig      `mapstructure:"webhooks"`
+	Polling      PollingConfig       `mapstructure:"polling"`
+	Instances    InstancesConfig     `mapstructure:"instances"`
+	Auth         AuthConfig          `mapstructure:"auth"`
+	Queue        QueueConfig         `mapstructure:"queue"`
+	Scheduler    SchedulerConfig     `mapstructure:"scheduler"`
+	Metrics      MetricsConfig       `mapstructure:"metrics"`
+	Tracing      TracingConfig       `mapstructure:"tracing"`
+	Health       HealthConfig        `mapstructure:"health"`
+	Upload       UploadConfig        `mapstructure:"upload"`
+	URLPreflight URLPreflightConfig  `mapstructure:"url_preflight"`
+	Recipients   RecipientsConfig    `mapstructure:"recipient_check"`
+	Validator    *validator.Validate `mapstructure:"-"`
 }
 
 type ServerConfig struct {
@@ -31,14 +46,63 @@ type CORSConfig struct {
 
 type GreenAPIConfig struct {
 	BaseURL        string               `mapstructure:"base_url" validate:"required,url"`
+	MediaURL       string               `mapstructure:"media_url" validate:"omitempty,url"`
 	TimeoutSeconds int                  `mapstructure:"timeout_seconds" validate:"required,min=1"`
 	Retry          GreenAPIRetryConfig  `mapstructure:"retry" validate:"required"`
 	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker" validate:"required"`
+	RateLimit      RateLimitConfig      `mapstructure:"rate_limit"`
+	// DecodeMode is how /api/v2 checks GREEN-API responses against the typed
+	// models: lenient (default) or strict.
+	DecodeMode string         `mapstructure:"decode_mode" validate:"omitempty,oneof=lenient strict"`
+	Cassette   CassetteConfig `mapstructure:"cassette"`
 }
 
+// CassetteConfig records GREEN-API exchanges to Path or answers calls from
+// it instead of calling GREEN-API. A .json Path is written as JSON, any other
+// as YAML.
+type CassetteConfig struct {
+	Mode string `mapstructure:"mode" validate:"omitempty,oneof=passthrough record replay"`
+	Path string `mapstructure:"path"`
+}
+
+// Cassette modes of CassetteConfig.
+const (
+	CassettePassthrough = "passthrough"
+	CassetteRecord      = "record"
+	CassetteReplay      = "replay"
+)
+
+// GreenAPIRetryConfig sets how failed attempts are repeated. DelayMS takes
+// precedence over DelaySeconds and is the first delay of every strategy.
+// Retries stop once the next delay would end after BudgetMS or the request
+// context deadline, whichever comes first.
 type GreenAPIRetryConfig struct {
-	MaxRetries   int `mapstructure:"max_retries" validate:"min=0,max=10"`
-	DelaySeconds int `mapstructure:"delay_seconds" validate:"required,min=1,max=60"`
+	MaxRetries   int     `mapstructure:"max_retries" validate:"min=0,max=10"`
+	Strategy     string  `mapstructure:"strategy" validate:"omitempty,oneof=constant exponential decorrelated_jitter"`
+	DelaySeconds int     `mapstructure:"delay_seconds" validate:"min=0,max=60"`
+	DelayMS      int     `mapstructure:"delay_ms" validate:"min=0,max=60000"`
+	MaxDelayMS   int     `mapstructure:"max_delay_ms" validate:"min=0,max=300000"`
+	Multiplier   float64 `mapstructure:"multiplier" validate:"omitempty,gt=1,max=10"`
+	BudgetMS     int     `mapstructure:"budget_ms" validate:"min=0,max=600000"`
+}
+
+// Retry strategies of GreenAPIRetryConfig.
+const (
+	RetryConstant           = "constant"
+	RetryExponential        = "exponential"
+	RetryDecorrelatedJitter = "decorrelated_jitter"
+)
+
+// RateLimitConfig caps the calls made for one idInstance with two token
+// buckets: one for sends and one for everything else. A zero rate disables the
+// bucket. A call waits up to MaxWaitMS for a token and then fails with
+// rate_limited; zero fails fast.
+type RateLimitConfig struct {
+	SendsPerSecond float64 `mapstructure:"sends_per_second" validate:"min=0"`
+	SendBurst      int     `mapstructure:"send_burst" validate:"min=0"`
+	ReadsPerSecond float64 `mapstructure:"reads_per_second" validate:"min=0"`
+	ReadBurst      int     `mapstructure:"read_burst" validate:"min=0"`
+	MaxWaitMS      int     `mapstructure:"max_wait_ms" validate:"min=0,max=60000"`
 }
 
 type CircuitBreakerConfig struct {
@@ -49,8 +113,184 @@ type CircuitBreakerConfig struct {
 	IntervalSeconds     int     `mapstructure:"interval_seconds" validate:"required,min=1,max=300"`
 	FailureRatio        float64 `mapstructure:"failure_ratio" validate:"required,gte=0,lte=1"`
 	MinRequests         uint32  `mapstructure:"min_requests" validate:"required,min=1,max=200"`
+	PerMethod           bool    `mapstructure:"per_method"`
+	IdleTTLSeconds      int     `mapstructure:"idle_ttl_seconds" validate:"min=0,max=86400"`
+	MaxBreakers         int     `mapstructure:"max_breakers" validate:"min=0,max=100000"`
+}
+
+type IdempotencyConfig struct {
+	TTLSeconds int `mapstructure:"ttl_seconds" validate:"min=0,max=604800"`
+	MaxEntries int `mapstructure:"max_entries" validate:"min=0,max=1000000"`
+}
+
+type WebhooksConfig struct {
+	Enabled   bool                    `mapstructure:"enabled"`
+	Instances []WebhookInstanceConfig `mapstructure:"instances" validate:"dive"`
+}
+
+// WebhookInstanceConfig binds an idInstance to the webhookUrlToken configured
+// for it in GREEN-API; the token arrives in the Authorization header.
+type WebhookInstanceConfig struct {
+	IDInstance string `mapstructure:"id_instance" validate:"required"`
+	URLToken   string `mapstructure:"url_token" validate:"required"`
+}
+
+// PollingConfig enables receiveNotification/deleteNotification polling for
+// instances that cannot receive webhooks.
+type PollingConfig struct {
+	Enabled               bool                    `mapstructure:"enabled"`
+	ReceiveTimeoutSeconds int                     `mapstructure:"receive_timeout_seconds" validate:"min=0,max=60"`
+	ErrorDelaySeconds     int                     `mapstructure:"error_delay_seconds" validate:"min=0,max=300"`
+	Instances             []PollingInstanceConfig `mapstructure:"instances" validate:"dive"`
+}
+
+// PollingInstanceConfig names a registered instance or carries raw credentials.
+type PollingInstanceConfig struct {
+	Instance         string `mapstructure:"instance"`
+	IDInstance       string `mapstructure:"id_instance" validate:"required_without=Instance"`
+	APITokenInstance string `mapstructure:"api_token_instance" validate:"required_without=Instance"`
+}
+
+// InstancesConfig is the server-side registry of named GREEN-API instances.
+type InstancesConfig struct {
+	RegisteredOnly bool                  `mapstructure:"registered_only"`
+	Registry       []InstanceConfig      `mapstructure:"registry" validate:"dive"`
+	Store          CredentialStoreConfig `mapstructure:"store"`
+}
+
+// CredentialStoreConfig points to the encrypted credential store. The master
+// keys are read from MasterKeyEnv and MasterKeyFiles; the first one is primary.
+type CredentialStoreConfig struct {
+	Path           string   `mapstructure:"path"`
+	MasterKeyEnv   string   `mapstructure:"master_key_env"`
+	MasterKeyFiles []string `mapstructure:"master_key_files" validate:"dive,required"`
+}
+
+type InstanceConfig struct {
+	Name             string `mapstructure:"name" validate:"required"`
+	IDInstance       string `mapstructure:"id_instance" validate:"required"`
+	APITokenInstance string `mapstructure:"api_token_instance" validate:"required"`
+}
+
+// AuthConfig enables API key authentication for /api/v1. Keys created via
+// the admin API are kept in StorePath (in memory when empty); bootstrap keys
+// come from config so that the first admin key exists.
+type AuthConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	StorePath     string            `mapstructure:"store_path"`
+	BootstrapKeys []BootstrapAPIKey `mapstructure:"bootstrap_keys" validate:"dive"`
+}
+
+type BootstrapAPIKey struct {
+	ID     string   `mapstructure:"id" validate:"required"`
+	Name   string   `mapstructure:"name" validate:"required"`
+	Hash   string   `mapstructure:"hash" validate:"required,len=64,hexadecimal"`
+	Scopes []string `mapstructure:"scopes" validate:"required,min=1"`
+}
+
+// QueueConfig enables the persistent outbound queue behind ?async=true.
+// Jobs are stored in a bbolt file at Path and delivered by Workers goroutines.
+type QueueConfig struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	Path                string `mapstructure:"path" validate:"required_if=Enabled true"`
+	Workers             int    `mapstructure:"workers" validate:"min=0,max=64"`
+	MaxAttempts         int    `mapstructure:"max_attempts" validate:"min=0,max=100"`
+	BackoffSeconds      int    `mapstructure:"backoff_seconds" validate:"min=0,max=3600"`
+	MaxBackoffSeconds   int    `mapstructure:"max_backoff_seconds" validate:"min=0,max=86400"`
+	PollIntervalSeconds int    `mapstructure:"poll_interval_seconds" validate:"min=0,max=60"`
+	RetentionSeconds    int    `mapstructure:"retention_seconds" validate:"min=0,max=31536000"`
+}
+
+// SchedulerConfig enables sendAt/cron requests. Schedules are stored in a
+// bbolt file at Path and executed by Workers goroutines. A one-shot run that
+// fails without reaching GREEN-API is retried like a queued job.
+type SchedulerConfig struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	Path                string `mapstructure:"path" validate:"required_if=Enabled true"`
+	Workers             int    `mapstructure:"workers" validate:"min=0,max=64"`
+	PollIntervalSeconds int    `mapstructure:"poll_interval_seconds" validate:"min=0,max=60"`
+	MaxAttempts         int    `mapstructure:"max_attempts" validate:"min=0,max=100"`
+	BackoffSeconds      int    `mapstructure:"backoff_seconds" validate:"min=0,max=3600"`
+	MaxBackoffSeconds   int    `mapstructure:"max_backoff_seconds" validate:"min=0,max=86400"`
+}
+
+// MetricsConfig exposes Prometheus metrics on GET /metrics.
+type MetricsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// TracingConfig exports OpenTelemetry traces over OTLP/HTTP to Endpoint,
+// e.g. http://otel-collector:4318 (/v1/traces is added when no path is given).
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint" validate:"required_if=Enabled true,omitempty,url"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio" validate:"min=0,max=1"`
+}
+
+// HealthConfig tunes GET /ready. ProbeInstances are registered instance names
+// whose getStateInstance is called at most once per probe interval; a zero
+// MaxQueueDepth disables the queue depth check.
+type HealthConfig struct {
+	MaxQueueDepth        int      `mapstructure:"max_queue_depth" validate:"min=0"`
+	ProbeInstances       []string `mapstructure:"probe_instances" validate:"dive,required"`
+	ProbeIntervalSeconds int      `mapstructure:"probe_interval_seconds" validate:"min=0,max=3600"`
+	ProbeTimeoutSeconds  int      `mapstructure:"probe_timeout_seconds" validate:"min=0,max=60"`
 }
 
+// UploadConfig limits POST /api/v1/send-file. AllowedTypes are MIME types or
+// prefixes such as "image/" matched against the sniffed file content; an empty
+// list accepts any type.
+type UploadConfig struct {
+	MaxFileBytes   int64    `mapstructure:"max_file_bytes" validate:"min=0"`
+	TimeoutSeconds int      `mapstructure:"timeout_seconds" validate:"min=0,max=3600"`
+	AllowedTypes   []string `mapstructure:"allowed_types" validate:"dive,required"`
+}
+
+// URLPreflightConfig checks urlFile before sendFileByUrl: the host must
+// resolve to public addresses and a HEAD request must find the file within the
+// upload size and type limits.
+type URLPreflightConfig struct {
+	Enabled        bool `mapstructure:"enabled"`
+	TimeoutSeconds int  `mapstructure:"timeout_seconds" validate:"min=0,max=60"`
+}
+
+// RecipientsConfig checks personal chats with checkWhatsapp before
+// sendMessage. Answers are cached per instance and phone either way.
+type RecipientsConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	CacheTTLSeconds int  `mapstructure:"cache_ttl_seconds" validate:"min=0,max=604800"`
+	MaxEntries      int  `mapstructure:"max_entries" validate:"min=0,max=1000000"`
+}
+
+const (
+	defaultBreakerIdleTTL    = 10 * time.Minute
+	defaultMaxBreakers       = 10000
+	defaultIdempotencyTTL    = 24 * time.Hour
+	defaultIdempotentEntries = 100000
+	defaultReceiveTimeout    = 5 * time.Second
+	defaultPollErrorDelay    = 5 * time.Second
+	defaultQueueWorkers      = 4
+	defaultQueueAttempts     = 10
+	defaultQueueBackoff      = 2 * time.Second
+	defaultQueueMaxBackoff   = 5 * time.Minute
+	defaultQueuePollInterval = time.Second
+	defaultQueueRetention    = 7 * 24 * time.Hour
+	defaultSchedulerWorkers  = 2
+	defaultSchedulerPoll     = time.Second
+	defaultTracingService    = "green-api-backend"
+	defaultProbeInterval     = 30 * time.Second
+	defaultProbeTimeout      = 5 * time.Second
+	defaultMaxFileBytes      = 100 << 20
+	defaultUploadTimeout     = 5 * time.Minute
+	defaultPreflightTimeout  = 5 * time.Second
+	defaultRecipientTTL      = time.Hour
+	defaultRecipientEntries  = 100000
+	defaultRetryMaxDelay     = 30 * time.Second
+	defaultRetryMultiplier   = 2
+	defaultRetryBudget       = 30 * time.Second
+)
+
 type LoggingConfig struct {
 	Level  string `mapstructure:"level" validate:"required,oneof=debug info warn error"`
 	Format string `mapstructure:"format" validate:"required,eq=json"`
@@ -76,6 +316,15 @@ func Load(path string) (Config, error) {
 	if err := validate.Struct(cfg); err != nil {
 		return Config{}, fmt.Errorf("validate config: %w", err)
 	}
+	if cfg.GreenAPI.Retry.MaxRetries > 0 && cfg.GreenAPI.Retry.Delay() == 0 {
+		return Config{}, fmt.Errorf("validate config: green_api.retry.delay_ms or delay_seconds is required when max_retries is set")
+	}
+	if cfg.GreenAPI.Cassette.Enabled() && cfg.GreenAPI.Cassette.Path == "" {
+		return Config{}, fmt.Errorf("validate config: green_api.cassette.path is required in %s mode", cfg.GreenAPI.Cassette.Mode)
+	}
+	if cfg.Polling.Enabled && cfg.Polling.ReceiveTimeout() >= cfg.GreenAPI.Timeout() {
+		return Config{}, fmt.Errorf("validate config: polling.receive_timeout_seconds must be lower than green_api.timeout_seconds")
+	}
 
 	cfg.Validator = validate
 	return cfg, nil
@@ -101,10 +350,86 @@ func (g GreenAPIConfig) Timeout() time.Duration {
 	return time.Duration(g.TimeoutSeconds) * time.Second
 }
 
+// Decoding is the decode mode, lenient unless decode_mode says otherwise.
+func (g GreenAPIConfig) Decoding() string {
+	if g.DecodeMode == "" {
+		return "lenient"
+	}
+	return g.DecodeMode
+}
+
+// Enabled reports whether calls are recorded or replayed.
+func (c CassetteConfig) Enabled() bool {
+	return c.Mode == CassetteRecord || c.Mode == CassetteReplay
+}
+
+// Media is the host sendFileByUpload is called on. Without media_url it is
+// base_url with its "api" host label replaced by "media", so
+// https://1103.api.green-api.com becomes https://1103.media.green-api.com.
+func (g GreenAPIConfig) Media() string {
+	if g.MediaURL != "" {
+		return g.MediaURL
+	}
+	parsed, err := url.Parse(g.BaseURL)
+	if err != nil {
+		return g.BaseURL
+	}
+	labels := strings.Split(parsed.Hostname(), ".")
+	for i, label := range labels {
+		if label == "api" {
+			labels[i] = "media"
+			host := strings.Join(labels, ".")
+			if port := parsed.Port(); port != "" {
+				host = net.JoinHostPort(host, port)
+			}
+			parsed.Host = host
+			return parsed.String()
+		}
+	}
+	return g.BaseURL
+}
+
 func (r GreenAPIRetryConfig) Delay() time.Duration {
+	if r.DelayMS > 0 {
+		return time.Duration(r.DelayMS) * time.Millisecond
+	}
 	return time.Duration(r.DelaySeconds) * time.Second
 }
 
+func (r GreenAPIRetryConfig) StrategyName() string {
+	if r.Strategy == "" {
+		return RetryConstant
+	}
+	return r.Strategy
+}
+
+// MaxDelay caps the growing delays of the exponential strategies. A
+// Retry-After header may ask for longer.
+func (r GreenAPIRetryConfig) MaxDelay() time.Duration {
+	if r.MaxDelayMS == 0 {
+		return defaultRetryMaxDelay
+	}
+	return time.Duration(r.MaxDelayMS) * time.Millisecond
+}
+
+func (r GreenAPIRetryConfig) Factor() float64 {
+	if r.Multiplier == 0 {
+		return defaultRetryMultiplier
+	}
+	return r.Multiplier
+}
+
+func (r GreenAPIRetryConfig) Budget() time.Duration {
+	if r.BudgetMS == 0 {
+		return defaultRetryBudget
+	}
+	return time.Duration(r.BudgetMS) * time.Millisecond
+}
+
+func (r RateLimitConfig) MaxWait() time.Duration {
+	return time.Duration(r.MaxWaitMS) * time.Millisecond
+}
+
 func (c CircuitBreakerConfig) OpenTimeout() time.Duration {
 	return time.Duration(c.OpenTimeoutSeconds) * time.Second
 }
@@ -112,3 +437,208 @@ func (c CircuitBreakerConfig) OpenTimeout() time.Duration {
 func (c CircuitBreakerConfig) Interval() time.Duration {
 	return time.Duration(c.IntervalSeconds) * time.Second
 }
+
+// IdleTTL is how long a per-instance breaker may stay unused before it is dropped.
+func (c CircuitBreakerConfig) IdleTTL() time.Duration {
+	if c.IdleTTLSeconds == 0 {
+		return defaultBreakerIdleTTL
+	}
+	return time.Duration(c.IdleTTLSeconds) * time.Second
+}
+
+// Capacity bounds the number of breakers kept in memory at once.
+func (c CircuitBreakerConfig) Capacity() int {
+	if c.MaxBreakers == 0 {
+		return defaultMaxBreakers
+	}
+	return c.MaxBreakers
+}
+
+// TTL is how long a completed request is replayed for the same Idempotency-Key.
+func (i IdempotencyConfig) TTL() time.Duration {
+	if i.TTLSeconds == 0 {
+		return defaultIdempotencyTTL
+	}
+	return time.Duration(i.TTLSeconds) * time.Second
+}
+
+// Capacity bounds the number of remembered idempotency keys. Keys are kept
+// for their whole TTL; new keys are refused while the store is full.
+func (i IdempotencyConfig) Capacity() int {
+	if i.MaxEntries == 0 {
+		return defaultIdempotentEntries
+	}
+	return i.MaxEntries
+}
+
+// ReceiveTimeout is the long-poll duration passed to receiveNotification.
+func (p PollingConfig) ReceiveTimeout() time.Duration {
+	if p.ReceiveTimeoutSeconds == 0 {
+		return defaultReceiveTimeout
+	}
+	return time.Duration(p.ReceiveTimeoutSeconds) * time.Second
+}
+
+// ErrorDelay is the pause after a failed poll or handler before trying again.
+func (p PollingConfig) ErrorDelay() time.Duration {
+	if p.ErrorDelaySeconds == 0 {
+		return defaultPollErrorDelay
+	}
+	return time.Duration(p.ErrorDelaySeconds) * time.Second
+}
+
+// WorkerCount is the number of jobs delivered concurrently.
+func (q QueueConfig) WorkerCount() int {
+	if q.Workers == 0 {
+		return defaultQueueWorkers
+	}
+	return q.Workers
+}
+
+// Attempts is how many times a job is tried before it is marked failed.
+func (q QueueConfig) Attempts() int {
+	if q.MaxAttempts == 0 {
+		return defaultQueueAttempts
+	}
+	return q.MaxAttempts
+}
+
+// Backoff is the delay before the second attempt; it doubles after each retry.
+func (q QueueConfig) Backoff() time.Duration {
+	if q.BackoffSeconds == 0 {
+		return defaultQueueBackoff
+	}
+	return time.Duration(q.BackoffSeconds) * time.Second
+}
+
+func (q QueueConfig) MaxBackoff() time.Duration {
+	if q.MaxBackoffSeconds == 0 {
+		return defaultQueueMaxBackoff
+	}
+	return time.Duration(q.MaxBackoffSeconds) * time.Second
+}
+
+// PollInterval bounds how long an idle worker waits before checking for due retries.
+func (q QueueConfig) PollInterval() time.Duration {
+	if q.PollIntervalSeconds == 0 {
+		return defaultQueuePollInterval
+	}
+	return time.Duration(q.PollIntervalSeconds) * time.Second
+}
+
+// Retention is how long finished jobs remain available via GET /jobs/:id.
+func (q QueueConfig) Retention() time.Duration {
+	if q.RetentionSeconds == 0 {
+		return defaultQueueRetention
+	}
+	return time.Duration(q.RetentionSeconds) * time.Second
+}
+
+// WorkerCount is the number of schedules executed concurrently.
+func (s SchedulerConfig) WorkerCount() int {
+	if s.Workers == 0 {
+		return defaultSchedulerWorkers
+	}
+	return s.Workers
+}
+
+// PollInterval bounds how late a schedule may start after its run time.
+func (s SchedulerConfig) PollInterval() time.Duration {
+	if s.PollIntervalSeconds == 0 {
+		return defaultSchedulerPoll
+	}
+	return time.Duration(s.PollIntervalSeconds) * time.Second
+}
+
+// Attempts is how many times a one-shot schedule runs before it is marked
+// failed.
+func (s SchedulerConfig) Attempts() int {
+	if s.MaxAttempts == 0 {
+		return defaultQueueAttempts
+	}
+	return s.MaxAttempts
+}
+
+// Backoff is the delay before a one-shot schedule runs again; it doubles
+// after each retry.
+func (s SchedulerConfig) Backoff() time.Duration {
+	if s.BackoffSeconds == 0 {
+		return defaultQueueBackoff
+	}
+	return time.Duration(s.BackoffSeconds) * time.Second
+}
+
+func (s SchedulerConfig) MaxBackoff() time.Duration {
+	if s.MaxBackoffSeconds == 0 {
+		return defaultQueueMaxBackoff
+	}
+	return time.Duration(s.MaxBackoffSeconds) * time.Second
+}
+
+// Service is the service.name resource attribute of exported spans.
+func (t TracingConfig) Service() string {
+	if t.ServiceName == "" {
+		return defaultTracingService
+	}
+	return t.ServiceName
+}
+
+// Sampling is the fraction of new traces that are recorded. Traces started
+// upstream keep the caller's sampling decision.
+func (t TracingConfig) Sampling() float64 {
+	if t.SampleRatio == 0 {
+		return 1
+	}
+	return t.SampleRatio
+}
+
+func (h HealthConfig) ProbeInterval() time.Duration {
+	if h.ProbeIntervalSeconds == 0 {
+		return defaultProbeInterval
+	}
+	return time.Duration(h.ProbeIntervalSeconds) * time.Second
+}
+
+func (h HealthConfig) ProbeTimeout() time.Duration {
+	if h.ProbeTimeoutSeconds == 0 {
+		return defaultProbeTimeout
+	}
+	return time.Duration(h.ProbeTimeoutSeconds) * time.Second
+}
+
+func (u UploadConfig) MaxBytes() int64 {
+	if u.MaxFileBytes == 0 {
+		return defaultMaxFileBytes
+	}
+	return u.MaxFileBytes
+}
+
+func (u UploadConfig) Timeout() time.Duration {
+	if u.TimeoutSeconds == 0 {
+		return defaultUploadTimeout
+	}
+	return time.Duration(u.TimeoutSeconds) * time.Second
+}
+
+func (p URLPreflightConfig) Timeout() time.Duration {
+	if p.TimeoutSeconds == 0 {
+		return defaultPreflightTimeout
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// CacheTTL is how long a checkWhatsapp answer is reused.
+func (r RecipientsConfig) CacheTTL() time.Duration {
+	if r.CacheTTLSeconds == 0 {
+		return defaultRecipientTTL
+	}
+	return time.Duration(r.CacheTTLSeconds) * time.Second
+}
+
+// Capacity bounds the number of cached checkWhatsapp answers.
+func (r RecipientsConfig) Capacity() int {
+	if r.MaxEntries == 0 {
+		return defaultRecipientEntries
+	}
+	return r.MaxEntries
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +66,170 @@ server:
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "validate config")
 }
+
+func TestLoad_PollingTimeoutMustFitClientTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(cfgPath, []byte(`
+server:
+  host: 0.0.0.0
+  port: 8080
+  read_timeout_seconds: 15
+  write_timeout_seconds: 15
+  shutdown_timeout_seconds: 10
+cors:
+  allowed_origins:
+    - http://localhost:5000
+green_api:
+  base_url: https://api.green-api.com
+  timeout_seconds: 10
+  retry:
+    max_retries: 2
+    delay_seconds: 1
+  circuit_breaker:
+    name: green-api
+    consecutive_failures: 5
+    half_open_max_requests: 1
+    open_timeout_seconds: 30
+    interval_seconds: 60
+    failure_ratio: 0.5
+    min_requests: 5
+polling:
+  enabled: true
+  receive_timeout_seconds: 20
+  instances:
+    - id_instance: "1101000001"
+      api_token_instance: token
+logging:
+  level: info
+  format: json
+`), 0o644)
+	require.NoError(t, err)
+
+	_, err = Load(cfgPath)
+	require.ErrorContains(t, err, "receive_timeout_seconds")
+}
+
+func TestGreenAPIConfig_Media(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		cfg  GreenAPIConfig
+		want string
+	}{
+		{name: "shared host", cfg: GreenAPIConfig{BaseURL: "https://api.green-api.com"}, want: "https://media.green-api.com"},
+		{name: "instance host", cfg: GreenAPIConfig{BaseURL: "https://1103.api.green-api.com"}, want: "https://1103.media.green-api.com"},
+		{name: "explicit", cfg: GreenAPIConfig{BaseURL: "https://api.green-api.com", MediaURL: "https://media.example.com"}, want: "https://media.example.com"},
+		{name: "no api label", cfg: GreenAPIConfig{BaseURL: "http://127.0.0.1:9000"}, want: "http://127.0.0.1:9000"},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, tc.cfg.Media(), tc.name)
+	}
+}
+
+func TestLoad_RetryStrategy(t *testing.T) {
+	t.Parallel()
+
+	write := func(retry string) string {
+		cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(cfgPath, []byte(`
+server:
+  host: 0.0.0.0
+  port: 8080
+  read_timeout_seconds: 15
+  write_timeout_seconds: 15
+  shutdown_timeout_seconds: 10
+cors:
+  allowed_origins:
+    - http://localhost:5000
+green_api:
+  base_url: https://api.green-api.com
+  timeout_seconds: 10
+  retry:
+`+retry+`
+  circuit_breaker:
+    name: green-api
+    consecutive_failures: 5
+    half_open_max_requests: 1
+    open_timeout_seconds: 30
+    interval_seconds: 60
+    failure_ratio: 0.5
+    min_requests: 5
+logging:
+  level: info
+  format: json
+`), 0o644)
+		require.NoError(t, err)
+		return cfgPath
+	}
+
+	cfg, err := Load(write("    max_retries: 3\n    strategy: decorrelated_jitter\n    delay_ms: 250\n    max_delay_ms: 4000\n    budget_ms: 10000"))
+	require.NoError(t, err)
+	require.Equal(t, RetryDecorrelatedJitter, cfg.GreenAPI.Retry.StrategyName())
+	require.Equal(t, 250*time.Millisecond, cfg.GreenAPI.Retry.Delay())
+	require.Equal(t, 4*time.Second, cfg.GreenAPI.Retry.MaxDelay())
+	require.Equal(t, 10*time.Second, cfg.GreenAPI.Retry.Budget())
+
+	_, err = Load(write("    max_retries: 3\n    strategy: linear\n    delay_ms: 250"))
+	require.ErrorContains(t, err, "Strategy")
+
+	_, err = Load(write("    max_retries: 3"))
+	require.ErrorContains(t, err, "delay_ms or delay_seconds")
+}
+
+func TestLoad_CassetteNeedsPath(t *testing.T) {
+	t.Parallel()
+
+	write := func(cassette string) string {
+		cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(cfgPath, []byte(`
+server:
+  host: 0.0.0.0
+  port: 8080
+  read_timeout_seconds: 15
+  write_timeout_seconds: 15
+  shutdown_timeout_seconds: 10
+cors:
+  allowed_origins:
+    - http://localhost:5000
+green_api:
+  base_url: https://api.green-api.com
+  timeout_seconds: 10
+  retry:
+    max_retries: 2
+    delay_seconds: 1
+  circuit_breaker:
+    name: green-api
+    consecutive_failures: 5
+    half_open_max_requests: 1
+    open_timeout_seconds: 30
+    interval_seconds: 60
+    failure_ratio: 0.5
+    min_requests: 5
+  cassette:
+`+cassette+`
+logging:
+  level: info
+  format: json
+`), 0o644)
+		require.NoError(t, err)
+		return cfgPath
+	}
+
+	cfg, err := Load(write("    mode: replay\n    path: testdata/demo.yaml"))
+	require.NoError(t, err)
+	require.True(t, cfg.GreenAPI.Cassette.Enabled())
+
+	cfg, err = Load(write("    mode: passthrough"))
+	require.NoError(t, err)
+	require.False(t, cfg.GreenAPI.Cassette.Enabled())
+
+	_, err = Load(write("    mode: record"))
+	require.ErrorContains(t, err, "green_api.cassette.path")
+
+	_, err = Load(write("    mode: rewind\n    path: demo.yaml"))
+	require.ErrorContains(t, err, "Mode")
+}
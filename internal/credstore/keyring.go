@@ -0,0 +1,165 @@
+package credstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const keySize = 32
+
+var ErrUnknownKey = errors.New("master key not found in keyring")
+
+// Keyring holds the master keys used to wrap record keys. The first key is
+// the primary one: new and rotated records are wrapped with it, the others
+// are kept only to read records written before a rotation.
+type Keyring struct {
+	keys []masterKey
+}
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewKeyring builds a keyring from raw 32-byte keys, primary first.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one master key is required")
+	}
+
+	keyring := &Keyring{}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		id := keyID(key)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		keyring.keys = append(keyring.keys, masterKey{id: id, aead: aead})
+	}
+	return keyring, nil
+}
+
+// LoadKeyring reads base64 master keys from the env variable (when set) and
+// then from files, in order; the first key found becomes primary.
+func LoadKeyring(envName string, files []string) (*Keyring, error) {
+	var encoded []string
+	if envName != "" {
+		if value := strings.TrimSpace(os.Getenv(envName)); value != "" {
+			encoded = append(encoded, value)
+		}
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read master key file: %w", err)
+		}
+		encoded = append(encoded, strings.TrimSpace(string(data)))
+	}
+
+	keys := make([][]byte, 0, len(encoded))
+	for _, value := range encoded {
+		key, err := DecodeKey(value)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(keys...)
+}
+
+// GenerateKey returns a new random master key encoded as base64.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("generate master key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key must be base64: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// PrimaryID identifies the key new records are wrapped with.
+func (k *Keyring) PrimaryID() string {
+	return k.keys[0].id
+}
+
+func (k *Keyring) wrap(dataKey []byte) (string, []byte, error) {
+	primary := k.keys[0]
+	sealed, err := seal(primary.aead, dataKey, []byte(primary.id))
+	if err != nil {
+		return "", nil, err
+	}
+	return primary.id, sealed, nil
+}
+
+func (k *Keyring) unwrap(id string, sealed []byte) ([]byte, error) {
+	for _, key := range k.keys {
+		if key.id == id {
+			return open(key.aead, sealed, []byte(id))
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("init aes: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("init gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts plaintext and prefixes the random nonce to the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,305 @@
+package credstore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"green-api/internal/instance"
+)
+
+const fileVersion = 1
+
+// Store keeps GREEN-API credentials encrypted at rest in a JSON file.
+//
+// Every record has its own random data key that encrypts the token with
+// AES-GCM; the data key itself is wrapped with the primary master key of the
+// Keyring. The file is re-read whenever it changes on disk, so records added
+// or rotated by the credstore command are picked up without a restart.
+type Store struct {
+	path    string
+	keyring *Keyring
+
+	mu      sync.Mutex
+	records map[string]record
+	modTime time.Time
+	size    int64
+}
+
+type storeFile struct {
+	Version int      `json:"version"`
+	Records []record `json:"records"`
+}
+
+type record struct {
+	Name       string    `json:"name"`
+	IDInstance string    `json:"idInstance"`
+	KeyID      string    `json:"keyId"`
+	WrappedKey []byte    `json:"wrappedKey"`
+	Ciphertext []byte    `json:"ciphertext"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Open loads the store at path. A missing file is treated as an empty store
+// and is created on the first write.
+func Open(path string, keyring *Keyring) (*Store, error) {
+	store := &Store{path: path, keyring: keyring, records: make(map[string]record)}
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *Store) Resolve(_ context.Context, name string) (instance.Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return instance.Credentials{}, err
+	}
+
+	rec, ok := s.records[strings.TrimSpace(name)]
+	if !ok {
+		return instance.Credentials{}, fmt.Errorf("%w: %s", instance.ErrNotFound, name)
+	}
+
+	token, err := s.decrypt(rec)
+	if err != nil {
+		return instance.Credentials{}, fmt.Errorf("decrypt credentials %q: %w", rec.Name, err)
+	}
+	return instance.Credentials{IDInstance: rec.IDInstance, APITokenInstance: token}, nil
+}
+
+func (s *Store) List(context.Context) ([]instance.Summary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]instance.Summary, 0, len(s.records))
+	for _, rec := range s.records {
+		summaries = append(summaries, instance.Summary{Name: rec.Name, IDInstance: rec.IDInstance})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+// Put adds or replaces the credentials stored under name.
+func (s *Store) Put(name string, creds instance.Credentials) error {
+	name = strings.TrimSpace(name)
+	if name == "" || creds.IDInstance == "" || creds.APITokenInstance == "" {
+		return fmt.Errorf("name, idInstance and apiTokenInstance are required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	rec, err := s.encrypt(name, creds)
+	if err != nil {
+		return err
+	}
+	s.records[name] = rec
+	return s.persist()
+}
+
+// Delete removes the credentials stored under name.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if _, ok := s.records[name]; !ok {
+		return fmt.Errorf("%w: %s", instance.ErrNotFound, name)
+	}
+	delete(s.records, name)
+	return s.persist()
+}
+
+// Rotate re-encrypts every record with a fresh data key wrapped by the
+// primary master key and returns the number of records rewritten. Readers
+// keep working throughout because the file is replaced atomically and the
+// previous master key stays in their keyring until rotation is finished.
+func (s *Store) Rotate() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.reload(); err != nil {
+		return 0, err
+	}
+
+	rotated := make(map[string]record, len(s.records))
+	for name, rec := range s.records {
+		token, err := s.decrypt(rec)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt credentials %q: %w", name, err)
+		}
+		fresh, err := s.encrypt(name, instance.Credentials{IDInstance: rec.IDInstance, APITokenInstance: token})
+		if err != nil {
+			return 0, err
+		}
+		rotated[name] = fresh
+	}
+
+	s.records = rotated
+	if err := s.persist(); err != nil {
+		return 0, err
+	}
+	return len(rotated), nil
+}
+
+func (s *Store) encrypt(name string, creds instance.Credentials) (record, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return record{}, fmt.Errorf("generate data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return record{}, err
+	}
+	ciphertext, err := seal(aead, []byte(creds.APITokenInstance), recordAAD(name, creds.IDInstance))
+	if err != nil {
+		return record{}, err
+	}
+
+	keyID, wrapped, err := s.keyring.wrap(dataKey)
+	if err != nil {
+		return record{}, err
+	}
+
+	return record{
+		Name:       name,
+		IDInstance: creds.IDInstance,
+		KeyID:      keyID,
+		WrappedKey: wrapped,
+		Ciphertext: ciphertext,
+		UpdatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+func (s *Store) decrypt(rec record) (string, error) {
+	dataKey, err := s.keyring.unwrap(rec.KeyID, rec.WrappedKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	token, err := open(aead, rec.Ciphertext, recordAAD(rec.Name, rec.IDInstance))
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// recordAAD binds the ciphertext to its name and idInstance so that records
+// cannot be swapped inside the file.
+func recordAAD(name, idInstance string) []byte {
+	return []byte(name + "\x00" + idInstance)
+}
+
+// reload re-reads the file when its size or modification time changed.
+// The caller must hold s.mu.
+func (s *Store) reload() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.records = make(map[string]record)
+		s.modTime, s.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat credential store: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("read credential store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("decode credential store: %w", err)
+	}
+	if file.Version != fileVersion {
+		return fmt.Errorf("unsupported credential store version %d", file.Version)
+	}
+
+	records := make(map[string]record, len(file.Records))
+	for _, rec := range file.Records {
+		records[rec.Name] = rec
+	}
+	s.records = records
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// persist atomically replaces the file with the current records.
+// The caller must hold s.mu.
+func (s *Store) persist() error {
+	file := storeFile{Version: fileVersion, Records: make([]record, 0, len(s.records))}
+	for _, rec := range s.records {
+		file.Records = append(file.Records, rec)
+	}
+	sort.Slice(file.Records, func(i, j int) bool { return file.Records[i].Name < file.Records[j].Name })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode credential store: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create credential store dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp credential store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod credential store: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write credential store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync credential store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close credential store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace credential store: %w", err)
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("stat credential store: %w", err)
+	}
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,138 @@
+package credstore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/instance"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+
+	encoded, err := GenerateKey()
+	require.NoError(t, err)
+	key, err := DecodeKey(encoded)
+	require.NoError(t, err)
+	return key
+}
+
+func testKeyring(t *testing.T, keys ...[]byte) *Keyring {
+	t.Helper()
+
+	keyring, err := NewKeyring(keys...)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestStore_PutResolveKeepsTokenEncrypted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store, err := Open(path, testKeyring(t, testKey(t)))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put("sales", instance.Credentials{IDInstance: "1101000001", APITokenInstance: "super-secret-token"}))
+
+	creds, err := store.Resolve(context.Background(), "sales")
+	require.NoError(t, err)
+	require.Equal(t, "super-secret-token", creds.APITokenInstance)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "super-secret-token")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = store.Resolve(context.Background(), "unknown")
+	require.ErrorIs(t, err, instance.ErrNotFound)
+}
+
+func TestStore_RotateReencryptsWithPrimaryKey(t *testing.T) {
+	t.Parallel()
+
+	oldKey, newKey := testKey(t), testKey(t)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	writer, err := Open(path, testKeyring(t, oldKey))
+	require.NoError(t, err)
+	require.NoError(t, writer.Put("sales", instance.Credentials{IDInstance: "1", APITokenInstance: "token-1"}))
+	require.NoError(t, writer.Put("support", instance.Credentials{IDInstance: "2", APITokenInstance: "token-2"}))
+
+	// The running server already knows both keys during the rotation window.
+	server, err := Open(path, testKeyring(t, newKey, oldKey))
+	require.NoError(t, err)
+
+	rotator, err := Open(path, testKeyring(t, newKey, oldKey))
+	require.NoError(t, err)
+	rotated, err := rotator.Rotate()
+	require.NoError(t, err)
+	require.Equal(t, 2, rotated)
+
+	creds, err := server.Resolve(context.Background(), "support")
+	require.NoError(t, err)
+	require.Equal(t, "token-2", creds.APITokenInstance)
+
+	newOnly, err := Open(path, testKeyring(t, newKey))
+	require.NoError(t, err)
+	creds, err = newOnly.Resolve(context.Background(), "sales")
+	require.NoError(t, err)
+	require.Equal(t, "token-1", creds.APITokenInstance)
+
+	oldOnly, err := Open(path, testKeyring(t, oldKey))
+	require.NoError(t, err)
+	_, err = oldOnly.Resolve(context.Background(), "sales")
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestStore_RejectsSwappedRecords(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	store, err := Open(path, testKeyring(t, testKey(t)))
+	require.NoError(t, err)
+	require.NoError(t, store.Put("sales", instance.Credentials{IDInstance: "1", APITokenInstance: "token-1"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var file storeFile
+	require.NoError(t, json.Unmarshal(data, &file))
+	file.Records[0].IDInstance = "2"
+	data, err = json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	reader, err := Open(path, store.keyring)
+	require.NoError(t, err)
+	_, err = reader.Resolve(context.Background(), "sales")
+	require.Error(t, err)
+}
+
+func TestLoadKeyring_EnvThenFiles(t *testing.T) {
+	primary, err := GenerateKey()
+	require.NoError(t, err)
+	secondary, err := GenerateKey()
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(t.TempDir(), "master.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(secondary+"\n"), 0o600))
+	t.Setenv("TEST_CREDSTORE_MASTER_KEY", primary)
+
+	keyring, err := LoadKeyring("TEST_CREDSTORE_MASTER_KEY", []string{keyFile})
+	require.NoError(t, err)
+
+	primaryKey, err := DecodeKey(primary)
+	require.NoError(t, err)
+	require.Equal(t, keyID(primaryKey), keyring.PrimaryID())
+	require.Len(t, keyring.keys, 2)
+
+	_, err = LoadKeyring("", nil)
+	require.Error(t, err)
+}
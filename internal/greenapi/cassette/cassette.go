@@ -0,0 +1,345 @@
+// Package cassette records GREEN-API exchanges to a file and replays them, so
+// tests and demos run deterministically without GREEN-API. Plug a Transport
+// into the client with greenapi.WithTransport.
+//
+// Interactions are keyed by HTTP method and normalized path: idInstance and
+// apiTokenInstance are replaced by placeholders and the query is sorted, so a
+// cassette recorded with one instance replays for any other. Tokens and the
+// phone numbers of personal chats are scrubbed before anything is written.
+package cassette
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+
+	"green-api/internal/greenapi"
+)
+
+// Mode selects what a Transport does with a request.
+type Mode string
+
+const (
+	// Passthrough sends every request upstream and records nothing.
+	Passthrough Mode = "passthrough"
+	// Record sends every request upstream and appends the exchange to the
+	// cassette file.
+	Record Mode = "record"
+	// Replay answers from the cassette file and never calls upstream.
+	Replay Mode = "replay"
+)
+
+// ErrNotRecorded is returned in replay mode for a request the cassette has no
+// interaction for.
+var ErrNotRecorded = errors.New("cassette has no recorded interaction")
+
+// recordedHeaders are the only response headers kept in a cassette.
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Cassette is the file format.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is one recorded exchange.
+type Interaction struct {
+	Method   string   `json:"method" yaml:"method"`
+	Path     string   `json:"path" yaml:"path"`
+	Request  Request  `json:"request" yaml:"request"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+// Request keeps the JSON body of a request for reference; it is not used for
+// matching.
+type Request struct {
+	Body string `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int               `json:"status" yaml:"status"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    string            `json:"body" yaml:"body"`
+}
+
+// Transport is an http.RoundTripper in one of the three modes.
+type Transport struct {
+	mode Mode
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	// replay cursors: index of the next interaction per key.
+	cursors map[string]int
+	// key pseudonymizes phone numbers while recording.
+	key []byte
+}
+
+// New returns a Transport that sends requests through next (Passthrough and
+// Record) or answers them from the cassette at path (Replay). In record mode
+// an existing cassette is replaced.
+func New(mode Mode, path string, next http.RoundTripper) (*Transport, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &Transport{mode: mode, path: path, next: next, cursors: make(map[string]int)}
+
+	switch mode {
+	case Passthrough:
+	case Record:
+		t.key = make([]byte, 32)
+		if _, err := rand.Read(t.key); err != nil {
+			return nil, fmt.Errorf("cassette key: %w", err)
+		}
+		if err := t.save(); err != nil {
+			return nil, err
+		}
+	case Replay:
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		t.cassette = cassette
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return t, nil
+}
+
+// Load reads a cassette file.
+func Load(path string) (Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Cassette{}, fmt.Errorf("read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if isJSON(path) {
+		err = json.Unmarshal(data, &cassette)
+	} else {
+		err = yaml.Unmarshal(data, &cassette)
+	}
+	if err != nil {
+		return Cassette{}, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch t.mode {
+	case Replay:
+		return t.replay(req)
+	case Record:
+		return t.record(req)
+	default:
+		return t.next.RoundTrip(req)
+	}
+}
+
+// replay answers with the next interaction recorded for the request. Once
+// they are used up the last one is repeated, so polling loops keep working.
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	method, path := req.Method, NormalizePath(req.URL)
+	key := method + " " + path
+
+	t.mu.Lock()
+	var matches []Interaction
+	for _, interaction := range t.cassette.Interactions {
+		if interaction.Method == method && interaction.Path == path {
+			matches = append(matches, interaction)
+		}
+	}
+	if len(matches) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s", ErrNotRecorded, key)
+	}
+	next := t.cursors[key]
+	t.cursors[key] = next + 1
+	t.mu.Unlock()
+
+	recorded := matches[min(next, len(matches)-1)].Response
+	header := make(http.Header, len(recorded.Headers))
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record sends req upstream and appends the scrubbed exchange to the file.
+// Only JSON request bodies are kept; streamed uploads pass through untouched.
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil && isJSONContent(req.Header.Get("Content-Type")) {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		requestBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Method:   req.Method,
+		Path:     NormalizePath(req.URL),
+		Request:  Request{Body: t.scrub(requestBody)},
+		Response: Response{Status: resp.StatusCode, Body: t.scrub(responseBody)},
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			if interaction.Response.Headers == nil {
+				interaction.Response.Headers = make(map[string]string)
+			}
+			interaction.Response.Headers[name] = value
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction)
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// save rewrites the whole cassette; callers hold t.mu or own t exclusively.
+func (t *Transport) save() error {
+	var (
+		data []byte
+		err  error
+	)
+	if isJSON(t.path) {
+		data, err = json.MarshalIndent(t.cassette, "", "  ")
+	} else {
+		data, err = yaml.Marshal(t.cassette)
+	}
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0o600); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+var (
+	// instancePathPattern splits /waInstance{id}/{method}/{token}/rest.
+	instancePathPattern = regexp.MustCompile(`^/waInstance[^/]*/([^/]+)/[^/]+(/.*)?$`)
+	// personalChatPattern matches personal chat IDs and wids.
+	personalChatPattern = regexp.MustCompile(`\b(\d{6,15})@c\.us\b`)
+	// phoneNumberPattern matches phoneNumber fields, quoted or not.
+	phoneNumberPattern = regexp.MustCompile(`("phoneNumber"\s*:\s*"?)(\d{6,15})`)
+	// secretFieldPattern matches webhookUrlToken and authorization code
+	// string values.
+	secretFieldPattern = regexp.MustCompile(`("(?:webhookUrlToken|code)"\s*:\s*")((?:[^"\\]|\\.)*)(")`)
+	// qrCodePattern tells a qr response carrying an image from the others.
+	qrCodePattern = regexp.MustCompile(`"type"\s*:\s*"qrCode"`)
+	// qrMessagePattern matches the message of a qr response.
+	qrMessagePattern = regexp.MustCompile(`("message"\s*:\s*")(?:[^"\\]|\\.)*(")`)
+)
+
+// qrPlaceholder is a 1x1 PNG recorded instead of a real QR code, so a
+// replayed qr response still decodes.
+const qrPlaceholder = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAACklEQVR4nGP4DwABAQEAsTj2FAAAAABJRU5ErkJggg=="
+
+// NormalizePath is the cassette key of u without the method: the path with
+// idInstance and apiTokenInstance replaced and the query sorted.
+func NormalizePath(u *url.URL) string {
+	path := instancePathPattern.ReplaceAllString(u.Path, "/waInstance{idInstance}/$1/{apiTokenInstance}$2")
+	if query := u.Query().Encode(); query != "" {
+		path += "?" + query
+	}
+	return path
+}
+
+// scrub removes tokens, webhookUrlToken values, authorization codes and QR
+// images, and replaces phone numbers with stable pseudonyms of the same
+// length, so one number maps to the same fake number within a recording
+// session.
+func (t *Transport) scrub(body []byte) string {
+	s := greenapi.Redact(string(body))
+	s = secretFieldPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := secretFieldPattern.FindStringSubmatch(match)
+		if parts[2] == "" {
+			return match
+		}
+		return parts[1] + greenapi.RedactedToken + parts[3]
+	})
+	if qrCodePattern.MatchString(s) {
+		s = qrMessagePattern.ReplaceAllString(s, "${1}"+qrPlaceholder+"${2}")
+	}
+	s = personalChatPattern.ReplaceAllStringFunc(s, func(match string) string {
+		digits := strings.TrimSuffix(match, "@c.us")
+		return t.pseudonym(digits) + "@c.us"
+	})
+	return phoneNumberPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := phoneNumberPattern.FindStringSubmatch(match)
+		return parts[1] + t.pseudonym(parts[2])
+	})
+}
+
+func (t *Transport) pseudonym(digits string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(digits))
+	sum := mac.Sum(nil)
+
+	fake := make([]byte, len(digits))
+	for i := range fake {
+		fake[i] = '0' + sum[i%len(sum)]%10
+	}
+	return string(fake)
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+func isJSONContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
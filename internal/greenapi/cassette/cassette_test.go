@@ -0,0 +1,234 @@
+package cassette_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/greenapi"
+	"green-api/internal/greenapi/cassette"
+	"green-api/internal/greenapi/fake"
+)
+
+const (
+	idInstance = "1101000001"
+	token      = "d75b3a66374942c5b3c019c698abc2067e151558acbd412c"
+	phone      = "79991234567"
+)
+
+func newClient(t *testing.T, baseURL string, transport http.RoundTripper) *greenapi.Client {
+	t.Helper()
+
+	return greenapi.NewClient(config.GreenAPIConfig{
+		BaseURL:        baseURL,
+		MediaURL:       baseURL,
+		TimeoutSeconds: 2,
+		CircuitBreaker: config.CircuitBreakerConfig{
+			Name:                "cassette",
+			ConsecutiveFailures: 50,
+			HalfOpenMaxRequests: 1,
+			OpenTimeoutSeconds:  60,
+			IntervalSeconds:     60,
+			FailureRatio:        1,
+			MinRequests:         100,
+		},
+	}, zap.NewNop(), greenapi.WithTransport(transport))
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	t.Parallel()
+
+	for _, file := range []string{"cassette.yaml", "cassette.json"} {
+		t.Run(file, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), file)
+			ctx := context.Background()
+
+			server := fake.New()
+			server.AddInstance(idInstance, token)
+			upstream := httptest.NewServer(server)
+			t.Cleanup(func() {
+				server.Close()
+				upstream.Close()
+			})
+
+			recorder, err := cassette.New(cassette.Record, path, http.DefaultTransport)
+			require.NoError(t, err)
+			recording := newClient(t, upstream.URL, recorder)
+
+			first, err := recording.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: phone + "@c.us", Message: "one"})
+			require.NoError(t, err)
+			second, err := recording.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: phone + "@c.us", Message: "two"})
+			require.NoError(t, err)
+			settings, err := recording.GetSettings(ctx, idInstance, token)
+			require.NoError(t, err)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NotContains(t, string(data), token)
+			require.NotContains(t, string(data), idInstance)
+			require.NotContains(t, string(data), phone)
+			require.Contains(t, string(data), "/waInstance{idInstance}/sendMessage/{apiTokenInstance}")
+
+			player, err := cassette.New(cassette.Replay, path, nil)
+			require.NoError(t, err)
+			// Nothing listens on the replaying client's base URL: every answer
+			// comes from the file, whatever instance asks.
+			replaying := newClient(t, "http://127.0.0.1:1", player)
+
+			replayed, err := replaying.SendMessage(ctx, "1101000002", "other-token", greenapi.SendMessageParams{ChatID: "70000000000@c.us", Message: "one"})
+			require.NoError(t, err)
+			require.Equal(t, first.StatusCode, replayed.StatusCode)
+			require.JSONEq(t, string(first.Body), string(replayed.Body))
+
+			replayed, err = replaying.SendMessage(ctx, "1101000002", "other-token", greenapi.SendMessageParams{ChatID: "70000000000@c.us", Message: "two"})
+			require.NoError(t, err)
+			require.JSONEq(t, string(second.Body), string(replayed.Body))
+
+			// Exhausted: the last recorded answer repeats.
+			replayed, err = replaying.SendMessage(ctx, "1101000002", "other-token", greenapi.SendMessageParams{ChatID: "70000000000@c.us", Message: "three"})
+			require.NoError(t, err)
+			require.JSONEq(t, string(second.Body), string(replayed.Body))
+
+			replayedSettings, err := replaying.GetSettings(ctx, "1101000002", "other-token")
+			require.NoError(t, err)
+			var recorded, got greenapi.Settings
+			require.NoError(t, greenapi.Decode(settings, greenapi.DecodeStrict, &recorded))
+			require.NoError(t, greenapi.Decode(replayedSettings, greenapi.DecodeStrict, &got))
+			require.NotEqual(t, recorded.WID, got.WID, "wid is pseudonymized")
+			require.Len(t, got.WID, len(recorded.WID))
+			require.Equal(t, recorded.TypeAccount, got.TypeAccount)
+
+			_, err = replaying.GetStateInstance(ctx, "1101000002", "other-token")
+			require.ErrorIs(t, err, cassette.ErrNotRecorded)
+		})
+	}
+}
+
+func TestCassette_RecordScrubsSecrets(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	ctx := context.Background()
+
+	server := fake.New()
+	server.AddInstance(idInstance, token)
+	upstream := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Close()
+		upstream.Close()
+	})
+
+	recorder, err := cassette.New(cassette.Record, path, http.DefaultTransport)
+	require.NoError(t, err)
+	recording := newClient(t, upstream.URL, recorder)
+
+	const webhookToken = "whsec-5f1c0e7a9b"
+	secret := webhookToken
+	_, err = recording.SetSettings(ctx, idInstance, token, greenapi.InstanceSettings{WebhookURLToken: &secret})
+	require.NoError(t, err)
+	resp, err := recording.GetSettings(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.Contains(t, string(resp.Body), webhookToken)
+
+	require.True(t, server.SetState(idInstance, "notAuthorized"))
+	resp, err = recording.QR(ctx, idInstance, token)
+	require.NoError(t, err)
+	var qr greenapi.QR
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &qr))
+	require.Equal(t, greenapi.QRCode, qr.Type)
+	resp, err = recording.GetAuthorizationCode(ctx, idInstance, token, 79991234567)
+	require.NoError(t, err)
+	var code greenapi.AuthorizationCode
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &code))
+	require.NotEmpty(t, code.Code)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), webhookToken)
+	require.NotContains(t, string(data), code.Code)
+	require.NotContains(t, string(data), qr.Message)
+	require.Contains(t, string(data), greenapi.RedactedToken)
+
+	player, err := cassette.New(cassette.Replay, path, nil)
+	require.NoError(t, err)
+	resp, err = newClient(t, "http://127.0.0.1:1", player).QR(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &qr))
+	image, err := qr.PNG()
+	require.NoError(t, err)
+	require.NotEmpty(t, image, "the placeholder still decodes")
+}
+
+func TestCassette_PassthroughRecordsNothing(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+	server := fake.New()
+	server.AddInstance(idInstance, token)
+	upstream := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Close()
+		upstream.Close()
+	})
+
+	transport, err := cassette.New(cassette.Passthrough, path, http.DefaultTransport)
+	require.NoError(t, err)
+	_, err = newClient(t, upstream.URL, transport).GetStateInstance(context.Background(), idInstance, token)
+	require.NoError(t, err)
+
+	require.Equal(t, 1, server.Calls("getStateInstance"))
+	require.NoFileExists(t, path)
+}
+
+func TestCassette_ReplayNeedsFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := cassette.New(cassette.Replay, filepath.Join(t.TempDir(), "missing.yaml"), nil)
+	require.Error(t, err)
+}
+
+func TestNormalizePath(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "instance path",
+			url:  "https://api.green-api.com/waInstance1101000001/getSettings/secret",
+			want: "/waInstance{idInstance}/getSettings/{apiTokenInstance}",
+		},
+		{
+			name: "extra segments are kept",
+			url:  "https://api.green-api.com/waInstance1101000001/deleteNotification/secret/42",
+			want: "/waInstance{idInstance}/deleteNotification/{apiTokenInstance}/42",
+		},
+		{
+			name: "query is sorted",
+			url:  "https://api.green-api.com/waInstance1101000001/receiveNotification/secret?b=2&a=1",
+			want: "/waInstance{idInstance}/receiveNotification/{apiTokenInstance}?a=1&b=2",
+		},
+		{
+			name: "other paths are kept",
+			url:  "https://api.green-api.com/health",
+			want: "/health",
+		},
+	}
+	for _, tc := range cases {
+		u, err := url.Parse(tc.url)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.want, cassette.NormalizePath(u), tc.name)
+	}
+}
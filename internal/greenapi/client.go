@@ -10,22 +10,33 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/zap"
 
 	"green-api/internal/config"
 )
 
 type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	retry      config.GreenAPIRetryConfig
-	breaker    *gobreaker.TwoStepCircuitBreaker
-	logger     *zap.Logger
+	httpClient   *http.Client
+	uploadClient *http.Client
+	baseURL      string
+	mediaURL     string
+	retry        config.GreenAPIRetryConfig
+	breakers     *keyedSet[*gobreaker.TwoStepCircuitBreaker]
+	limits       *rateLimits
+	perMethod    bool
+	logger       *zap.Logger
+	observer     Observer
+	tracer       trace.Tracer
+	health       upstreamHealth
 }
 
 type Response struct {
@@ -40,154 +51,413 @@ type UpstreamError struct {
 	Cause   error
 }
 
+// BreakerOpenError reports which instance (and method, when breakers are
+// kept per method) was rejected by an open circuit breaker.
+type BreakerOpenError struct {
+	IDInstance string
+	Method     string
+	Cause      error
+}
+
 var ErrCircuitBreakerOpen = errors.New("green-api circuit breaker open")
 
 func (e *UpstreamError) Error() string {
 	if e.Cause == nil {
-		return e.Message
+		return Redact(e.Message)
 	}
-	return fmt.Sprintf("%s: %v", e.Message, e.Cause)
+	return Redact(fmt.Sprintf("%s: %v", e.Message, e.Cause))
 }
 
 func (e *UpstreamError) Unwrap() error {
 	return e.Cause
 }
 
-func NewClient(cfg config.GreenAPIConfig, logger *zap.Logger) *Client {
-	settings := gobreaker.Settings{
-		Name:        cfg.CircuitBreaker.Name,
-		MaxRequests: cfg.CircuitBreaker.HalfOpenMaxRequests,
-		Interval:    cfg.CircuitBreaker.Interval(),
-		Timeout:     cfg.CircuitBreaker.OpenTimeout(),
+func (e *BreakerOpenError) Error() string {
+	target := "instance " + e.IDInstance
+	if e.Method != "" {
+		target += " method " + e.Method
+	}
+	if e.Cause == nil {
+		return fmt.Sprintf("%v for %s", ErrCircuitBreakerOpen, target)
+	}
+	return fmt.Sprintf("%v for %s: %v", ErrCircuitBreakerOpen, target, e.Cause)
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrCircuitBreakerOpen
+}
+
+func (e *BreakerOpenError) Unwrap() error {
+	return e.Cause
+}
+
+// call describes a single GREEN-API method invocation.
+type call struct {
+	idInstance string
+	method     string
+	httpMethod string
+	path       string
+	payload    any
+	retry      retryPolicy
+	// send puts the call in the rate limit bucket of sends.
+	send bool
+	// source reports a read failure of a streamed request body. Such a
+	// failure is the caller's and is not counted against the breaker.
+	source func() error
+}
+
+// retryPolicy decides which failed attempts of a call may be repeated.
+type retryPolicy int
+
+const (
+	// retrySafe is used for reads: timeouts, connection failures, 429 and 5xx
+	// are retried.
+	retrySafe retryPolicy = iota
+	// retryUnsent is used for sends: only failures that provably never reached
+	// upstream and 429 are retried, so a slow but successful send is never
+	// duplicated.
+	retryUnsent
+)
+
+// WithTransport sends every request, uploads included, through transport
+// instead of http.DefaultTransport.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+		c.uploadClient.Transport = transport
+	}
+}
+
+func NewClient(cfg config.GreenAPIConfig, logger *zap.Logger, opts ...ClientOption) *Client {
+	client := &Client{
+		httpClient: &http.Client{Timeout: cfg.Timeout()},
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		mediaURL:   strings.TrimRight(cfg.Media(), "/"),
+		retry:      cfg.Retry,
+		limits:     newRateLimits(cfg.RateLimit, cfg.CircuitBreaker),
+		perMethod:  cfg.CircuitBreaker.PerMethod,
+		logger:     logger,
+		observer:   nopObserver{},
+		tracer:     noop.NewTracerProvider().Tracer(tracerName),
+		// Uploads take as long as the file needs; the caller's context bounds them.
+		uploadClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	observer := client.observer
+	client.breakers = newKeyedSet(cfg.CircuitBreaker.IdleTTL(), cfg.CircuitBreaker.Capacity(), func(key string) *gobreaker.TwoStepCircuitBreaker {
+		settings := breakerSettings(cfg.CircuitBreaker, key, logger, observer)
+		observer.ObserveBreakerState(settings.Name, gobreaker.StateClosed.String())
+		return gobreaker.NewTwoStepCircuitBreaker(settings)
+	})
+	client.breakers.onEvict = func(key string) {
+		observer.ForgetBreaker(cfg.CircuitBreaker.Name + ":" + key)
+	}
+	// Dropping an open or half-open breaker would close it and let traffic
+	// through to a failing instance. The set still caps them and folds
+	// further instances into one overflow breaker.
+	client.breakers.pinned = func(breaker *gobreaker.TwoStepCircuitBreaker) bool {
+		return breaker.State() != gobreaker.StateClosed
+	}
+	return client
+}
+
+func breakerSettings(cfg config.CircuitBreakerConfig, key string, logger *zap.Logger, observer Observer) gobreaker.Settings {
+	return gobreaker.Settings{
+		Name:        cfg.Name + ":" + key,
+		MaxRequests: cfg.HalfOpenMaxRequests,
+		Interval:    cfg.Interval(),
+		Timeout:     cfg.OpenTimeout(),
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
-			if counts.Requests < cfg.CircuitBreaker.MinRequests {
+			if counts.Requests < cfg.MinRequests {
 				return false
 			}
-			if counts.ConsecutiveFailures >= cfg.CircuitBreaker.ConsecutiveFailures {
+			if counts.ConsecutiveFailures >= cfg.ConsecutiveFailures {
 				return true
 			}
 			if counts.Requests == 0 {
 				return false
 			}
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-			return failureRatio >= cfg.CircuitBreaker.FailureRatio
+			return failureRatio >= cfg.FailureRatio
 		},
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			logger.Warn("green_api_circuit_breaker_state_changed",
 				zap.String("breaker", name),
+				zap.String("key", key),
 				zap.String("from", from.String()),
 				zap.String("to", to.String()),
 			)
+			observer.ObserveBreakerState(name, to.String())
 		},
 	}
-
-	return &Client{
-		httpClient: &http.Client{Timeout: cfg.Timeout()},
-		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
-		retry:      cfg.Retry,
-		breaker:    gobreaker.NewTwoStepCircuitBreaker(settings),
-		logger:     logger,
-	}
 }
 
 func (c *Client) GetSettings(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
-	path := fmt.Sprintf("/waInstance%s/getSettings/%s", idInstance, apiTokenInstance)
-	return c.do(ctx, http.MethodGet, path, nil)
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "getSettings",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "getSettings", apiTokenInstance),
+	})
+}
+
+// SetSettings changes the fields set in settings and leaves the others as
+// they are. Repeating it is harmless, so it is retried like a read.
+func (c *Client) SetSettings(ctx context.Context, idInstance, apiTokenInstance string, settings InstanceSettings) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "setSettings",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "setSettings", apiTokenInstance),
+		payload:    settings.payload(),
+	})
 }
 
 func (c *Client) GetStateInstance(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
-	path := fmt.Sprintf("/waInstance%s/getStateInstance/%s", idInstance, apiTokenInstance)
-	return c.do(ctx, http.MethodGet, path, nil)
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "getStateInstance",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "getStateInstance", apiTokenInstance),
+	})
 }
 
-func (c *Client) SendMessage(ctx context.Context, idInstance, apiTokenInstance, chatID, message string) (Response, error) {
-	path := fmt.Sprintf("/waInstance%s/sendMessage/%s", idInstance, apiTokenInstance)
-	payload := map[string]string{
-		"chatId":  chatID,
-		"message": message,
-	}
-	return c.do(ctx, http.MethodPost, path, payload)
+// SendMessageParams is the body of sendMessage. Optional fields are left out
+// when empty, so GREEN-API applies its own defaults.
+type SendMessageParams struct {
+	ChatID  string `json:"chatId"`
+	Message string `json:"message"`
+	// QuotedMessageID makes the message a reply to an earlier message.
+	QuotedMessageID string `json:"quotedMessageId,omitempty"`
+	// LinkPreview disables the preview of the first link when false.
+	LinkPreview *bool `json:"linkPreview,omitempty"`
+	// Mentions lists the personal chat IDs mentioned in a group message.
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+func (c *Client) SendMessage(ctx context.Context, idInstance, apiTokenInstance string, params SendMessageParams) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "sendMessage",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "sendMessage", apiTokenInstance),
+		retry:      retryUnsent,
+		send:       true,
+		payload:    params,
+	})
 }
 
 func (c *Client) SendFileByURL(ctx context.Context, idInstance, apiTokenInstance, chatID, urlFile, fileName string) (Response, error) {
-	path := fmt.Sprintf("/waInstance%s/sendFileByUrl/%s", idInstance, apiTokenInstance)
-	payload := map[string]string{
-		"chatId":   chatID,
-		"urlFile":  urlFile,
-		"fileName": fileName,
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "sendFileByUrl",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "sendFileByUrl", apiTokenInstance),
+		retry:      retryUnsent,
+		send:       true,
+		payload: map[string]string{
+			"chatId":   chatID,
+			"urlFile":  urlFile,
+			"fileName": fileName,
+		},
+	})
+}
+
+// ReceiveNotification long-polls the instance notification queue for up to
+// receiveTimeout. The body is "null" when the queue stayed empty.
+func (c *Client) ReceiveNotification(ctx context.Context, idInstance, apiTokenInstance string, receiveTimeout time.Duration) (Response, error) {
+	path := instancePath(idInstance, "receiveNotification", apiTokenInstance)
+	if seconds := int(receiveTimeout / time.Second); seconds > 0 {
+		path += "?receiveTimeout=" + strconv.Itoa(seconds)
 	}
-	return c.do(ctx, http.MethodPost, path, payload)
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "receiveNotification",
+		httpMethod: http.MethodGet,
+		path:       path,
+	})
 }
 
-func (c *Client) do(ctx context.Context, method, path string, payload any) (Response, error) {
-	fullURL := c.baseURL + path
+// DeleteNotification acknowledges a notification received with ReceiveNotification.
+func (c *Client) DeleteNotification(ctx context.Context, idInstance, apiTokenInstance string, receiptID int64) (Response, error) {
+	path := instancePath(idInstance, "deleteNotification", apiTokenInstance) + "/" + strconv.FormatInt(receiptID, 10)
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "deleteNotification",
+		httpMethod: http.MethodDelete,
+		path:       path,
+	})
+}
+
+func instancePath(idInstance, method, apiTokenInstance string) string {
+	return fmt.Sprintf("/waInstance%s/%s/%s", idInstance, method, apiTokenInstance)
+}
+
+func (cl call) class() string {
+	if cl.send {
+		return ClassSend
+	}
+	return ClassRead
+}
+
+func (c *Client) breakerKey(cl call) string {
+	if c.perMethod {
+		return cl.idInstance + "/" + cl.method
+	}
+	return cl.idInstance
+}
+
+func (c *Client) do(ctx context.Context, cl call) (Response, error) {
+	fullURL := c.baseURL + cl.path
 	if _, err := url.ParseRequestURI(fullURL); err != nil {
-		return Response{}, &UpstreamError{Message: "invalid upstream url", Cause: err}
+		return Response{}, &UpstreamError{Message: "invalid upstream url", Cause: redactError(err)}
 	}
 
 	var bodyBytes []byte
-	if payload != nil {
-		encoded, err := json.Marshal(payload)
+	if cl.payload != nil {
+		encoded, err := json.Marshal(cl.payload)
 		if err != nil {
 			return Response{}, fmt.Errorf("marshal payload: %w", err)
 		}
 		bodyBytes = encoded
 	}
 
-	constantBackOff := backoff.NewConstantBackOff(c.retry.Delay())
+	breaker := c.breakers.get(c.breakerKey(cl))
+	delays := newBackOff(c.retry)
+	budget := newRetryBudget(ctx, c.retry.Budget())
 	maxAttempts := c.retry.MaxRetries + 1
 
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		resp, err := c.executeOnce(ctx, method, fullURL, bodyBytes)
+	for attempt := 1; ; attempt++ {
+		if err := c.limits.wait(ctx, cl); err != nil {
+			return Response{}, c.failure(cl, err)
+		}
+		resp, err := c.executeOnce(ctx, breaker, cl, attempt, fullURL, bytes.NewReader(bodyBytes), "application/json")
 		if err != nil {
-			if attempt < maxAttempts && shouldRetryError(err) {
-				c.wait(ctx, constantBackOff.NextBackOff())
+			err = redactError(err)
+			c.logger.Debug("green_api_attempt_failed",
+				zap.String("method", cl.method),
+				zap.String("url", Redact(fullURL)),
+				zap.Int("attempt", attempt),
+				zap.Error(err),
+			)
+			if attempt < maxAttempts && cl.retry.allowsError(err) && budget.pause(ctx, delays.NextBackOff()) {
+				c.observer.ObserveRetry(cl.method, "error")
 				continue
 			}
-			if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
-				return Response{}, &UpstreamError{Message: "green-api circuit breaker is open", Cause: fmt.Errorf("%w: %v", ErrCircuitBreakerOpen, err)}
-			}
-			return Response{}, &UpstreamError{Message: "green-api request failed", Cause: err}
+			return Response{}, c.failure(cl, err)
 		}
 
 		response, readErr := readResponse(resp)
 		if readErr != nil {
-			return Response{}, &UpstreamError{Message: "read green-api response", Cause: readErr}
+			return Response{}, &UpstreamError{Message: "read green-api response", Cause: redactError(readErr)}
 		}
 
-		if response.StatusCode >= http.StatusInternalServerError && attempt < maxAttempts {
-			c.wait(ctx, constantBackOff.NextBackOff())
-			continue
+		c.logger.Debug("green_api_attempt_completed",
+			zap.String("method", cl.method),
+			zap.String("url", Redact(fullURL)),
+			zap.Int("attempt", attempt),
+			zap.Int("status", response.StatusCode),
+		)
+
+		if attempt < maxAttempts && cl.retry.allowsStatus(response.StatusCode) {
+			delay := delays.NextBackOff()
+			if after, ok := retryAfter(response, time.Now()); ok {
+				delay = after
+			}
+			if budget.pause(ctx, delay) {
+				c.observer.ObserveRetry(cl.method, "status")
+				continue
+			}
 		}
 
 		return response, nil
 	}
+}
 
-	return Response{}, &UpstreamError{Message: "green-api request failed after retries"}
+// failure wraps the error of the last attempt of cl.
+func (c *Client) failure(cl call, err error) error {
+	if errors.Is(err, ErrRateLimited) {
+		return &UpstreamError{Message: "green-api call not made", Cause: err}
+	}
+	if isBreakerRejection(err) {
+		openErr := &BreakerOpenError{IDInstance: cl.idInstance, Cause: err}
+		if c.perMethod {
+			openErr.Method = cl.method
+		}
+		return &UpstreamError{Message: "green-api circuit breaker is open", Cause: openErr}
+	}
+	return &UpstreamError{Message: "green-api request failed", Cause: err}
 }
 
-func (c *Client) executeOnce(ctx context.Context, method, fullURL string, body []byte) (*http.Response, error) {
-	request, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(body))
+// executeOnce performs a single attempt under its own client span. The span
+// never carries the URL, which contains the instance token.
+func (c *Client) executeOnce(ctx context.Context, breaker *gobreaker.TwoStepCircuitBreaker, cl call, attempt int, fullURL string, body io.Reader, contentType string) (*http.Response, error) {
+	ctx, span := c.tracer.Start(ctx, "greenapi."+cl.method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			AttributeMethod.String(cl.method),
+			AttributeIDInstance.String(cl.idInstance),
+			AttributeAttempt.Int(attempt),
+			AttributeBreakerState.String(breaker.State().String()),
+			semconv.HTTPRequestMethodKey.String(cl.httpMethod),
+		),
+	)
+	defer span.End()
+
+	request, err := http.NewRequestWithContext(ctx, cl.httpMethod, fullURL, body)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		err = fmt.Errorf("build request: %w", err)
+		recordSpanError(span, err)
+		return nil, err
 	}
-	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Content-Type", contentType)
+	span.SetAttributes(semconv.ServerAddress(request.URL.Hostname()))
 
-	done, err := c.breaker.Allow()
+	started := time.Now()
+	done, err := breaker.Allow()
 	if err != nil {
+		c.observer.ObserveAttempt(cl.method, attemptOutcome(0, err), time.Since(started))
+		recordSpanError(span, err)
 		return nil, err
 	}
 
-	response, err := c.httpClient.Do(request)
+	httpClient := c.httpClient
+	if cl.source != nil {
+		httpClient = c.uploadClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil && cl.source != nil && cl.source() != nil {
+		err = cl.source()
+		done(true)
+		c.observer.ObserveAttempt(cl.method, OutcomeError, time.Since(started))
+		recordSpanError(span, err)
+		return nil, err
+	}
 	if err != nil {
 		done(false)
+		c.health.record(time.Now(), 0, err)
+		c.observer.ObserveAttempt(cl.method, attemptOutcome(0, err), time.Since(started))
+		recordSpanError(span, err)
 		return nil, err
 	}
 
 	done(response.StatusCode < http.StatusInternalServerError)
+	c.health.record(time.Now(), response.StatusCode, nil)
+	c.observer.ObserveAttempt(cl.method, attemptOutcome(response.StatusCode, nil), time.Since(started))
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+	}
 	return response, nil
 }
 
+func isBreakerRejection(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
+
 func readResponse(resp *http.Response) (Response, error) {
 	defer resp.Body.Close()
 
@@ -204,6 +474,44 @@ func readResponse(resp *http.Response) (Response, error) {
 	}, nil
 }
 
+func (p retryPolicy) allowsError(err error) bool {
+	if p == retryUnsent {
+		return isNotSent(err)
+	}
+	return shouldRetryError(err) || isNotSent(err)
+}
+
+// allowsStatus lets every call repeat a 429: GREEN-API rejected the request
+// before processing it, so even a send cannot be duplicated.
+func (p retryPolicy) allowsStatus(statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return p == retrySafe && statusCode >= http.StatusInternalServerError
+}
+
+// NotSent reports whether err proves the request never reached GREEN-API:
+// the circuit breaker or rate limit rejected it or the connection could not
+// be made.
+func NotSent(err error) bool {
+	return errors.Is(err, ErrCircuitBreakerOpen) || errors.Is(err, ErrRateLimited) || isNotSent(err)
+}
+
+// isNotSent reports whether err happened before the request could reach
+// upstream: DNS resolution or TCP dial failures.
+func isNotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial"
+	}
+	return false
+}
+
 func shouldRetryError(err error) bool {
 	if errors.Is(err, context.Canceled) {
 		return false
@@ -227,17 +535,3 @@ func shouldRetryError(err error) bool {
 
 	return false
 }
-
-func (c *Client) wait(ctx context.Context, delay time.Duration) {
-	if delay <= 0 {
-		return
-	}
-
-	timer := time.NewTimer(delay)
-	defer timer.Stop()
-
-	select {
-	case <-ctx.Done():
-	case <-timer.C:
-	}
-}
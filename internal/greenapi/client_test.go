@@ -2,13 +2,25 @@ package greenapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"green-api/internal/config"
@@ -117,6 +129,41 @@ func TestClient_SendFileByURLPayload(t *testing.T) {
 	require.Equal(t, `{"idMessage":"abc"}`, string(resp.Body))
 }
 
+func TestClient_SendMessagePayload(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		bodies <- string(body)
+		_, _ = w.Write([]byte(`{"idMessage":"1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	_, err := client.SendMessage(context.Background(), "1101000001", "token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hi"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"chatId":"77771234567@c.us","message":"hi"}`, <-bodies)
+
+	noPreview := false
+	_, err = client.SendMessage(context.Background(), "1101000001", "token", SendMessageParams{
+		ChatID:          "120363043968066561@g.us",
+		Message:         "@79991234567 hi",
+		QuotedMessageID: "BAE5F4886F6F2D05",
+		LinkPreview:     &noPreview,
+		Mentions:        []string{"79991234567@c.us"},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"chatId":"120363043968066561@g.us",
+		"message":"@79991234567 hi",
+		"quotedMessageId":"BAE5F4886F6F2D05",
+		"linkPreview":false,
+		"mentions":["79991234567@c.us"]
+	}`, <-bodies)
+}
+
 func TestClient_InvalidBaseURL(t *testing.T) {
 	t.Parallel()
 
@@ -156,7 +203,7 @@ func TestClient_SendMessagePath(t *testing.T) {
 	defer server.Close()
 
 	client := NewClient(testConfig(server.URL), zap.NewNop())
-	resp, err := client.SendMessage(context.Background(), "123", "token", "77771234567@c.us", "hello")
+	resp, err := client.SendMessage(context.Background(), "123", "token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hello"})
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 	require.Equal(t, `{"idMessage":"1"}`, string(resp.Body))
@@ -182,3 +229,365 @@ func TestClient_RetryOnServer5xxExhausted(t *testing.T) {
 	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
 }
+
+func TestClient_CircuitBreakerIsolatedPerInstance(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/waInstance1/getSettings/token" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.MaxRetries = 0
+	cfg.CircuitBreaker.ConsecutiveFailures = 1
+	cfg.CircuitBreaker.MinRequests = 1
+	client := NewClient(cfg, zap.NewNop())
+
+	_, err := client.GetSettings(context.Background(), "1", "token")
+	require.NoError(t, err)
+
+	_, err = client.GetSettings(context.Background(), "1", "token")
+	var openErr *BreakerOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.Equal(t, "1", openErr.IDInstance)
+	require.ErrorIs(t, err, ErrCircuitBreakerOpen)
+
+	resp, err := client.GetSettings(context.Background(), "2", "token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestClient_OpenBreakersAreCapped(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.MaxRetries = 0
+	cfg.CircuitBreaker.ConsecutiveFailures = 1
+	cfg.CircuitBreaker.MinRequests = 1
+	cfg.CircuitBreaker.MaxBreakers = 2
+	client := NewClient(cfg, zap.NewNop())
+
+	// Every made-up instance trips its breaker, so none of them can be evicted.
+	for i := range 10 {
+		_, _ = client.GetSettings(context.Background(), strconv.Itoa(i), "token")
+	}
+	require.Equal(t, 4, client.breakers.len())
+
+	_, err := client.GetSettings(context.Background(), "new", "token")
+	require.ErrorIs(t, err, ErrCircuitBreakerOpen, "instances past the cap share the tripped overflow breaker")
+
+	names := []string{}
+	for _, breaker := range client.Health().Breakers {
+		names = append(names, breaker.Name)
+	}
+	require.Contains(t, names, "test-breaker:overflow")
+}
+
+func TestClient_CircuitBreakerPerMethod(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/waInstance1/sendMessage/token" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.MaxRetries = 0
+	cfg.CircuitBreaker.ConsecutiveFailures = 1
+	cfg.CircuitBreaker.MinRequests = 1
+	cfg.CircuitBreaker.PerMethod = true
+	client := NewClient(cfg, zap.NewNop())
+
+	_, err := client.SendMessage(context.Background(), "1", "token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hi"})
+	require.NoError(t, err)
+
+	_, err = client.SendMessage(context.Background(), "1", "token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hi"})
+	var openErr *BreakerOpenError
+	require.ErrorAs(t, err, &openErr)
+	require.Equal(t, "sendMessage", openErr.Method)
+
+	_, err = client.GetStateInstance(context.Background(), "1", "token")
+	require.NoError(t, err)
+}
+
+func TestClient_SendMessageNotRetriedOn5xx(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	resp, err := client.SendMessage(context.Background(), "123", "token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestRetryPolicy_SendsRetriedOnlyWhenNotSent(t *testing.T) {
+	t.Parallel()
+
+	dialErr := &url.Error{Op: "Post", URL: "http://x", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	readErr := &url.Error{Op: "Post", URL: "http://x", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}
+
+	require.True(t, retryUnsent.allowsError(dialErr))
+	require.False(t, retryUnsent.allowsError(readErr))
+	require.False(t, retryUnsent.allowsError(context.DeadlineExceeded))
+	require.False(t, retryUnsent.allowsStatus(http.StatusBadGateway))
+
+	require.True(t, retrySafe.allowsError(dialErr))
+	require.True(t, retrySafe.allowsError(context.DeadlineExceeded))
+	require.True(t, retrySafe.allowsStatus(http.StatusBadGateway))
+}
+
+func TestClient_NotificationPaths(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			require.Equal(t, "/waInstance123/receiveNotification/token", r.URL.Path)
+			require.Equal(t, "20", r.URL.Query().Get("receiveTimeout"))
+			_, _ = w.Write([]byte(`null`))
+		case http.MethodDelete:
+			require.Equal(t, "/waInstance123/deleteNotification/token/42", r.URL.Path)
+			_, _ = w.Write([]byte(`{"result":true}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	resp, err := client.ReceiveNotification(context.Background(), "123", "token", 20*time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "null", string(resp.Body))
+
+	resp, err = client.DeleteNotification(context.Background(), "123", "token", 42)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	attempts []string
+	retries  []string
+	states   []string
+	forgot   []string
+}
+
+func (o *recordingObserver) ObserveAttempt(method, outcome string, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attempts = append(o.attempts, method+"="+outcome)
+}
+
+func (o *recordingObserver) ObserveRetry(method, reason string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retries = append(o.retries, method+"="+reason)
+}
+
+func (o *recordingObserver) ObserveBreakerState(breaker, state string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.states = append(o.states, breaker+"="+state)
+}
+
+func (o *recordingObserver) ForgetBreaker(breaker string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.forgot = append(o.forgot, breaker)
+}
+
+func TestClient_ObserverReceivesAttemptsRetriesAndBreakerStates(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.MaxRetries = 1
+	cfg.Retry.DelaySeconds = 0
+	cfg.CircuitBreaker.ConsecutiveFailures = 2
+	cfg.CircuitBreaker.MinRequests = 2
+	cfg.CircuitBreaker.OpenTimeoutSeconds = 300
+	observer := &recordingObserver{}
+	client := NewClient(cfg, zap.NewNop(), WithObserver(observer))
+
+	resp, err := client.GetSettings(context.Background(), "1101000001", "token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	_, err = client.GetSettings(context.Background(), "1101000001", "token")
+	require.ErrorIs(t, err, ErrCircuitBreakerOpen)
+
+	require.Equal(t, []string{
+		"getSettings=server_error",
+		"getSettings=server_error",
+		"getSettings=breaker_open",
+	}, observer.attempts)
+	require.Equal(t, []string{"getSettings=status"}, observer.retries)
+	require.Equal(t, []string{
+		"test-breaker:1101000001=closed",
+		"test-breaker:1101000001=open",
+	}, observer.states)
+}
+
+func TestClient_ObserverForgetsEvictedBreakers(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.CircuitBreaker.MaxBreakers = 1
+	observer := &recordingObserver{}
+	client := NewClient(cfg, zap.NewNop(), WithObserver(observer))
+
+	_, err := client.GetSettings(context.Background(), "1101000001", "token")
+	require.NoError(t, err)
+	_, err = client.GetSettings(context.Background(), "1101000002", "token")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"test-breaker:1101000001"}, observer.forgot)
+}
+
+func TestClient_RecordsSpanPerAttemptWithoutToken(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.DelaySeconds = 0
+	recorder := tracetest.NewSpanRecorder()
+	client := NewClient(cfg, zap.NewNop(), WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	_, err := client.GetSettings(context.Background(), "1101000001", "secret-token")
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for i, span := range spans {
+		require.Equal(t, "greenapi.getSettings", span.Name())
+		require.Equal(t, trace.SpanKindClient, span.SpanKind())
+
+		attrs := attribute.NewSet(span.Attributes()...)
+		attempt, _ := attrs.Value(AttributeAttempt)
+		require.Equal(t, int64(i+1), attempt.AsInt64())
+		state, _ := attrs.Value(AttributeBreakerState)
+		require.Equal(t, "closed", state.AsString())
+		id, _ := attrs.Value(AttributeIDInstance)
+		require.Equal(t, "1101000001", id.AsString())
+
+		for _, kv := range span.Attributes() {
+			require.NotContains(t, kv.Value.Emit(), "secret-token")
+		}
+	}
+
+	status := func(span sdktrace.ReadOnlySpan) int64 {
+		attrs := attribute.NewSet(span.Attributes()...)
+		value, _ := attrs.Value("http.response.status_code")
+		return value.AsInt64()
+	}
+	require.Equal(t, int64(http.StatusBadGateway), status(spans[0]))
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Equal(t, int64(http.StatusOK), status(spans[1]))
+}
+
+func TestClient_SpanErrorIsRedacted(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	baseURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := testConfig(baseURL)
+	cfg.Retry.MaxRetries = 0
+	recorder := tracetest.NewSpanRecorder()
+	client := NewClient(cfg, zap.NewNop(), WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+
+	_, err = client.GetSettings(context.Background(), "1101000001", "secret-token")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.NotContains(t, spans[0].Status().Description, "secret-token")
+	require.NotEmpty(t, spans[0].Events())
+	for _, event := range spans[0].Events() {
+		for _, kv := range event.Attributes {
+			require.NotContains(t, kv.Value.Emit(), "secret-token")
+		}
+	}
+}
+
+func TestClient_HealthTracksOutcomesAndBreakers(t *testing.T) {
+	t.Parallel()
+
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.MaxRetries = 0
+	cfg.CircuitBreaker.ConsecutiveFailures = 1
+	cfg.CircuitBreaker.MinRequests = 1
+	client := NewClient(cfg, zap.NewNop())
+
+	health := client.Health()
+	require.True(t, health.LastSuccessAt.IsZero())
+	require.Empty(t, health.Breakers)
+
+	_, err := client.GetSettings(context.Background(), "1101000002", "token")
+	require.NoError(t, err)
+	fail.Store(true)
+	_, err = client.GetSettings(context.Background(), "1101000001", "token")
+	require.NoError(t, err)
+	_, err = client.GetSettings(context.Background(), "1101000001", "token")
+	require.ErrorIs(t, err, ErrCircuitBreakerOpen)
+
+	health = client.Health()
+	require.False(t, health.LastSuccessAt.IsZero())
+	require.False(t, health.LastFailureAt.IsZero())
+	require.Equal(t, "upstream status 503", health.LastError)
+	require.Equal(t, []BreakerStatus{
+		{Name: "test-breaker:1101000001", State: "open"},
+		{Name: "test-breaker:1101000002", State: "closed"},
+	}, health.Breakers)
+}
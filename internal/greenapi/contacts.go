@@ -0,0 +1,40 @@
+package greenapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// CheckWhatsapp reports whether phoneNumber (international format, digits
+// only) has a WhatsApp account; decode it into CheckWhatsappResult.
+func (c *Client) CheckWhatsapp(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "checkWhatsapp",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "checkWhatsapp", apiTokenInstance),
+		payload:    map[string]int64{"phoneNumber": phoneNumber},
+	})
+}
+
+// GetContacts lists the contacts and groups of the linked phone.
+func (c *Client) GetContacts(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "getContacts",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "getContacts", apiTokenInstance),
+	})
+}
+
+// GetContactInfo describes the contact of a chat: name, avatar, business
+// profile.
+func (c *Client) GetContactInfo(ctx context.Context, idInstance, apiTokenInstance, chatID string) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "getContactInfo",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "getContactInfo", apiTokenInstance),
+		payload:    map[string]string{"chatId": chatID},
+	})
+}
@@ -0,0 +1,54 @@
+package greenapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_ContactCalls(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch r.URL.Path {
+		case "/waInstance1101000001/checkWhatsapp/token":
+			require.Equal(t, http.MethodPost, r.Method)
+			require.JSONEq(t, `{"phoneNumber":79991234567}`, string(body))
+			_, _ = w.Write([]byte(`{"existsWhatsapp":true}`))
+		case "/waInstance1101000001/getContacts/token":
+			require.Equal(t, http.MethodGet, r.Method)
+			_, _ = w.Write([]byte(`[]`))
+		case "/waInstance1101000001/getContactInfo/token":
+			require.Equal(t, http.MethodPost, r.Method)
+			require.JSONEq(t, `{"chatId":"79991234567@c.us"}`, string(body))
+			_, _ = w.Write([]byte(`{"chatId":"79991234567@c.us"}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	ctx := context.Background()
+
+	resp, err := client.CheckWhatsapp(ctx, "1101000001", "token", 79991234567)
+	require.NoError(t, err)
+	var check CheckWhatsappResult
+	require.NoError(t, Decode(resp, DecodeStrict, &check))
+	require.True(t, check.ExistsWhatsapp)
+
+	resp, err = client.GetContacts(ctx, "1101000001", "token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = client.GetContactInfo(ctx, "1101000001", "token", "79991234567@c.us")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
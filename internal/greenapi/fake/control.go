@@ -0,0 +1,98 @@
+package fake
+
+import (
+	"net/http"
+)
+
+// controlPrefix is where the control API lives, so a running fake can be
+// scripted without Go code.
+const controlPrefix = "/__fake/"
+
+func (s *Server) controlRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /__fake/instances", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IDInstance       string `json:"idInstance"`
+			APITokenInstance string `json:"apiTokenInstance"`
+		}
+		if !decodeBody(w, r, &body) {
+			return
+		}
+		if body.IDInstance == "" || body.APITokenInstance == "" {
+			writeError(w, http.StatusBadRequest, "idInstance and apiTokenInstance are required")
+			return
+		}
+		s.AddInstance(body.IDInstance, body.APITokenInstance)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("PUT /__fake/instances/{id}/state", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			StateInstance string `json:"stateInstance"`
+		}
+		if !decodeBody(w, r, &body) {
+			return
+		}
+		if !s.SetState(r.PathValue("id"), body.StateInstance) {
+			writeError(w, http.StatusNotFound, "instance is not registered")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /__fake/instances/{id}/sent", func(w http.ResponseWriter, r *http.Request) {
+		sent := s.Sent(r.PathValue("id"))
+		if sent == nil {
+			sent = []Message{}
+		}
+		writeJSON(w, http.StatusOK, sent)
+	})
+
+	mux.HandleFunc("POST /__fake/instances/{id}/receive", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ChatID string `json:"chatId"`
+			Text   string `json:"text"`
+		}
+		if !decodeBody(w, r, &body) {
+			return
+		}
+		if !validChatID(body.ChatID) {
+			writeError(w, http.StatusBadRequest, "chatId is invalid")
+			return
+		}
+		msg, ok := s.Receive(r.PathValue("id"), body.ChatID, body.Text)
+		if !ok {
+			writeError(w, http.StatusNotFound, "instance is not registered")
+			return
+		}
+		writeJSON(w, http.StatusCreated, msg)
+	})
+
+	mux.HandleFunc("PUT /__fake/whatsapp/{phone}", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ExistsWhatsapp bool `json:"existsWhatsapp"`
+		}
+		if !decodeBody(w, r, &body) {
+			return
+		}
+		s.SetWhatsapp(r.PathValue("phone"), body.ExistsWhatsapp)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /__fake/faults", func(w http.ResponseWriter, r *http.Request) {
+		var fault Fault
+		if !decodeBody(w, r, &fault) {
+			return
+		}
+		s.Inject(fault)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /__fake/faults", func(w http.ResponseWriter, _ *http.Request) {
+		s.ClearFaults()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
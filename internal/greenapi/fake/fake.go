@@ -0,0 +1,337 @@
+// Package fake is an in-memory GREEN-API for local development and tests. It
+// speaks the wire protocol of the methods the service calls, keeps the state
+// of every instance (settings, sent and received messages, notification
+// queue, authorization) and can be scripted to fail with Inject.
+//
+// The package does not import greenapi, so the client's own tests can use it.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an http.Handler serving the GREEN-API paths
+// /waInstance{idInstance}/{method}/{apiTokenInstance} of the registered
+// instances and the control API under /__fake/. The zero value is not usable;
+// call New.
+type Server struct {
+	mu           sync.Mutex
+	instances    map[string]*instance
+	faults       []*Fault
+	calls        map[string]int
+	autoRegister bool
+	noWhatsapp   map[string]bool
+	sequence     uint64
+	now          func() time.Time
+	control      *http.ServeMux
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type instance struct {
+	token         string
+	state         string
+	settings      map[string]any
+	journal       []Message
+	notifications []notification
+	// wake is closed and replaced whenever a notification is queued, so
+	// long-polling receiveNotification calls return at once.
+	wake chan struct{}
+}
+
+type notification struct {
+	ReceiptID int64          `json:"receiptId"`
+	Body      map[string]any `json:"body"`
+}
+
+// Option customizes a Server built by New.
+type Option func(*Server)
+
+// WithAutoRegister accepts any idInstance and token: an unknown instance is
+// created, authorized, on its first call with the token of that call.
+func WithAutoRegister() Option {
+	return func(s *Server) {
+		s.autoRegister = true
+	}
+}
+
+// WithClock replaces time.Now for message timestamps and journal windows.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+func New(opts ...Option) *Server {
+	s := &Server{
+		instances:  make(map[string]*instance),
+		calls:      make(map[string]int),
+		noWhatsapp: make(map[string]bool),
+		now:        time.Now,
+		closed:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.control = s.controlRoutes()
+	return s
+}
+
+// AddInstance registers an authorized instance with default settings.
+// Registering an existing instance again only replaces its token.
+func (s *Server) AddInstance(idInstance, apiTokenInstance string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addInstanceLocked(idInstance, apiTokenInstance)
+}
+
+func (s *Server) addInstanceLocked(idInstance, apiTokenInstance string) *instance {
+	if inst, ok := s.instances[idInstance]; ok {
+		inst.token = apiTokenInstance
+		return inst
+	}
+	s.sequence++
+	inst := &instance{
+		token:    apiTokenInstance,
+		state:    "authorized",
+		settings: defaultSettings(fmt.Sprintf("7%010d@c.us", s.sequence)),
+		wake:     make(chan struct{}),
+	}
+	s.instances[idInstance] = inst
+	return inst
+}
+
+func defaultSettings(wid string) map[string]any {
+	settings := map[string]any{
+		"wid":                           wid,
+		"countryInstance":               "",
+		"typeAccount":                   "trial",
+		"webhookUrl":                    "",
+		"webhookUrlToken":               "",
+		"delaySendMessagesMilliseconds": 1000,
+	}
+	for _, flag := range flagSettings {
+		settings[flag] = "no"
+	}
+	return settings
+}
+
+// flagSettings are the "yes"/"no" fields of getSettings.
+var flagSettings = []string{
+	"markIncomingMessagesReaded",
+	"markIncomingMessagesReadedOnReply",
+	"outgoingWebhook",
+	"outgoingMessageWebhook",
+	"outgoingAPIMessageWebhook",
+	"incomingWebhook",
+	"stateWebhook",
+	"keepOnlineStatus",
+	"pollMessageWebhook",
+	"incomingCallWebhook",
+	"editedMessageWebhook",
+	"deletedMessageWebhook",
+}
+
+// SetState changes the stateInstance reported by getStateInstance. It returns
+// false when the instance is not registered.
+func (s *Server) SetState(idInstance, state string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instances[idInstance]
+	if ok {
+		inst.state = state
+	}
+	return ok
+}
+
+// SetWhatsapp changes what checkWhatsapp reports for phoneNumber (digits
+// only). Every number has WhatsApp until told otherwise.
+func (s *Server) SetWhatsapp(phoneNumber string, exists bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if exists {
+		delete(s.noWhatsapp, phoneNumber)
+	} else {
+		s.noWhatsapp[phoneNumber] = true
+	}
+}
+
+// Sent returns the messages sent by the instance, oldest first.
+func (s *Server) Sent(idInstance string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instances[idInstance]
+	if !ok {
+		return nil
+	}
+	var sent []Message
+	for _, msg := range inst.journal {
+		if msg.Type == TypeOutgoing {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+// Calls counts the calls of a GREEN-API method, such as "sendMessage", that
+// reached the server, including those answered by a fault.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Receive simulates an incoming text message: it is added to the journal and
+// an incomingMessageReceived notification is queued.
+func (s *Server) Receive(idInstance, chatID, text string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instances[idInstance]
+	if !ok {
+		return Message{}, false
+	}
+	msg := s.recordLocked(inst, Message{
+		Type:        TypeIncoming,
+		TypeMessage: "textMessage",
+		ChatID:      chatID,
+		TextMessage: text,
+	})
+	s.notifyLocked(inst, map[string]any{
+		"typeWebhook":  "incomingMessageReceived",
+		"instanceData": map[string]any{"idInstance": idInstance, "wid": inst.settings["wid"]},
+		"timestamp":    msg.Timestamp,
+		"idMessage":    msg.IDMessage,
+		"senderData":   map[string]any{"chatId": chatID, "sender": chatID},
+		"messageData": map[string]any{
+			"typeMessage":     "textMessage",
+			"textMessageData": map[string]any{"textMessage": text},
+		},
+	})
+	return msg, true
+}
+
+// Notify queues a raw notification body for receiveNotification and returns
+// its receipt ID.
+func (s *Server) Notify(idInstance string, body map[string]any) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instances[idInstance]
+	if !ok {
+		return 0, false
+	}
+	return s.notifyLocked(inst, body), true
+}
+
+func (s *Server) notifyLocked(inst *instance, body map[string]any) int64 {
+	s.sequence++
+	inst.notifications = append(inst.notifications, notification{ReceiptID: int64(s.sequence), Body: body})
+	close(inst.wake)
+	inst.wake = make(chan struct{})
+	return int64(s.sequence)
+}
+
+func (s *Server) recordLocked(inst *instance, msg Message) Message {
+	s.sequence++
+	msg.IDMessage = fmt.Sprintf("BAE5%012X", s.sequence)
+	msg.Timestamp = s.now().Unix()
+	inst.journal = append(inst.journal, msg)
+	return msg
+}
+
+// Close releases calls held by a Hang fault or a long-polling
+// receiveNotification, so an httptest.Server can shut down.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, controlPrefix) {
+		s.control.ServeHTTP(w, r)
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 3 || !strings.HasPrefix(segments[0], "waInstance") {
+		writeError(w, http.StatusNotFound, "unknown path")
+		return
+	}
+	idInstance := strings.TrimPrefix(segments[0], "waInstance")
+	method, token, extra := segments[1], segments[2], segments[3:]
+
+	s.mu.Lock()
+	s.calls[method]++
+	fault := s.matchFaultLocked(method, idInstance)
+	s.mu.Unlock()
+
+	if fault != nil && !s.apply(w, r, fault) {
+		return
+	}
+
+	s.mu.Lock()
+	inst, ok := s.instances[idInstance]
+	if !ok && s.autoRegister && token != "" {
+		inst, ok = s.addInstanceLocked(idInstance, token), true
+	}
+	authorized := ok && inst.token == token
+	s.mu.Unlock()
+	if !authorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	handler, known := s.methods()[methodKey{r.Method, method}]
+	if !known {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("method %s %s is not supported by the fake", r.Method, method))
+		return
+	}
+	handler(w, r, call{idInstance: idInstance, inst: inst, extra: extra})
+}
+
+type methodKey struct {
+	httpMethod string
+	method     string
+}
+
+type call struct {
+	idInstance string
+	inst       *instance
+	extra      []string
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers like GREEN-API does for a rejected call.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"code":        status,
+		"description": http.StatusText(status),
+		"message":     message,
+	})
+}
+
+// newestFirst returns the journal entries that pass keep, newest first, as
+// the journal methods do.
+func newestFirst(journal []Message, keep func(Message) bool) []Message {
+	result := []Message{}
+	for _, msg := range slices.Backward(journal) {
+		if keep(msg) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
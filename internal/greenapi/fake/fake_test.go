@@ -0,0 +1,322 @@
+package fake_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/greenapi"
+	"green-api/internal/greenapi/fake"
+)
+
+const (
+	idInstance = "1101000001"
+	token      = "token"
+	chatID     = "79991234567@c.us"
+)
+
+func startFake(t *testing.T, opts ...fake.Option) (*fake.Server, *greenapi.Client, string) {
+	t.Helper()
+
+	server := fake.New(opts...)
+	server.AddInstance(idInstance, token)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Close()
+		httpServer.Close()
+	})
+
+	client := greenapi.NewClient(config.GreenAPIConfig{
+		BaseURL:        httpServer.URL,
+		MediaURL:       httpServer.URL,
+		TimeoutSeconds: 2,
+		Retry:          config.GreenAPIRetryConfig{MaxRetries: 2, DelayMS: 10},
+		CircuitBreaker: config.CircuitBreakerConfig{
+			Name:                "fake",
+			ConsecutiveFailures: 50,
+			HalfOpenMaxRequests: 1,
+			OpenTimeoutSeconds:  60,
+			IntervalSeconds:     60,
+			FailureRatio:        1,
+			MinRequests:         100,
+		},
+	}, zap.NewNop())
+	return server, client, httpServer.URL
+}
+
+func TestFake_SendsAreTracked(t *testing.T) {
+	t.Parallel()
+
+	server, client, _ := startFake(t)
+	ctx := context.Background()
+
+	resp, err := client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: chatID, Message: "hi"})
+	require.NoError(t, err)
+	var sent greenapi.SendResult
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &sent))
+
+	_, err = client.SendFileByURL(ctx, idInstance, token, chatID, "https://example.com/a.pdf", "a.pdf")
+	require.NoError(t, err)
+	resp, err = client.SendFileByUpload(ctx, idInstance, token, greenapi.UploadParams{ChatID: chatID, FileName: "b.txt"}, strings.NewReader("hello"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	messages := server.Sent(idInstance)
+	require.Len(t, messages, 3)
+	require.Equal(t, sent.IDMessage, messages[0].IDMessage)
+	require.Equal(t, "hi", messages[0].TextMessage)
+	require.Equal(t, "a.pdf", messages[1].FileName)
+	require.Equal(t, int64(5), messages[2].Size)
+
+	resp, err = client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: "nope", Message: "hi"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.Len(t, server.Sent(idInstance), 3)
+}
+
+func TestFake_SettingsAndStateDecodeStrictly(t *testing.T) {
+	t.Parallel()
+
+	server, client, _ := startFake(t)
+	ctx := context.Background()
+
+	resp, err := client.GetSettings(ctx, idInstance, token)
+	require.NoError(t, err)
+	var settings greenapi.Settings
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &settings))
+	require.NotEmpty(t, settings.WID)
+
+	require.True(t, server.SetState(idInstance, "yellowCard"))
+	resp, err = client.GetStateInstance(ctx, idInstance, token)
+	require.NoError(t, err)
+	var state greenapi.State
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &state))
+	require.Equal(t, greenapi.StateYellowCard, state.StateInstance)
+
+	resp, err = client.GetSettings(ctx, idInstance, "wrong-token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestFake_SetSettings(t *testing.T) {
+	t.Parallel()
+
+	_, client, _ := startFake(t)
+	ctx := context.Background()
+
+	webhookURL, incoming := "https://example.com/hook", greenapi.Flag(true)
+	resp, err := client.SetSettings(ctx, idInstance, token, greenapi.InstanceSettings{WebhookURL: &webhookURL, IncomingWebhook: &incoming})
+	require.NoError(t, err)
+	var saved greenapi.SaveSettingsResult
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &saved))
+	require.True(t, saved.SaveSettings)
+
+	resp, err = client.GetSettings(ctx, idInstance, token)
+	require.NoError(t, err)
+	var settings greenapi.Settings
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &settings))
+	require.Equal(t, webhookURL, settings.WebhookURL)
+	require.True(t, bool(settings.IncomingWebhook))
+	require.False(t, bool(settings.OutgoingWebhook))
+}
+
+func TestFake_RelinkPhone(t *testing.T) {
+	t.Parallel()
+
+	server, client, _ := startFake(t)
+	ctx := context.Background()
+
+	resp, err := client.QR(ctx, idInstance, token)
+	require.NoError(t, err)
+	var qr greenapi.QR
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &qr))
+	require.Equal(t, greenapi.QRAlreadyLogged, qr.Type)
+
+	resp, err = client.Logout(ctx, idInstance, token)
+	require.NoError(t, err)
+	var logout greenapi.LogoutResult
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &logout))
+	require.True(t, logout.IsLogout)
+
+	resp, err = client.QR(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &qr))
+	require.Equal(t, greenapi.QRCode, qr.Type)
+	image, err := qr.PNG()
+	require.NoError(t, err)
+	require.NotEmpty(t, image)
+
+	resp, err = client.GetAuthorizationCode(ctx, idInstance, token, 79991234567)
+	require.NoError(t, err)
+	var code greenapi.AuthorizationCode
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &code))
+	require.True(t, code.Status)
+	require.NotEmpty(t, code.Code)
+
+	require.True(t, server.SetState(idInstance, "authorized"))
+	resp, err = client.Reboot(ctx, idInstance, token)
+	require.NoError(t, err)
+	var reboot greenapi.RebootResult
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &reboot))
+	require.True(t, reboot.IsReboot)
+	require.Equal(t, 1, server.Calls("getAuthorizationCode"))
+}
+
+func TestFake_NotificationsAndJournals(t *testing.T) {
+	t.Parallel()
+
+	server, client, baseURL := startFake(t)
+	ctx := context.Background()
+
+	incoming, ok := server.Receive(idInstance, chatID, "hello")
+	require.True(t, ok)
+
+	resp, err := client.ReceiveNotification(ctx, idInstance, token, time.Second)
+	require.NoError(t, err)
+	var received struct {
+		ReceiptID int64 `json:"receiptId"`
+		Body      struct {
+			TypeWebhook string `json:"typeWebhook"`
+			IDMessage   string `json:"idMessage"`
+		} `json:"body"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body, &received))
+	require.Equal(t, "incomingMessageReceived", received.Body.TypeWebhook)
+	require.Equal(t, incoming.IDMessage, received.Body.IDMessage)
+
+	resp, err = client.DeleteNotification(ctx, idInstance, token, received.ReceiptID)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"result":true}`, string(resp.Body))
+
+	started := time.Now()
+	resp, err = client.ReceiveNotification(ctx, idInstance, token, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "null", strings.TrimSpace(string(resp.Body)))
+	require.GreaterOrEqual(t, time.Since(started), 900*time.Millisecond)
+
+	_, err = client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: chatID, Message: "reply"})
+	require.NoError(t, err)
+
+	var journal []fake.Message
+	getJSON(t, baseURL, "lastIncomingMessages", &journal)
+	require.Len(t, journal, 1)
+	require.Equal(t, "hello", journal[0].TextMessage)
+	getJSON(t, baseURL, "lastOutgoingMessages", &journal)
+	require.Len(t, journal, 1)
+	require.Equal(t, "reply", journal[0].TextMessage)
+}
+
+// getJSON calls a journal method the client has no wrapper for.
+func getJSON(t *testing.T, baseURL, method string, dst any) {
+	t.Helper()
+
+	resp, err := http.Get(baseURL + "/waInstance" + idInstance + "/" + method + "/" + token)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(dst))
+}
+
+func TestFake_InjectedFaults(t *testing.T) {
+	t.Parallel()
+
+	server, client, _ := startFake(t)
+	ctx := context.Background()
+
+	server.Inject(fake.Fault{Method: "getSettings", Status: http.StatusBadGateway, Times: 2})
+	resp, err := client.GetSettings(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, "reads are retried past two 502s")
+	require.Equal(t, 3, server.Calls("getSettings"))
+
+	server.Inject(fake.Fault{Method: "sendMessage", Status: http.StatusTooManyRequests, RetryAfterSeconds: 1, Times: 1})
+	started := time.Now()
+	_, err = client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: chatID, Message: "hi"})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(started), time.Second, "Retry-After is honoured")
+	require.Len(t, server.Sent(idInstance), 1)
+
+	server.Inject(fake.Fault{Method: "sendMessage", IDInstance: idInstance, Hang: true})
+	_, err = client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: chatID, Message: "lost"})
+	require.Error(t, err)
+	require.Len(t, server.Sent(idInstance), 1)
+
+	server.ClearFaults()
+	server.Inject(fake.Fault{LatencyMS: 100})
+	started = time.Now()
+	_, err = client.GetStateInstance(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+}
+
+func TestFake_ControlAPI(t *testing.T) {
+	t.Parallel()
+
+	server := fake.New(fake.WithAutoRegister())
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	post := func(path, body string) int {
+		resp, err := http.Post(httpServer.URL+path, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	resp, err := http.Get(httpServer.URL + "/waInstance7103000001/getStateInstance/any-token")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "unknown instances are registered on first use")
+
+	require.Equal(t, http.StatusNoContent, post("/__fake/faults", `{"method":"getStateInstance","status":503,"times":1}`))
+	resp, err = http.Get(httpServer.URL + "/waInstance7103000001/getStateInstance/any-token")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	require.Equal(t, http.StatusCreated, post("/__fake/instances/7103000001/receive", `{"chatId":"79991234567@c.us","text":"hi"}`))
+	require.Equal(t, http.StatusNotFound, post("/__fake/instances/missing/receive", `{"chatId":"79991234567@c.us","text":"hi"}`))
+	_, ok := server.Notify("7103000001", map[string]any{"typeWebhook": "stateInstanceChanged"})
+	require.True(t, ok)
+}
+
+func TestFake_Contacts(t *testing.T) {
+	t.Parallel()
+
+	server, client, _ := startFake(t)
+	ctx := context.Background()
+
+	var check greenapi.CheckWhatsappResult
+	resp, err := client.CheckWhatsapp(ctx, idInstance, token, 79991234567)
+	require.NoError(t, err)
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &check))
+	require.True(t, check.ExistsWhatsapp)
+
+	server.SetWhatsapp("79991234567", false)
+	resp, err = client.CheckWhatsapp(ctx, idInstance, token, 79991234567)
+	require.NoError(t, err)
+	require.NoError(t, greenapi.Decode(resp, greenapi.DecodeStrict, &check))
+	require.False(t, check.ExistsWhatsapp)
+
+	_, ok := server.Receive(idInstance, chatID, "hello")
+	require.True(t, ok)
+	_, err = client.SendMessage(ctx, idInstance, token, greenapi.SendMessageParams{ChatID: chatID, Message: "hi"})
+	require.NoError(t, err)
+	resp, err = client.GetContacts(ctx, idInstance, token)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"id":"79991234567@c.us","name":"","contactName":"","type":"user"}]`, string(resp.Body))
+
+	resp, err = client.GetContactInfo(ctx, idInstance, token, chatID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(resp.Body), `"chatId":"79991234567@c.us"`)
+}
@@ -0,0 +1,93 @@
+package fake
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Fault makes matching calls slow or failing. An empty Method or IDInstance
+// matches every call. Faults are tried in the order they were injected and
+// the first match applies.
+type Fault struct {
+	Method     string `json:"method,omitempty"`
+	IDInstance string `json:"idInstance,omitempty"`
+	// Times is how many calls the fault applies to; zero means every call
+	// until ClearFaults.
+	Times int `json:"times,omitempty"`
+	// LatencyMS delays the answer.
+	LatencyMS int `json:"latencyMs,omitempty"`
+	// Status answers with this status instead of handling the call.
+	Status int `json:"status,omitempty"`
+	// RetryAfterSeconds is sent as Retry-After along with Status.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+	// Hang never answers, so the call ends with the client's timeout.
+	Hang bool `json:"hang,omitempty"`
+}
+
+// Inject adds a fault.
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// matchFaultLocked returns a copy of the fault applying to the call and uses
+// up one of its times.
+func (s *Server) matchFaultLocked(method, idInstance string) *Fault {
+	for i, fault := range s.faults {
+		if (fault.Method != "" && fault.Method != method) || (fault.IDInstance != "" && fault.IDInstance != idInstance) {
+			continue
+		}
+		matched := *fault
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return &matched
+	}
+	return nil
+}
+
+// apply plays the fault and reports whether the call should still be handled
+// normally.
+func (s *Server) apply(w http.ResponseWriter, r *http.Request, fault *Fault) bool {
+	if fault.Hang {
+		select {
+		case <-r.Context().Done():
+		case <-s.closed:
+		}
+		return false
+	}
+
+	if fault.LatencyMS > 0 {
+		timer := time.NewTimer(time.Duration(fault.LatencyMS) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return false
+		case <-s.closed:
+			return false
+		}
+	}
+
+	if fault.Status == 0 {
+		return true
+	}
+	if fault.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(fault.RetryAfterSeconds))
+	}
+	writeError(w, fault.Status, "injected fault")
+	return false
+}
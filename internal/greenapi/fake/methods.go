@@ -0,0 +1,482 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Journal directions of Message.Type.
+const (
+	TypeOutgoing = "outgoing"
+	TypeIncoming = "incoming"
+)
+
+// Message is a journal entry in the shape of lastOutgoingMessages,
+// lastIncomingMessages and getChatHistory.
+type Message struct {
+	Type            string `json:"type"`
+	IDMessage       string `json:"idMessage"`
+	Timestamp       int64  `json:"timestamp"`
+	TypeMessage     string `json:"typeMessage"`
+	ChatID          string `json:"chatId"`
+	TextMessage     string `json:"textMessage,omitempty"`
+	DownloadURL     string `json:"downloadUrl,omitempty"`
+	FileName        string `json:"fileName,omitempty"`
+	Caption         string `json:"caption,omitempty"`
+	QuotedMessageID string `json:"quotedMessageId,omitempty"`
+	SendByAPI       bool   `json:"sendByApi,omitempty"`
+	StatusMessage   string `json:"statusMessage,omitempty"`
+	// Method is the GREEN-API method that sent the message.
+	Method string `json:"-"`
+	// Size is the number of bytes received by sendFileByUpload.
+	Size int64 `json:"-"`
+}
+
+const (
+	defaultReceiveTimeout = 5 * time.Second
+	maxReceiveTimeout     = 60 * time.Second
+	defaultJournalMinutes = 24 * 60
+	defaultHistoryCount   = 100
+	maxUploadMemory       = 1 << 20
+)
+
+type methodHandler func(w http.ResponseWriter, r *http.Request, c call)
+
+func (s *Server) methods() map[methodKey]methodHandler {
+	return map[methodKey]methodHandler{
+		{http.MethodGet, "getSettings"}:           s.getSettings,
+		{http.MethodPost, "setSettings"}:          s.setSettings,
+		{http.MethodGet, "getStateInstance"}:      s.getStateInstance,
+		{http.MethodPost, "sendMessage"}:          s.sendMessage,
+		{http.MethodPost, "sendFileByUrl"}:        s.sendFileByURL,
+		{http.MethodPost, "sendFileByUpload"}:     s.sendFileByUpload,
+		{http.MethodGet, "receiveNotification"}:   s.receiveNotification,
+		{http.MethodDelete, "deleteNotification"}: s.deleteNotification,
+		{http.MethodGet, "lastOutgoingMessages"}:  s.lastMessages(TypeOutgoing),
+		{http.MethodGet, "lastIncomingMessages"}:  s.lastMessages(TypeIncoming),
+		{http.MethodPost, "getChatHistory"}:       s.getChatHistory,
+		{http.MethodGet, "reboot"}:                s.reboot,
+		{http.MethodGet, "logout"}:                s.logout,
+		{http.MethodGet, "qr"}:                    s.qr,
+		{http.MethodPost, "getAuthorizationCode"}: s.getAuthorizationCode,
+		{http.MethodPost, "checkWhatsapp"}:        s.checkWhatsapp,
+		{http.MethodGet, "getContacts"}:           s.getContacts,
+		{http.MethodPost, "getContactInfo"}:       s.getContactInfo,
+	}
+}
+
+func (s *Server) getSettings(w http.ResponseWriter, _ *http.Request, c call) {
+	s.mu.Lock()
+	settings := make(map[string]any, len(c.inst.settings))
+	for key, value := range c.inst.settings {
+		settings[key] = value
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// setSettings applies the writable settings in the body. Flags must be sent
+// as "yes" or "no", like GREEN-API expects.
+func (s *Server) setSettings(w http.ResponseWriter, r *http.Request, c call) {
+	var body map[string]any
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	for key, value := range body {
+		switch {
+		case slices.Contains(flagSettings, key):
+			if value != "yes" && value != "no" {
+				writeError(w, http.StatusBadRequest, key+` must be "yes" or "no"`)
+				return
+			}
+		case key == "webhookUrl" || key == "webhookUrlToken":
+			if _, ok := value.(string); !ok {
+				writeError(w, http.StatusBadRequest, key+" must be a string")
+				return
+			}
+		case key == "delaySendMessagesMilliseconds":
+			if _, ok := value.(float64); !ok {
+				writeError(w, http.StatusBadRequest, key+" must be a number")
+				return
+			}
+		default:
+			delete(body, key)
+		}
+	}
+
+	s.mu.Lock()
+	for key, value := range body {
+		c.inst.settings[key] = value
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"saveSettings": true})
+}
+
+func (s *Server) getStateInstance(w http.ResponseWriter, _ *http.Request, c call) {
+	s.mu.Lock()
+	state := c.inst.state
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"stateInstance": state})
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request, c call) {
+	var body struct {
+		ChatID          string `json:"chatId"`
+		Message         string `json:"message"`
+		QuotedMessageID string `json:"quotedMessageId"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !validChatID(body.ChatID) {
+		writeError(w, http.StatusBadRequest, "chatId is invalid")
+		return
+	}
+	if body.Message == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	s.send(w, c, Message{
+		TypeMessage:     "textMessage",
+		ChatID:          body.ChatID,
+		TextMessage:     body.Message,
+		QuotedMessageID: body.QuotedMessageID,
+		Method:          "sendMessage",
+	})
+}
+
+func (s *Server) sendFileByURL(w http.ResponseWriter, r *http.Request, c call) {
+	var body struct {
+		ChatID   string `json:"chatId"`
+		URLFile  string `json:"urlFile"`
+		FileName string `json:"fileName"`
+		Caption  string `json:"caption"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !validChatID(body.ChatID) {
+		writeError(w, http.StatusBadRequest, "chatId is invalid")
+		return
+	}
+	if body.URLFile == "" || body.FileName == "" {
+		writeError(w, http.StatusBadRequest, "urlFile and fileName are required")
+		return
+	}
+
+	s.send(w, c, Message{
+		TypeMessage: "documentMessage",
+		ChatID:      body.ChatID,
+		DownloadURL: body.URLFile,
+		FileName:    body.FileName,
+		Caption:     body.Caption,
+		Method:      "sendFileByUrl",
+	})
+}
+
+func (s *Server) sendFileByUpload(w http.ResponseWriter, r *http.Request, c call) {
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		writeError(w, http.StatusBadRequest, "expected a multipart form")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	chatID := r.FormValue("chatId")
+	if !validChatID(chatID) {
+		writeError(w, http.StatusBadRequest, "chatId is invalid")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	size, err := io.Copy(io.Discard, file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "cannot read file")
+		return
+	}
+
+	fileName := r.FormValue("fileName")
+	if fileName == "" {
+		fileName = header.Filename
+	}
+	s.send(w, c, Message{
+		TypeMessage:     "documentMessage",
+		ChatID:          chatID,
+		FileName:        fileName,
+		Caption:         r.FormValue("caption"),
+		QuotedMessageID: r.FormValue("quotedMessageId"),
+		Method:          "sendFileByUpload",
+		Size:            size,
+	})
+}
+
+// send records msg as sent by the API and answers with its idMessage.
+func (s *Server) send(w http.ResponseWriter, c call, msg Message) {
+	msg.Type = TypeOutgoing
+	msg.SendByAPI = true
+	msg.StatusMessage = "sent"
+
+	s.mu.Lock()
+	msg = s.recordLocked(c.inst, msg)
+	s.mu.Unlock()
+
+	body := map[string]string{"idMessage": msg.IDMessage}
+	if msg.Method == "sendFileByUpload" {
+		body["urlFile"] = "https://fake.green-api.invalid/files/" + msg.IDMessage
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// receiveNotification returns the oldest queued notification, again and
+// again until it is deleted, waiting up to receiveTimeout seconds for one.
+func (s *Server) receiveNotification(w http.ResponseWriter, r *http.Request, c call) {
+	timeout := defaultReceiveTimeout
+	if seconds, err := strconv.Atoi(r.URL.Query().Get("receiveTimeout")); err == nil && seconds > 0 {
+		timeout = min(time.Duration(seconds)*time.Second, maxReceiveTimeout)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if len(c.inst.notifications) > 0 {
+			next := c.inst.notifications[0]
+			s.mu.Unlock()
+			writeJSON(w, http.StatusOK, next)
+			return
+		}
+		wake := c.inst.wake
+		s.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			writeJSON(w, http.StatusOK, nil)
+			return
+		case <-s.closed:
+			writeJSON(w, http.StatusOK, nil)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) deleteNotification(w http.ResponseWriter, _ *http.Request, c call) {
+	if len(c.extra) != 1 {
+		writeError(w, http.StatusBadRequest, "receiptId is required")
+		return
+	}
+	receiptID, err := strconv.ParseInt(c.extra[0], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "receiptId must be a number")
+		return
+	}
+
+	s.mu.Lock()
+	deleted := false
+	for i, queued := range c.inst.notifications {
+		if queued.ReceiptID == receiptID {
+			c.inst.notifications = append(c.inst.notifications[:i], c.inst.notifications[i+1:]...)
+			deleted = true
+			break
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"result": deleted})
+}
+
+func (s *Server) lastMessages(direction string) methodHandler {
+	return func(w http.ResponseWriter, r *http.Request, c call) {
+		minutes := defaultJournalMinutes
+		if raw := r.URL.Query().Get("minutes"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "minutes must be a positive number")
+				return
+			}
+			minutes = parsed
+		}
+
+		s.mu.Lock()
+		since := s.now().Add(-time.Duration(minutes) * time.Minute).Unix()
+		messages := newestFirst(c.inst.journal, func(msg Message) bool {
+			return msg.Type == direction && msg.Timestamp >= since
+		})
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, messages)
+	}
+}
+
+func (s *Server) getChatHistory(w http.ResponseWriter, r *http.Request, c call) {
+	var body struct {
+		ChatID string `json:"chatId"`
+		Count  int    `json:"count"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !validChatID(body.ChatID) {
+		writeError(w, http.StatusBadRequest, "chatId is invalid")
+		return
+	}
+	if body.Count <= 0 {
+		body.Count = defaultHistoryCount
+	}
+
+	s.mu.Lock()
+	messages := newestFirst(c.inst.journal, func(msg Message) bool {
+		return msg.ChatID == body.ChatID
+	})
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, messages[:min(body.Count, len(messages))])
+}
+
+func (s *Server) reboot(w http.ResponseWriter, _ *http.Request, _ call) {
+	writeJSON(w, http.StatusOK, map[string]bool{"isReboot": true})
+}
+
+// logout unlinks the phone: the instance stays notAuthorized until SetState
+// or the control API simulates a new link.
+func (s *Server) logout(w http.ResponseWriter, _ *http.Request, c call) {
+	s.mu.Lock()
+	c.inst.state = "notAuthorized"
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"isLogout": true})
+}
+
+// qrPNG is a 1x1 PNG standing in for a QR code.
+const qrPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAACklEQVR4nGNgAAAAAgABSK+kcQAAAABJRU5ErkJggg=="
+
+func (s *Server) qr(w http.ResponseWriter, _ *http.Request, c call) {
+	s.mu.Lock()
+	state := c.inst.state
+	s.mu.Unlock()
+
+	if state == "authorized" {
+		writeJSON(w, http.StatusOK, map[string]string{"type": "alreadyLogged", "message": "instance account already authorized"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"type": "qrCode", "message": qrPNG})
+}
+
+func (s *Server) getAuthorizationCode(w http.ResponseWriter, r *http.Request, c call) {
+	var body struct {
+		PhoneNumber int64 `json:"phoneNumber"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.PhoneNumber <= 0 {
+		writeError(w, http.StatusBadRequest, "phoneNumber is required")
+		return
+	}
+
+	s.mu.Lock()
+	authorized := c.inst.state == "authorized"
+	s.sequence++
+	code := fmt.Sprintf("FAKE%04X", s.sequence%0x10000)
+	s.mu.Unlock()
+
+	if authorized {
+		writeJSON(w, http.StatusOK, map[string]any{"status": false, "code": ""})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": true, "code": code})
+}
+
+func (s *Server) checkWhatsapp(w http.ResponseWriter, r *http.Request, _ call) {
+	var body struct {
+		PhoneNumber int64 `json:"phoneNumber"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.PhoneNumber <= 0 {
+		writeError(w, http.StatusBadRequest, "phoneNumber is required")
+		return
+	}
+
+	s.mu.Lock()
+	exists := !s.noWhatsapp[strconv.FormatInt(body.PhoneNumber, 10)]
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"existsWhatsapp": exists})
+}
+
+// getContacts lists every chat in the journal of the instance, in the order
+// they first appeared.
+func (s *Server) getContacts(w http.ResponseWriter, _ *http.Request, c call) {
+	contacts := []map[string]string{}
+	s.mu.Lock()
+	seen := make(map[string]bool)
+	for _, msg := range c.inst.journal {
+		if seen[msg.ChatID] {
+			continue
+		}
+		seen[msg.ChatID] = true
+		kind := "user"
+		if strings.HasSuffix(msg.ChatID, "@g.us") {
+			kind = "group"
+		}
+		contacts = append(contacts, map[string]string{
+			"id":          msg.ChatID,
+			"name":        "",
+			"contactName": "",
+			"type":        kind,
+		})
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, contacts)
+}
+
+func (s *Server) getContactInfo(w http.ResponseWriter, r *http.Request, _ call) {
+	var body struct {
+		ChatID string `json:"chatId"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !validChatID(body.ChatID) {
+		writeError(w, http.StatusBadRequest, "chatId is invalid")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chatId":      body.ChatID,
+		"name":        "",
+		"contactName": "",
+		"avatar":      "",
+		"email":       "",
+		"category":    "",
+		"description": "",
+		"isBusiness":  false,
+		"products":    []any{},
+	})
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+// validChatID accepts the chat ID suffixes GREEN-API knows.
+func validChatID(chatID string) bool {
+	for _, suffix := range []string{"@c.us", "@g.us", "@newsletter"} {
+		if strings.HasSuffix(chatID, suffix) && len(chatID) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
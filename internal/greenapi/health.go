@@ -0,0 +1,77 @@
+package greenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// BreakerStatus is the current state of one circuit breaker.
+type BreakerStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// Health summarizes what Client has seen of GREEN-API. Zero times mean that
+// no such attempt has happened since start.
+type Health struct {
+	LastSuccessAt time.Time
+	LastFailureAt time.Time
+	// LastError describes the last failure with tokens redacted.
+	LastError string
+	Breakers  []BreakerStatus
+}
+
+// upstreamHealth records the outcome of attempts that reached, or tried to
+// reach, GREEN-API. Breaker rejections and cancelled calls are not recorded.
+type upstreamHealth struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+func (h *upstreamHealth) record(at time.Time, statusCode int, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case err != nil:
+		h.lastFailure = at
+		h.lastError = redactError(err).Error()
+	case statusCode >= 500:
+		h.lastFailure = at
+		h.lastError = fmt.Sprintf("upstream status %d", statusCode)
+	default:
+		h.lastSuccess = at
+	}
+}
+
+// Health reports the last upstream success and failure and the state of every
+// live circuit breaker, sorted by name.
+func (c *Client) Health() Health {
+	c.health.mu.Lock()
+	health := Health{
+		LastSuccessAt: c.health.lastSuccess,
+		LastFailureAt: c.health.lastFailure,
+		LastError:     c.health.lastError,
+	}
+	c.health.mu.Unlock()
+
+	health.Breakers = []BreakerStatus{}
+	c.breakers.each(func(_ string, breaker *gobreaker.TwoStepCircuitBreaker) {
+		health.Breakers = append(health.Breakers, BreakerStatus{Name: breaker.Name(), State: breaker.State().String()})
+	})
+	sort.Slice(health.Breakers, func(i, j int) bool {
+		return health.Breakers[i].Name < health.Breakers[j].Name
+	})
+	return health
+}
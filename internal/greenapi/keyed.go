@@ -0,0 +1,161 @@
+package greenapi
+
+import (
+	"sync"
+	"time"
+)
+
+// keyedSet lazily creates one value per key, drops values that were not used
+// for idleTTL and never holds more than maxSize entries (least recently used
+// entries are evicted first). Entries reported by pinned are kept, so the set
+// may outgrow maxSize while they are, but never overflowFactor times maxSize:
+// past that, new keys share an overflow value until room is freed.
+type keyedSet[T any] struct {
+	mu        sync.Mutex
+	create    func(key string) T
+	entries   map[string]*keyedEntry[T]
+	idleTTL   time.Duration
+	maxSize   int
+	lastSweep time.Time
+	now       func() time.Time
+	// onEvict, when set, is called with the key of every dropped entry while
+	// the set is locked.
+	onEvict func(key string)
+	// pinned, when set, reports values that must not be dropped because
+	// recreating them would lose state, such as an open breaker.
+	pinned func(value T) bool
+	// overflowKey, when set, maps a key to the overflow value it shares once
+	// the set is full of pinned entries; by default all keys share
+	// defaultOverflowKey.
+	overflowKey func(key string) string
+	// overflow holds the shared values, created on first use and never
+	// dropped.
+	overflow map[string]T
+}
+
+const (
+	// defaultOverflowKey is passed to create for the shared overflow value.
+	defaultOverflowKey = "overflow"
+	// overflowFactor bounds pinned entries: keys are callers' idInstance
+	// values, so they must not grow the set without limit.
+	overflowFactor = 2
+)
+
+type keyedEntry[T any] struct {
+	value    T
+	lastUsed time.Time
+}
+
+func newKeyedSet[T any](idleTTL time.Duration, maxSize int, create func(key string) T) *keyedSet[T] {
+	return &keyedSet[T]{
+		create:  create,
+		entries: make(map[string]*keyedEntry[T]),
+		idleTTL: idleTTL,
+		maxSize: maxSize,
+		now:     time.Now,
+	}
+}
+
+func (s *keyedSet[T]) get(key string) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= s.idleTTL {
+		s.sweep(now)
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		entry.lastUsed = now
+		return entry.value
+	}
+
+	if s.maxSize > 0 && len(s.entries) >= s.maxSize {
+		s.evictOldest()
+		if len(s.entries) >= s.maxSize*overflowFactor {
+			return s.overflowValue(key)
+		}
+	}
+
+	entry := &keyedEntry[T]{value: s.create(key), lastUsed: now}
+	s.entries[key] = entry
+	return entry.value
+}
+
+// overflowValue returns the overflow value shared by key; callers hold s.mu.
+func (s *keyedSet[T]) overflowValue(key string) T {
+	shared := defaultOverflowKey
+	if s.overflowKey != nil {
+		shared = s.overflowKey(key)
+	}
+	value, ok := s.overflow[shared]
+	if !ok {
+		if s.overflow == nil {
+			s.overflow = make(map[string]T)
+		}
+		value = s.create(shared)
+		s.overflow[shared] = value
+	}
+	return value
+}
+
+// each calls fn for every live entry and overflow value.
+// fn must not call back into the set.
+func (s *keyedSet[T]) each(fn func(key string, value T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.entries {
+		fn(key, entry.value)
+	}
+	for key, value := range s.overflow {
+		fn(key, value)
+	}
+}
+
+func (s *keyedSet[T]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *keyedSet[T]) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.Sub(entry.lastUsed) >= s.idleTTL && !s.isPinned(entry) {
+			s.remove(key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (s *keyedSet[T]) evictOldest() {
+	var (
+		oldestKey  string
+		oldestUsed time.Time
+		found      bool
+	)
+	for key, entry := range s.entries {
+		if s.isPinned(entry) {
+			continue
+		}
+		if !found || entry.lastUsed.Before(oldestUsed) {
+			oldestKey = key
+			oldestUsed = entry.lastUsed
+			found = true
+		}
+	}
+	if found {
+		s.remove(oldestKey)
+	}
+}
+
+func (s *keyedSet[T]) isPinned(entry *keyedEntry[T]) bool {
+	return s.pinned != nil && s.pinned(entry.value)
+}
+
+func (s *keyedSet[T]) remove(key string) {
+	delete(s.entries, key)
+	if s.onEvict != nil {
+		s.onEvict(key)
+	}
+}
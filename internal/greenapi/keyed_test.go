@@ -0,0 +1,104 @@
+package greenapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedSet_CreatesLazilyAndReuses(t *testing.T) {
+	t.Parallel()
+
+	created := 0
+	set := newKeyedSet(time.Minute, 10, func(key string) string {
+		created++
+		return "value-" + key
+	})
+
+	require.Equal(t, "value-a", set.get("a"))
+	require.Equal(t, "value-a", set.get("a"))
+	require.Equal(t, 1, created)
+}
+
+func TestKeyedSet_EvictsIdleEntries(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	set := newKeyedSet(time.Minute, 10, func(key string) string { return key })
+	set.now = func() time.Time { return now }
+
+	set.get("a")
+	now = now.Add(30 * time.Second)
+	set.get("b")
+	now = now.Add(45 * time.Second)
+	set.get("b")
+
+	require.Equal(t, 1, set.len())
+}
+
+func TestKeyedSet_BoundedByMaxSize(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	set := newKeyedSet(time.Hour, 2, func(key string) string { return key })
+	set.now = func() time.Time { return now }
+
+	set.get("a")
+	now = now.Add(time.Second)
+	set.get("b")
+	now = now.Add(time.Second)
+	set.get("a")
+	now = now.Add(time.Second)
+	set.get("c")
+
+	keys := map[string]bool{}
+	set.each(func(key string, _ string) { keys[key] = true })
+	require.Equal(t, map[string]bool{"a": true, "c": true}, keys)
+}
+
+func TestKeyedSet_KeepsPinnedEntries(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	set := newKeyedSet(time.Minute, 2, func(key string) string { return key })
+	set.now = func() time.Time { return now }
+	set.pinned = func(value string) bool { return value == "open" }
+
+	set.get("open")
+	now = now.Add(time.Second)
+	set.get("b")
+	now = now.Add(time.Second)
+	set.get("c")
+
+	keys := map[string]bool{}
+	set.each(func(key string, _ string) { keys[key] = true })
+	require.Equal(t, map[string]bool{"open": true, "c": true}, keys)
+
+	now = now.Add(2 * time.Minute)
+	set.get("d")
+	keys = map[string]bool{}
+	set.each(func(key string, _ string) { keys[key] = true })
+	require.Equal(t, map[string]bool{"open": true, "d": true}, keys)
+}
+
+func TestKeyedSet_FoldsKeysPastHardCapIntoOverflow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	set := newKeyedSet(time.Hour, 2, func(key string) string { return "value-" + key })
+	set.now = func() time.Time { return now }
+	set.pinned = func(string) bool { return true }
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.Equal(t, "value-"+key, set.get(key))
+		now = now.Add(time.Second)
+	}
+	require.Equal(t, "value-overflow", set.get("e"))
+	require.Equal(t, "value-overflow", set.get("f"))
+	require.Equal(t, 4, set.len(), "pinned entries stop at twice maxSize")
+	require.Equal(t, "value-a", set.get("a"), "known keys keep their own value")
+
+	set.pinned = func(value string) bool { return value != "value-b" }
+	require.Equal(t, "value-e", set.get("e"), "a key gets its own value once room is freed")
+}
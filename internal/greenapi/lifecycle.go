@@ -0,0 +1,55 @@
+package greenapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Reboot restarts the instance. It is only retried when the request provably
+// never reached GREEN-API.
+func (c *Client) Reboot(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "reboot",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "reboot", apiTokenInstance),
+		retry:      retryUnsent,
+	})
+}
+
+// Logout unlinks the phone from the instance. It is only retried when the
+// request provably never reached GREEN-API.
+func (c *Client) Logout(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "logout",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "logout", apiTokenInstance),
+		retry:      retryUnsent,
+	})
+}
+
+// QR returns the current QR code for linking a phone; decode it into QR.
+func (c *Client) QR(ctx context.Context, idInstance, apiTokenInstance string) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "qr",
+		httpMethod: http.MethodGet,
+		path:       instancePath(idInstance, "qr", apiTokenInstance),
+	})
+}
+
+// GetAuthorizationCode requests a code for linking the phone phoneNumber
+// (international format, digits only) without scanning a QR code. Each call
+// issues a new code, so it is only retried when the request provably never
+// reached GREEN-API.
+func (c *Client) GetAuthorizationCode(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (Response, error) {
+	return c.do(ctx, call{
+		idInstance: idInstance,
+		method:     "getAuthorizationCode",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "getAuthorizationCode", apiTokenInstance),
+		retry:      retryUnsent,
+		payload:    map[string]int64{"phoneNumber": phoneNumber},
+	})
+}
@@ -0,0 +1,61 @@
+package greenapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_LifecycleCallsAreNotRetriedAfterReachingUpstream(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	ctx := context.Background()
+
+	for name, call := range map[string]func() (Response, error){
+		"reboot": func() (Response, error) { return client.Reboot(ctx, "1101000001", "token") },
+		"logout": func() (Response, error) { return client.Logout(ctx, "1101000001", "token") },
+		"getAuthorizationCode": func() (Response, error) {
+			return client.GetAuthorizationCode(ctx, "1101000001", "token", 79991234567)
+		},
+	} {
+		atomic.StoreInt32(&requests, 0)
+		resp, err := call()
+		require.NoError(t, err, name)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode, name)
+		require.Equal(t, int32(1), atomic.LoadInt32(&requests), name)
+	}
+}
+
+func TestClient_GetAuthorizationCodeSendsNumber(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/waInstance1101000001/getAuthorizationCode/token", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"phoneNumber":79991234567}`, string(body))
+		_, _ = w.Write([]byte(`{"status":true,"code":"WZGRHKQ9"}`))
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(testConfig(server.URL), zap.NewNop()).GetAuthorizationCode(context.Background(), "1101000001", "token", 79991234567)
+	require.NoError(t, err)
+	var code AuthorizationCode
+	require.NoError(t, Decode(resp, DecodeStrict, &code))
+	require.Equal(t, "WZGRHKQ9", code.Code)
+}
@@ -0,0 +1,412 @@
+package greenapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// DecodeMode sets how strictly a response body is checked against its model.
+type DecodeMode string
+
+const (
+	// DecodeLenient ignores unknown fields and values, so new GREEN-API
+	// fields never break a call.
+	DecodeLenient DecodeMode = "lenient"
+	// DecodeStrict rejects unknown fields, unknown enum values and missing
+	// required fields, so changes of the upstream contract surface at once.
+	DecodeStrict DecodeMode = "strict"
+)
+
+// ErrUnexpectedResponse is returned when a response body does not match its
+// model.
+var ErrUnexpectedResponse = errors.New("unexpected green-api response")
+
+// maxErrorText bounds the plain-text error body kept in ErrorBody.Message.
+const maxErrorText = 512
+
+// Flag is a GREEN-API "yes"/"no" setting. It also accepts JSON booleans and
+// is written as one.
+type Flag bool
+
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "null":
+	case `"yes"`, "true":
+		*f = true
+	case `"no"`, "false":
+		*f = false
+	default:
+		return fmt.Errorf(`flag must be "yes" or "no", got %s`, data)
+	}
+	return nil
+}
+
+// Settings is the getSettings response.
+type Settings struct {
+	WID                               string `json:"wid"`
+	CountryInstance                   string `json:"countryInstance"`
+	TypeAccount                       string `json:"typeAccount"`
+	WebhookURL                        string `json:"webhookUrl"`
+	WebhookURLToken                   string `json:"webhookUrlToken"`
+	DelaySendMessagesMilliseconds     int    `json:"delaySendMessagesMilliseconds"`
+	MarkIncomingMessagesReaded        Flag   `json:"markIncomingMessagesReaded"`
+	MarkIncomingMessagesReadedOnReply Flag   `json:"markIncomingMessagesReadedOnReply"`
+	OutgoingWebhook                   Flag   `json:"outgoingWebhook"`
+	OutgoingMessageWebhook            Flag   `json:"outgoingMessageWebhook"`
+	OutgoingAPIMessageWebhook         Flag   `json:"outgoingAPIMessageWebhook"`
+	IncomingWebhook                   Flag   `json:"incomingWebhook"`
+	StateWebhook                      Flag   `json:"stateWebhook"`
+	KeepOnlineStatus                  Flag   `json:"keepOnlineStatus"`
+	PollMessageWebhook                Flag   `json:"pollMessageWebhook"`
+	IncomingCallWebhook               Flag   `json:"incomingCallWebhook"`
+	EditedMessageWebhook              Flag   `json:"editedMessageWebhook"`
+	DeletedMessageWebhook             Flag   `json:"deletedMessageWebhook"`
+	// Deprecated by GREEN-API but still returned by older instances.
+	DeviceWebhook         *Flag  `json:"deviceWebhook,omitempty"`
+	StatusInstanceWebhook *Flag  `json:"statusInstanceWebhook,omitempty"`
+	IncomingBlockWebhook  *Flag  `json:"incomingBlockWebhook,omitempty"`
+	EnableMessagesHistory *Flag  `json:"enableMessagesHistory,omitempty"`
+	SharedSession         *Flag  `json:"sharedSession,omitempty"`
+	ProxyInstance         string `json:"proxyInstance,omitempty"`
+}
+
+func (s *Settings) check() error {
+	if s.WID == "" {
+		return errors.New("wid is missing")
+	}
+	return nil
+}
+
+// InstanceSettings are the writable fields of Settings, the setSettings body.
+// Nil fields are left unchanged. Unknown fields are rejected when decoding,
+// so a misspelt setting fails instead of being silently ignored.
+type InstanceSettings struct {
+	WebhookURL                        *string `json:"webhookUrl,omitempty"`
+	WebhookURLToken                   *string `json:"webhookUrlToken,omitempty"`
+	DelaySendMessagesMilliseconds     *int    `json:"delaySendMessagesMilliseconds,omitempty"`
+	MarkIncomingMessagesReaded        *Flag   `json:"markIncomingMessagesReaded,omitempty"`
+	MarkIncomingMessagesReadedOnReply *Flag   `json:"markIncomingMessagesReadedOnReply,omitempty"`
+	OutgoingWebhook                   *Flag   `json:"outgoingWebhook,omitempty"`
+	OutgoingMessageWebhook            *Flag   `json:"outgoingMessageWebhook,omitempty"`
+	OutgoingAPIMessageWebhook         *Flag   `json:"outgoingAPIMessageWebhook,omitempty"`
+	IncomingWebhook                   *Flag   `json:"incomingWebhook,omitempty"`
+	StateWebhook                      *Flag   `json:"stateWebhook,omitempty"`
+	KeepOnlineStatus                  *Flag   `json:"keepOnlineStatus,omitempty"`
+	PollMessageWebhook                *Flag   `json:"pollMessageWebhook,omitempty"`
+	IncomingCallWebhook               *Flag   `json:"incomingCallWebhook,omitempty"`
+	EditedMessageWebhook              *Flag   `json:"editedMessageWebhook,omitempty"`
+	DeletedMessageWebhook             *Flag   `json:"deletedMessageWebhook,omitempty"`
+}
+
+func (s *InstanceSettings) UnmarshalJSON(data []byte) error {
+	type plain InstanceSettings
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(s))
+}
+
+// SettingChange is one field that InstanceSettings would change. From and To
+// are JSON values; flags are booleans. Non-empty webhookUrlToken values are
+// shown as RedactedToken.
+type SettingChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// Diff lists the fields of s that differ from current, in the order of
+// InstanceSettings.
+func (s InstanceSettings) Diff(current Settings) []SettingChange {
+	requested, currentValues := jsonFields(s), jsonFields(current)
+	changes := []SettingChange{}
+	for _, field := range settingFields {
+		to, ok := requested[field]
+		if !ok {
+			continue
+		}
+		from := currentValues[field]
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+		if field == "webhookUrlToken" {
+			from, to = redactSecret(from), redactSecret(to)
+		}
+		changes = append(changes, SettingChange{Field: field, From: from, To: to})
+	}
+	return changes
+}
+
+func redactSecret(value any) any {
+	if value == nil || value == "" {
+		return value
+	}
+	return RedactedToken
+}
+
+// Only keeps the fields named in changes, so setSettings gets no more than
+// what actually changes.
+func (s InstanceSettings) Only(changes []SettingChange) InstanceSettings {
+	keep := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		keep[change.Field] = true
+	}
+	var only InstanceSettings
+	value, target := reflect.ValueOf(s), reflect.ValueOf(&only).Elem()
+	for i := range value.NumField() {
+		if keep[jsonName(value.Type().Field(i))] {
+			target.Field(i).Set(value.Field(i))
+		}
+	}
+	return only
+}
+
+// payload is s in the wire format of setSettings, with "yes"/"no" flags.
+func (s InstanceSettings) payload() map[string]any {
+	payload := jsonFields(s)
+	for field, value := range payload {
+		if flag, ok := value.(bool); ok {
+			payload[field] = "no"
+			if flag {
+				payload[field] = "yes"
+			}
+		}
+	}
+	return payload
+}
+
+// settingFields are the JSON names of the InstanceSettings fields in order.
+var settingFields = func() []string {
+	t := reflect.TypeFor[InstanceSettings]()
+	fields := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		fields = append(fields, jsonName(t.Field(i)))
+	}
+	return fields
+}()
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// jsonFields is v encoded as a JSON object and decoded into a map, which
+// brings settings of both models to the same representation.
+func jsonFields(v any) map[string]any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	return fields
+}
+
+// SaveSettingsResult is the setSettings response.
+type SaveSettingsResult struct {
+	SaveSettings bool `json:"saveSettings"`
+}
+
+// InstanceState is the authorization state of an instance.
+type InstanceState string
+
+const (
+	StateNotAuthorized InstanceState = "notAuthorized"
+	StateAuthorized    InstanceState = "authorized"
+	StateBlocked       InstanceState = "blocked"
+	StateSleepMode     InstanceState = "sleepMode"
+	StateStarting      InstanceState = "starting"
+	StateYellowCard    InstanceState = "yellowCard"
+)
+
+func (s InstanceState) known() bool {
+	switch s {
+	case StateNotAuthorized, StateAuthorized, StateBlocked, StateSleepMode, StateStarting, StateYellowCard:
+		return true
+	}
+	return false
+}
+
+// State is the getStateInstance response.
+type State struct {
+	StateInstance InstanceState `json:"stateInstance"`
+}
+
+func (s *State) check() error {
+	if !s.StateInstance.known() {
+		return fmt.Errorf("unknown stateInstance %q", s.StateInstance)
+	}
+	return nil
+}
+
+// SendResult is the response of every send method.
+type SendResult struct {
+	IDMessage string `json:"idMessage"`
+}
+
+func (r *SendResult) check() error {
+	if r.IDMessage == "" {
+		return errors.New("idMessage is missing")
+	}
+	return nil
+}
+
+// RebootResult is the reboot response.
+type RebootResult struct {
+	IsReboot bool `json:"isReboot"`
+}
+
+// LogoutResult is the logout response.
+type LogoutResult struct {
+	IsLogout bool `json:"isLogout"`
+}
+
+// QRType tells what the Message of a QR response holds.
+type QRType string
+
+const (
+	// QRCode: Message is a base64-encoded PNG image of the QR code.
+	QRCode QRType = "qrCode"
+	// QRAlreadyLogged: the instance is already authorized.
+	QRAlreadyLogged QRType = "alreadyLogged"
+	// QRError: Message explains why no QR code was issued.
+	QRError QRType = "error"
+)
+
+// QR is the qr response.
+type QR struct {
+	Type    QRType `json:"type"`
+	Message string `json:"message"`
+}
+
+func (q *QR) check() error {
+	switch q.Type {
+	case QRCode:
+		if _, err := q.PNG(); err != nil {
+			return err
+		}
+	case QRAlreadyLogged, QRError:
+	default:
+		return fmt.Errorf("unknown qr type %q", q.Type)
+	}
+	return nil
+}
+
+// PNG decodes the image of a QRCode response.
+func (q *QR) PNG() ([]byte, error) {
+	if q.Type != QRCode {
+		return nil, fmt.Errorf("qr type %q has no image", q.Type)
+	}
+	image, err := base64.StdEncoding.DecodeString(q.Message)
+	if err != nil {
+		return nil, fmt.Errorf("qr image: %w", err)
+	}
+	if !bytes.HasPrefix(image, pngSignature) {
+		return nil, errors.New("qr image is not a PNG")
+	}
+	return image, nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// AuthorizationCode is the getAuthorizationCode response. The code is entered
+// on the phone under Linked devices > Link with phone number.
+type AuthorizationCode struct {
+	Status bool   `json:"status"`
+	Code   string `json:"code"`
+}
+
+func (a *AuthorizationCode) check() error {
+	if a.Status && a.Code == "" {
+		return errors.New("code is missing")
+	}
+	return nil
+}
+
+// CheckWhatsappResult is the checkWhatsapp response.
+type CheckWhatsappResult struct {
+	ExistsWhatsapp bool `json:"existsWhatsapp"`
+}
+
+// ErrorBody is what GREEN-API says about a failed call. Plain-text bodies end
+// up in Message.
+type ErrorBody struct {
+	Code        int    `json:"code,omitempty"`
+	Description string `json:"description,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+// StatusError is a non-2xx GREEN-API response.
+type StatusError struct {
+	StatusCode int
+	Body       ErrorBody
+}
+
+func (e *StatusError) Error() string {
+	if text := e.Text(); text != "" {
+		return fmt.Sprintf("green-api responded with status %d: %s", e.StatusCode, text)
+	}
+	return fmt.Sprintf("green-api responded with status %d", e.StatusCode)
+}
+
+// Text is the most specific explanation found in the body.
+func (e *StatusError) Text() string {
+	if e.Body.Message != "" {
+		return e.Body.Message
+	}
+	return e.Body.Description
+}
+
+// Decode unmarshals the body of a 2xx response into v, which should point to
+// one of the models of this package. Any other status is returned as a
+// *StatusError. In strict mode the body must match the model exactly.
+func Decode(resp Response, mode DecodeMode, v any) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: decodeErrorBody(resp.Body)}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(resp.Body))
+	if mode == DecodeStrict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnexpectedResponse, err)
+	}
+	if mode != DecodeStrict {
+		return nil
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: data after the JSON value", ErrUnexpectedResponse)
+	}
+	if checked, ok := v.(interface{ check() error }); ok {
+		if err := checked.check(); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnexpectedResponse, err)
+		}
+	}
+	return nil
+}
+
+func decodeErrorBody(body []byte) ErrorBody {
+	var parsed ErrorBody
+	if json.Unmarshal(body, &parsed) == nil {
+		return parsed
+	}
+
+	text := strings.TrimSpace(string(body))
+	if len(text) > maxErrorText {
+		text = text[:maxErrorText]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return ErrorBody{Message: text}
+}
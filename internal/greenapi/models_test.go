@@ -0,0 +1,158 @@
+package greenapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode_Settings(t *testing.T) {
+	t.Parallel()
+
+	resp := Response{StatusCode: http.StatusOK, Body: []byte(`{
+		"wid":"79990000000@c.us","typeAccount":"trial","delaySendMessagesMilliseconds":1000,
+		"outgoingWebhook":"yes","incomingWebhook":"no","keepOnlineStatus":true,"deviceWebhook":"no"
+	}`)}
+
+	var settings Settings
+	require.NoError(t, Decode(resp, DecodeStrict, &settings))
+	require.Equal(t, "79990000000@c.us", settings.WID)
+	require.Equal(t, 1000, settings.DelaySendMessagesMilliseconds)
+	require.True(t, bool(settings.OutgoingWebhook))
+	require.False(t, bool(settings.IncomingWebhook))
+	require.True(t, bool(settings.KeepOnlineStatus))
+	require.NotNil(t, settings.DeviceWebhook)
+	require.Nil(t, settings.SharedSession)
+}
+
+func TestDecode_Modes(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name       string
+		body       string
+		into       func() any
+		lenientErr bool
+		strictErr  bool
+	}{
+		{name: "send result", body: `{"idMessage":"BAE5"}`, into: func() any { return &SendResult{} }},
+		{name: "unknown field", body: `{"idMessage":"BAE5","queued":true}`, into: func() any { return &SendResult{} }, strictErr: true},
+		{name: "missing idMessage", body: `{}`, into: func() any { return &SendResult{} }, strictErr: true},
+		{name: "trailing data", body: `{"idMessage":"BAE5"} {}`, into: func() any { return &SendResult{} }, strictErr: true},
+		{name: "known state", body: `{"stateInstance":"yellowCard"}`, into: func() any { return &State{} }},
+		{name: "unknown state", body: `{"stateInstance":"hibernating"}`, into: func() any { return &State{} }, strictErr: true},
+		{name: "bad flag", body: `{"wid":"1@c.us","incomingWebhook":"maybe"}`, into: func() any { return &Settings{} }, lenientErr: true, strictErr: true},
+		{name: "not json", body: `<html>`, into: func() any { return &SendResult{} }, lenientErr: true, strictErr: true},
+	}
+
+	for _, tc := range cases {
+		resp := Response{StatusCode: http.StatusOK, Body: []byte(tc.body)}
+
+		err := Decode(resp, DecodeLenient, tc.into())
+		require.Equal(t, tc.lenientErr, err != nil, tc.name+": lenient")
+		if err != nil {
+			require.ErrorIs(t, err, ErrUnexpectedResponse, tc.name)
+		}
+
+		err = Decode(resp, DecodeStrict, tc.into())
+		require.Equal(t, tc.strictErr, err != nil, tc.name+": strict")
+		if err != nil {
+			require.ErrorIs(t, err, ErrUnexpectedResponse, tc.name)
+		}
+	}
+}
+
+func TestDecode_StatusError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		body string
+		want ErrorBody
+		text string
+	}{
+		{name: "json", body: `{"code":400,"description":"Bad Request","message":"chatId is invalid"}`, want: ErrorBody{Code: 400, Description: "Bad Request", Message: "chatId is invalid"}, text: "chatId is invalid"},
+		{name: "description only", body: `{"description":"Instance not found"}`, want: ErrorBody{Description: "Instance not found"}, text: "Instance not found"},
+		{name: "plain text", body: " Unauthorized\n", want: ErrorBody{Message: "Unauthorized"}, text: "Unauthorized"},
+		{name: "empty", body: "", want: ErrorBody{}},
+	}
+
+	for _, tc := range cases {
+		err := Decode(Response{StatusCode: http.StatusBadRequest, Body: []byte(tc.body)}, DecodeStrict, &SendResult{})
+
+		var statusErr *StatusError
+		require.True(t, errors.As(err, &statusErr), tc.name)
+		require.Equal(t, http.StatusBadRequest, statusErr.StatusCode, tc.name)
+		require.Equal(t, tc.want, statusErr.Body, tc.name)
+		require.Equal(t, tc.text, statusErr.Text(), tc.name)
+	}
+}
+
+func TestDecode_QR(t *testing.T) {
+	t.Parallel()
+
+	const png = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAACklEQVR4nGNgAAAAAgABSK+kcQAAAABJRU5ErkJggg=="
+	cases := []struct {
+		name  string
+		body  string
+		valid bool
+		image bool
+	}{
+		{name: "qr code", body: `{"type":"qrCode","message":"` + png + `"}`, valid: true, image: true},
+		{name: "already logged", body: `{"type":"alreadyLogged","message":"instance account already authorized"}`, valid: true},
+		{name: "not base64", body: `{"type":"qrCode","message":"not an image"}`},
+		{name: "not a png", body: `{"type":"qrCode","message":"aGVsbG8="}`},
+		{name: "unknown type", body: `{"type":"qrLink","message":"x"}`},
+	}
+	for _, tc := range cases {
+		var qr QR
+		err := Decode(Response{StatusCode: http.StatusOK, Body: []byte(tc.body)}, DecodeStrict, &qr)
+		if !tc.valid {
+			require.ErrorIs(t, err, ErrUnexpectedResponse, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+
+		image, err := qr.PNG()
+		if tc.image {
+			require.NoError(t, err, tc.name)
+			require.Equal(t, pngSignature, image[:len(pngSignature)], tc.name)
+		} else {
+			require.Error(t, err, tc.name)
+		}
+	}
+}
+
+func TestInstanceSettings(t *testing.T) {
+	t.Parallel()
+
+	var settings InstanceSettings
+	require.Error(t, json.Unmarshal([]byte(`{"incomingWebhooks":"yes"}`), &settings), "misspelt field")
+	require.Error(t, json.Unmarshal([]byte(`{"incomingWebhook":"maybe"}`), &settings), "invalid flag")
+	require.Error(t, json.Unmarshal([]byte(`{"delaySendMessagesMilliseconds":"fast"}`), &settings), "invalid number")
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"webhookUrl":"https://example.com/hook","webhookUrlToken":"secret",
+		"incomingWebhook":true,"outgoingWebhook":"no","delaySendMessagesMilliseconds":1000
+	}`), &settings))
+
+	current := Settings{WID: "79990000000@c.us", WebhookURL: "", OutgoingWebhook: true, DelaySendMessagesMilliseconds: 1000}
+	changes := settings.Diff(current)
+	require.Equal(t, []SettingChange{
+		{Field: "webhookUrl", From: "", To: "https://example.com/hook"},
+		{Field: "webhookUrlToken", From: "", To: RedactedToken},
+		{Field: "outgoingWebhook", From: true, To: false},
+		{Field: "incomingWebhook", From: false, To: true},
+	}, changes)
+
+	only := settings.Only(changes)
+	require.Nil(t, only.DelaySendMessagesMilliseconds, "unchanged field is dropped")
+	require.Equal(t, map[string]any{
+		"webhookUrl":      "https://example.com/hook",
+		"webhookUrlToken": "secret",
+		"outgoingWebhook": "no",
+		"incomingWebhook": "yes",
+	}, only.payload())
+}
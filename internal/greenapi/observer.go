@@ -0,0 +1,60 @@
+package greenapi
+
+import "time"
+
+// Outcomes reported to Observer.ObserveAttempt.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeServerError = "server_error"
+	OutcomeError       = "error"
+	OutcomeBreakerOpen = "breaker_open"
+)
+
+// Observer receives instrumentation events from Client. Implementations must
+// be safe for concurrent use and must not block.
+type Observer interface {
+	// ObserveAttempt is called once per HTTP attempt of a GREEN-API method.
+	ObserveAttempt(method, outcome string, duration time.Duration)
+	// ObserveRetry is called when Client.do repeats an attempt; reason is
+	// "error" or "status".
+	ObserveRetry(method, reason string)
+	// ObserveBreakerState reports the state of a circuit breaker when it is
+	// created and on every transition.
+	ObserveBreakerState(breaker, state string)
+	// ForgetBreaker is called when an idle breaker is dropped.
+	ForgetBreaker(breaker string)
+}
+
+// ClientOption customizes a Client built by NewClient.
+type ClientOption func(*Client)
+
+// WithObserver reports attempts, retries and breaker states to observer.
+func WithObserver(observer Observer) ClientOption {
+	return func(c *Client) {
+		c.observer = observer
+	}
+}
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveAttempt(string, string, time.Duration) {}
+func (nopObserver) ObserveRetry(string, string)                  {}
+func (nopObserver) ObserveBreakerState(string, string)           {}
+func (nopObserver) ForgetBreaker(string)                         {}
+
+// attemptOutcome classifies a finished attempt for ObserveAttempt.
+func attemptOutcome(statusCode int, err error) string {
+	switch {
+	case err != nil && isBreakerRejection(err):
+		return OutcomeBreakerOpen
+	case err != nil:
+		return OutcomeError
+	case statusCode >= 500:
+		return OutcomeServerError
+	case statusCode >= 400:
+		return OutcomeClientError
+	default:
+		return OutcomeSuccess
+	}
+}
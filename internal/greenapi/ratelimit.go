@@ -0,0 +1,107 @@
+package greenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"green-api/internal/config"
+)
+
+// Method classes with separate rate limits.
+const (
+	ClassRead = "read"
+	ClassSend = "send"
+)
+
+var ErrRateLimited = errors.New("green-api rate limit exceeded")
+
+// RateLimitError reports that a call was not made because the instance used
+// up its rate limit. RetryAfter is when a token would have been available.
+type RateLimitError struct {
+	IDInstance string
+	Class      string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v for instance %s (%s), retry after %s", ErrRateLimited, e.IDInstance, e.Class, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// rateLimits holds the token buckets of every instance and class. Buckets of
+// idle instances are dropped like circuit breakers are.
+type rateLimits struct {
+	cfg      config.RateLimitConfig
+	limiters *keyedSet[*rate.Limiter]
+}
+
+func newRateLimits(cfg config.RateLimitConfig, breakers config.CircuitBreakerConfig) *rateLimits {
+	if cfg.SendsPerSecond == 0 && cfg.ReadsPerSecond == 0 {
+		return nil
+	}
+	limits := &rateLimits{cfg: cfg}
+	limits.limiters = newKeyedSet(breakers.IdleTTL(), breakers.Capacity(), func(key string) *rate.Limiter {
+		perSecond, burst := limits.bucket(key[strings.LastIndexByte(key, '/')+1:])
+		return rate.NewLimiter(rate.Limit(perSecond), max(burst, 1))
+	})
+	// A drained bucket is kept until it refills: a new one would allow another
+	// full burst at once. Instances past the cap on such buckets share one
+	// bucket per class.
+	limits.limiters.pinned = func(limiter *rate.Limiter) bool {
+		return limiter.Tokens() < float64(limiter.Burst())
+	}
+	limits.limiters.overflowKey = func(key string) string {
+		return defaultOverflowKey + key[strings.LastIndexByte(key, '/'):]
+	}
+	return limits
+}
+
+func (l *rateLimits) bucket(class string) (float64, int) {
+	if class == ClassSend {
+		return l.cfg.SendsPerSecond, l.cfg.SendBurst
+	}
+	return l.cfg.ReadsPerSecond, l.cfg.ReadBurst
+}
+
+// wait takes a token for cl. It waits while the token becomes available
+// within the configured maximum wait and before ctx expires, and otherwise
+// fails at once with a RateLimitError without using up the token.
+func (l *rateLimits) wait(ctx context.Context, cl call) error {
+	if l == nil {
+		return nil
+	}
+	class := cl.class()
+	if perSecond, _ := l.bucket(class); perSecond == 0 {
+		return nil
+	}
+
+	reservation := l.limiters.get(cl.idInstance + "/" + class).Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return nil
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if delay > l.cfg.MaxWait() || (hasDeadline && time.Now().Add(delay).After(deadline)) {
+		reservation.Cancel()
+		return &RateLimitError{IDInstance: cl.idInstance, Class: class, RetryAfter: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
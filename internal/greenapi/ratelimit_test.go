@@ -0,0 +1,147 @@
+package greenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+)
+
+func rateLimitedClient(t *testing.T, limits config.RateLimitConfig) (*Client, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"idMessage":"1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig(server.URL)
+	cfg.RateLimit = limits
+	return NewClient(cfg, zap.NewNop()), &requests
+}
+
+func sendHi(client *Client, idInstance string) error {
+	_, err := client.SendMessage(context.Background(), idInstance, "token", SendMessageParams{ChatID: "79991234567@c.us", Message: "hi"})
+	return err
+}
+
+func TestClient_RateLimitFailsFast(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 0.1, SendBurst: 1})
+
+	require.NoError(t, sendHi(client, "1101000001"))
+	err := sendHi(client, "1101000001")
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.True(t, NotSent(err), "a limited call never reaches GREEN-API")
+
+	var limitErr *RateLimitError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, ClassSend, limitErr.Class)
+	require.Greater(t, limitErr.RetryAfter, 5*time.Second)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestClient_RateLimitWaitsWithinMaxWait(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 20, SendBurst: 1, MaxWaitMS: 1000})
+
+	started := time.Now()
+	require.NoError(t, sendHi(client, "1101000001"))
+	require.NoError(t, sendHi(client, "1101000001"))
+	require.GreaterOrEqual(t, time.Since(started), 40*time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestClient_RateLimitBoundedByContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 1, SendBurst: 1, MaxWaitMS: 5000})
+	require.NoError(t, sendHi(client, "1101000001"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := client.SendMessage(ctx, "1101000001", "token", SendMessageParams{ChatID: "79991234567@c.us", Message: "hi"})
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Less(t, time.Since(started), 50*time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestClient_RateLimitBuckets(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 0.1, SendBurst: 1, ReadsPerSecond: 0.1, ReadBurst: 1})
+
+	require.NoError(t, sendHi(client, "1101000001"))
+	require.NoError(t, sendHi(client, "1101000002"), "instances have their own buckets")
+
+	_, err := client.GetSettings(context.Background(), "1101000001", "token")
+	require.NoError(t, err, "reads do not use the send bucket")
+	_, err = client.GetSettings(context.Background(), "1101000001", "token")
+	require.ErrorIs(t, err, ErrRateLimited)
+
+	require.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestClient_RateLimitDisabledPerClass(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 0.1, SendBurst: 1})
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetSettings(context.Background(), "1101000001", "token")
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestClient_RateLimitKeepsDrainedBuckets(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"idMessage":"1"}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.RateLimit = config.RateLimitConfig{SendsPerSecond: 0.1, SendBurst: 1}
+	cfg.CircuitBreaker.MaxBreakers = 1
+	client := NewClient(cfg, zap.NewNop())
+
+	require.NoError(t, sendHi(client, "1101000001"))
+	require.NoError(t, sendHi(client, "1101000002"), "a full set grows past its capacity")
+	require.ErrorIs(t, sendHi(client, "1101000001"), ErrRateLimited, "the drained bucket was not replaced by a full one")
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestClient_RateLimitCapsDrainedBuckets(t *testing.T) {
+	t.Parallel()
+
+	client, requests := rateLimitedClient(t, config.RateLimitConfig{SendsPerSecond: 0.1, SendBurst: 1, ReadsPerSecond: 0.1, ReadBurst: 1})
+	client.limits.limiters.maxSize = 1
+
+	require.NoError(t, sendHi(client, "1"))
+	require.NoError(t, sendHi(client, "2"))
+	require.Equal(t, 2, client.limits.limiters.len(), "drained buckets stop at twice the capacity")
+
+	require.NoError(t, sendHi(client, "3"), "the first instance past the cap takes the overflow send bucket")
+	require.ErrorIs(t, sendHi(client, "4"), ErrRateLimited, "later ones share it")
+	_, err := client.GetStateInstance(context.Background(), "5", "token")
+	require.NoError(t, err, "reads have their own overflow bucket")
+	require.Equal(t, 2, client.limits.limiters.len())
+	require.Equal(t, int32(4), atomic.LoadInt32(requests))
+}
@@ -0,0 +1,47 @@
+package greenapi
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+)
+
+// RedactedToken replaces apiTokenInstance values in errors, logs and dumps.
+const RedactedToken = "[REDACTED]"
+
+// instanceTokenPattern matches "/waInstance{id}/{method}/{apiTokenInstance}"
+// wherever it appears: paths, full URLs or error messages quoting them.
+var instanceTokenPattern = regexp.MustCompile(`(/waInstance[^/\s"']*/[A-Za-z]+/)([^/?#\s"']+)`)
+
+// Redact scrubs apiTokenInstance values from s.
+func Redact(s string) string {
+	return instanceTokenPattern.ReplaceAllString(s, "${1}"+RedactedToken)
+}
+
+// redactedError hides tokens in the message of the wrapped error while
+// keeping it available to errors.Is / errors.As.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return Redact(e.err.Error())
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError scrubs tokens from err. url.Error values produced by the HTTP
+// client are rewritten in place so that unwrapping them does not leak either.
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = Redact(urlErr.URL)
+	}
+	return &redactedError{err: err}
+}
@@ -0,0 +1,79 @@
+package greenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedact_ScrubsTokenFromPathsAndURLs(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"/waInstance1101/getSettings/abc123":                               "/waInstance1101/getSettings/[REDACTED]",
+		`Get "https://api.green-api.com/waInstance1/sendMessage/t0k": EOF`: `Get "https://api.green-api.com/waInstance1/sendMessage/[REDACTED]": EOF`,
+		"/waInstance1/deleteNotification/secret/42":                        "/waInstance1/deleteNotification/[REDACTED]/42",
+		"no token here": "no token here",
+	}
+	for input, expected := range cases {
+		require.Equal(t, expected, Redact(input))
+	}
+}
+
+func TestRedactError_KeepsErrorChain(t *testing.T) {
+	t.Parallel()
+
+	urlErr := &url.Error{Op: "Get", URL: "http://x/waInstance1/getSettings/secret", Err: context.DeadlineExceeded}
+	err := redactError(urlErr)
+
+	require.NotContains(t, err.Error(), "secret")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var unwrapped *url.Error
+	require.True(t, errors.As(err, &unwrapped))
+	require.NotContains(t, unwrapped.Error(), "secret")
+}
+
+func TestClient_ErrorsAndLogsNeverContainToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	baseURL := server.URL
+	server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg := testConfig(baseURL)
+	cfg.Retry.MaxRetries = 0
+	client := NewClient(cfg, zap.New(core))
+
+	_, err := client.SendMessage(context.Background(), "1101000001", "super-secret-token", SendMessageParams{ChatID: "77771234567@c.us", Message: "hi"})
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "super-secret-token")
+	require.Contains(t, err.Error(), RedactedToken)
+
+	require.NotZero(t, logs.Len())
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			require.NotContains(t, fmt.Sprint(value), "super-secret-token")
+		}
+	}
+}
+
+func TestClient_InvalidBaseURLDoesNotLeakToken(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(testConfig("://invalid"), zap.NewNop())
+
+	_, err := client.GetSettings(context.Background(), "1101000001", "super-secret-token")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "super-secret-token")
+}
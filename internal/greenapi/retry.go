@@ -0,0 +1,117 @@
+package greenapi
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"green-api/internal/config"
+)
+
+// newBackOff returns the delays between the attempts of one call.
+func newBackOff(cfg config.GreenAPIRetryConfig) backoff.BackOff {
+	switch cfg.StrategyName() {
+	case config.RetryExponential:
+		exponential := &backoff.ExponentialBackOff{
+			InitialInterval:     cfg.Delay(),
+			RandomizationFactor: backoff.DefaultRandomizationFactor,
+			Multiplier:          cfg.Factor(),
+			MaxInterval:         cfg.MaxDelay(),
+			Stop:                backoff.Stop,
+			Clock:               backoff.SystemClock,
+		}
+		exponential.Reset()
+		return exponential
+	case config.RetryDecorrelatedJitter:
+		jitter := &decorrelatedJitter{base: cfg.Delay(), max: cfg.MaxDelay()}
+		jitter.Reset()
+		return jitter
+	default:
+		return backoff.NewConstantBackOff(cfg.Delay())
+	}
+}
+
+// decorrelatedJitter picks every delay at random between the base delay and
+// three times the previous delay, capped at max, so that clients retrying at
+// the same time drift apart.
+type decorrelatedJitter struct {
+	base, max, prev time.Duration
+}
+
+func (d *decorrelatedJitter) NextBackOff() time.Duration {
+	if d.base <= 0 {
+		return 0
+	}
+	upper := max(d.prev*3, d.base)
+	delay := min(d.base+rand.N(upper-d.base+1), d.max)
+	d.prev = delay
+	return delay
+}
+
+func (d *decorrelatedJitter) Reset() {
+	d.prev = d.base
+}
+
+// retryBudget bounds the time a call may spend on retries.
+type retryBudget struct {
+	deadline time.Time
+}
+
+// newRetryBudget ends the budget at whichever comes first: budget from now or
+// the deadline of ctx.
+func newRetryBudget(ctx context.Context, budget time.Duration) retryBudget {
+	deadline := time.Now().Add(budget)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	return retryBudget{deadline: deadline}
+}
+
+// pause waits for delay before the next attempt. It returns false without
+// sleeping when ctx is already done or the delay would outlast the budget, and
+// false when ctx is cancelled while waiting.
+func (b retryBudget) pause(ctx context.Context, delay time.Duration) bool {
+	if ctx.Err() != nil || time.Now().Add(delay).After(b.deadline) {
+		return false
+	}
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryAfter reads the Retry-After header of a 429 or 503 response, given as
+// seconds or as an HTTP date.
+func retryAfter(resp Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Headers.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,174 @@
+package greenapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+)
+
+func TestNewBackOff_Strategies(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GreenAPIRetryConfig{DelayMS: 100, MaxDelayMS: 1000}
+
+	constant := newBackOff(cfg)
+	require.Equal(t, 100*time.Millisecond, constant.NextBackOff())
+	require.Equal(t, 100*time.Millisecond, constant.NextBackOff())
+
+	cfg.Strategy = config.RetryExponential
+	exponential := newBackOff(cfg)
+	var last time.Duration
+	for i := 0; i < 10; i++ {
+		last = exponential.NextBackOff()
+		require.LessOrEqual(t, last, 1500*time.Millisecond)
+	}
+	require.Greater(t, last, 400*time.Millisecond)
+
+	cfg.Strategy = config.RetryDecorrelatedJitter
+	jitter := newBackOff(cfg)
+	prev := 100 * time.Millisecond
+	for i := 0; i < 50; i++ {
+		delay := jitter.NextBackOff()
+		require.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		require.LessOrEqual(t, delay, min(prev*3, time.Second))
+		prev = delay
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	cases := []struct {
+		name   string
+		status int
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{name: "seconds", status: http.StatusTooManyRequests, header: "3", want: 3 * time.Second, ok: true},
+		{name: "date", status: http.StatusServiceUnavailable, header: "Fri, 02 Jan 2026 15:04:15 GMT", want: 10 * time.Second, ok: true},
+		{name: "past date", status: http.StatusTooManyRequests, header: "Fri, 02 Jan 2026 15:00:00 GMT", want: 0, ok: true},
+		{name: "garbage", status: http.StatusTooManyRequests, header: "soon"},
+		{name: "negative", status: http.StatusTooManyRequests, header: "-1"},
+		{name: "other status", status: http.StatusBadGateway, header: "3"},
+	}
+	for _, tc := range cases {
+		resp := Response{StatusCode: tc.status, Headers: http.Header{"Retry-After": {tc.header}}}
+		got, ok := retryAfter(resp, now)
+		require.Equal(t, tc.ok, ok, tc.name)
+		require.Equal(t, tc.want, got, tc.name)
+	}
+}
+
+func TestClient_SendRetriedOn429AfterRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"idMessage":"1"}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.DelaySeconds = 0
+	cfg.Retry.DelayMS = 5000
+	client := NewClient(cfg, zap.NewNop())
+
+	started := time.Now()
+	resp, err := client.SendMessage(context.Background(), "1101000001", "token", SendMessageParams{ChatID: "79991234567@c.us", Message: "hi"})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.Less(t, time.Since(started), time.Second)
+}
+
+func TestClient_RetryStopsWhenDelayExceedsBudget(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.BudgetMS = 5000
+	client := NewClient(cfg, zap.NewNop())
+
+	started := time.Now()
+	resp, err := client.GetSettings(context.Background(), "1101000001", "token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	require.Less(t, time.Since(started), time.Second)
+}
+
+func TestClient_RetryBoundedByContextDeadline(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.DelaySeconds = 0
+	cfg.Retry.DelayMS = 200
+	client := NewClient(cfg, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	resp, err := client.GetSettings(ctx, "1101000001", "token")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestClient_NoRetryAfterContextCancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+		cancel()
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Retry.DelaySeconds = 0
+	cfg.Retry.DelayMS = 2000
+	client := NewClient(cfg, zap.NewNop())
+
+	started := time.Now()
+	_, _ = client.GetSettings(ctx, "1101000001", "token")
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	require.Less(t, time.Since(started), time.Second)
+}
+
+func TestRetryPolicy_429RetriedForSends(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, retryUnsent.allowsStatus(http.StatusTooManyRequests))
+	require.True(t, retrySafe.allowsStatus(http.StatusTooManyRequests))
+	require.False(t, retryUnsent.allowsStatus(http.StatusServiceUnavailable))
+}
@@ -0,0 +1,31 @@
+package greenapi
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "green-api/internal/greenapi"
+
+// Span attributes of GREEN-API attempts. The token is never recorded.
+const (
+	AttributeMethod       = attribute.Key("greenapi.method")
+	AttributeIDInstance   = attribute.Key("greenapi.id_instance")
+	AttributeAttempt      = attribute.Key("greenapi.attempt")
+	AttributeBreakerState = attribute.Key("greenapi.breaker.state")
+)
+
+// WithTracerProvider records a client span for every HTTP attempt.
+func WithTracerProvider(provider trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// recordSpanError marks span as failed with the redacted error message.
+func recordSpanError(span trace.Span, err error) {
+	err = redactError(err)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
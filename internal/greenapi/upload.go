@@ -0,0 +1,162 @@
+package greenapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// UploadParams are the sendFileByUpload form fields sent with the file.
+type UploadParams struct {
+	ChatID   string
+	FileName string
+	Caption  string
+	// QuotedMessageID makes the file a reply to an earlier message.
+	QuotedMessageID string
+	// ContentType is the type of the file part; GREEN-API otherwise goes by
+	// the fileName extension.
+	ContentType string
+}
+
+// SendFileByUpload streams file to sendFileByUpload on the media host as a
+// multipart form, so the file is never held in memory. The body can only be
+// read once, so the call is never retried. An error returned by file is passed
+// back as is and does not count as an upstream failure.
+func (c *Client) SendFileByUpload(ctx context.Context, idInstance, apiTokenInstance string, params UploadParams, file io.Reader) (Response, error) {
+	cl := call{
+		idInstance: idInstance,
+		method:     "sendFileByUpload",
+		httpMethod: http.MethodPost,
+		path:       instancePath(idInstance, "sendFileByUpload", apiTokenInstance),
+		send:       true,
+	}
+	fullURL := c.mediaURL + cl.path
+	if _, err := url.ParseRequestURI(fullURL); err != nil {
+		return Response{}, &UpstreamError{Message: "invalid upstream url", Cause: redactError(err)}
+	}
+
+	if err := c.limits.wait(ctx, cl); err != nil {
+		return Response{}, c.failure(cl, err)
+	}
+
+	source := &uploadSource{reader: file}
+	cl.source = source.failure
+
+	body, pipe := io.Pipe()
+	form := multipart.NewWriter(pipe)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		pipe.CloseWithError(writeUploadForm(form, params, source))
+	}()
+
+	breaker := c.breakers.get(c.breakerKey(cl))
+	resp, err := c.executeOnce(ctx, breaker, cl, 1, fullURL, body, form.FormDataContentType())
+	if err != nil {
+		// Unblock the writer before waiting for it to stop reading file.
+		body.CloseWithError(err)
+		<-written
+		if sourceErr := source.failure(); sourceErr != nil {
+			return Response{}, sourceErr
+		}
+		err = redactError(err)
+		c.logger.Debug("green_api_attempt_failed",
+			zap.String("method", cl.method),
+			zap.String("url", Redact(fullURL)),
+			zap.Int("attempt", 1),
+			zap.Error(err),
+		)
+		return Response{}, c.failure(cl, err)
+	}
+
+	response, readErr := readResponse(resp)
+	body.Close()
+	<-written
+	if readErr != nil {
+		return Response{}, &UpstreamError{Message: "read green-api response", Cause: redactError(readErr)}
+	}
+
+	c.logger.Debug("green_api_attempt_completed",
+		zap.String("method", cl.method),
+		zap.String("url", Redact(fullURL)),
+		zap.Int("attempt", 1),
+		zap.Int("status", response.StatusCode),
+	)
+	return response, nil
+}
+
+// writeUploadForm writes the text fields before the file, so GREEN-API knows
+// the chat before the file arrives.
+func writeUploadForm(form *multipart.Writer, params UploadParams, file io.Reader) error {
+	fields := [][2]string{
+		{"chatId", params.ChatID},
+		{"fileName", params.FileName},
+		{"caption", params.Caption},
+		{"quotedMessageId", params.QuotedMessageID},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="`+escapeQuotes(params.FileName)+`"`)
+	contentType := params.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// uploadSource remembers the first error returned by the caller's reader, so
+// it can be told apart from a failure of the upstream connection.
+type uploadSource struct {
+	reader io.Reader
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *uploadSource) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mu.Unlock()
+	}
+	return n, err
+}
+
+func (s *uploadSource) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
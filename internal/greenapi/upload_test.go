@@ -0,0 +1,121 @@
+package greenapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClient_SendFileByUploadStreamsMultipartForm(t *testing.T) {
+	t.Parallel()
+
+	file := bytes.Repeat([]byte("0123456789"), 100_000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/waInstance1101000001/sendFileByUpload/token", r.URL.Path)
+		require.Equal(t, int64(-1), r.ContentLength)
+
+		form, err := r.MultipartReader()
+		require.NoError(t, err)
+		fields := map[string]string{}
+		for {
+			part, err := form.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			if part.FormName() == "file" {
+				require.Equal(t, "horse.png", part.FileName())
+				require.Equal(t, "image/png", part.Header.Get("Content-Type"))
+				require.Equal(t, file, data)
+				continue
+			}
+			fields[part.FormName()] = string(data)
+		}
+		require.Equal(t, map[string]string{
+			"chatId":   "79991234567@c.us",
+			"fileName": "horse.png",
+			"caption":  "Look",
+		}, fields)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"idMessage":"U1","urlFile":"https://sw-media-out.storage.greenapi.net/horse.png"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(testConfig(server.URL), zap.NewNop())
+	resp, err := client.SendFileByUpload(context.Background(), "1101000001", "token", UploadParams{
+		ChatID:      "79991234567@c.us",
+		FileName:    "horse.png",
+		Caption:     "Look",
+		ContentType: "image/png",
+	}, bytes.NewReader(file))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"idMessage":"U1","urlFile":"https://sw-media-out.storage.greenapi.net/horse.png"}`, string(resp.Body))
+}
+
+func TestClient_SendFileByUploadUsesMediaURL(t *testing.T) {
+	t.Parallel()
+
+	var mediaRequests int32
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&mediaRequests, 1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"idMessage":"U2"}`))
+	}))
+	defer media.Close()
+
+	cfg := testConfig("http://127.0.0.1:1")
+	cfg.MediaURL = media.URL
+	client := NewClient(cfg, zap.NewNop())
+	_, err := client.SendFileByUpload(context.Background(), "1101000001", "token", UploadParams{ChatID: "79991234567@c.us", FileName: "a.txt"}, bytes.NewReader([]byte("hello")))
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&mediaRequests))
+}
+
+func TestClient_SendFileByUploadReturnsReaderErrorWithoutTrippingBreaker(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"idMessage":"U3"}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.CircuitBreaker.ConsecutiveFailures = 1
+	cfg.CircuitBreaker.MinRequests = 1
+	client := NewClient(cfg, zap.NewNop())
+
+	errBroken := errors.New("client went away")
+	file := io.MultiReader(bytes.NewReader(make([]byte, 64<<10)), &erroringReader{err: errBroken})
+	_, err := client.SendFileByUpload(context.Background(), "1101000001", "token", UploadParams{ChatID: "79991234567@c.us", FileName: "a.bin"}, file)
+	require.ErrorIs(t, err, errBroken)
+
+	var upstreamErr *UpstreamError
+	require.False(t, errors.As(err, &upstreamErr))
+	require.Equal(t, "closed", client.Health().Breakers[0].State)
+
+	_, err = client.SendFileByUpload(context.Background(), "1101000001", "token", UploadParams{ChatID: "79991234567@c.us", FileName: "a.bin"}, bytes.NewReader([]byte("ok")))
+	require.NoError(t, err)
+}
+
+type erroringReader struct {
+	err error
+}
+
+func (r *erroringReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
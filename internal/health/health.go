@@ -0,0 +1,244 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/instance"
+)
+
+// Upstream reports what the GREEN-API client has seen so far.
+type Upstream interface {
+	Health() greenapi.Health
+}
+
+// QueueDepth reports the number of jobs waiting in the outbound queue.
+type QueueDepth interface {
+	Depth() (int, error)
+}
+
+// StateClient is the GREEN-API method used by live probes.
+type StateClient interface {
+	GetStateInstance(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+}
+
+// Report is the body of GET /health/details.
+type Report struct {
+	Ready     bool           `json:"ready"`
+	Reasons   []string       `json:"reasons,omitempty"`
+	Upstream  UpstreamReport `json:"upstream"`
+	Queue     *QueueReport   `json:"queue,omitempty"`
+	Probes    []ProbeResult  `json:"probes,omitempty"`
+	CheckedAt time.Time      `json:"checkedAt"`
+}
+
+type UpstreamReport struct {
+	LastSuccessAt *time.Time               `json:"lastSuccessAt,omitempty"`
+	LastFailureAt *time.Time               `json:"lastFailureAt,omitempty"`
+	LastError     string                   `json:"lastError,omitempty"`
+	Breakers      []greenapi.BreakerStatus `json:"breakers"`
+}
+
+type QueueReport struct {
+	Depth    int    `json:"depth"`
+	MaxDepth int    `json:"maxDepth,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ProbeResult is the outcome of the last getStateInstance call for a
+// registered instance. State is the stateInstance reported by GREEN-API; an
+// instance that is reachable but not authorized still counts as OK.
+type ProbeResult struct {
+	Instance  string    `json:"instance"`
+	OK        bool      `json:"ok"`
+	State     string    `json:"state,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Checker builds readiness reports. Liveness is not its concern: a process
+// that can answer HTTP is alive, so GET /health stays unconditional.
+type Checker struct {
+	upstream Upstream
+	now      func() time.Time
+
+	queue         QueueDepth
+	maxQueueDepth int
+
+	client        StateClient
+	resolver      instance.Resolver
+	probeNames    []string
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+
+	probeMu     sync.Mutex
+	probedAt    time.Time
+	probeResult []ProbeResult
+}
+
+// Option customizes a Checker built by NewChecker.
+type Option func(*Checker)
+
+// WithQueue reports the queue depth. A positive maxDepth makes the service
+// not ready while more jobs than that are waiting.
+func WithQueue(queue QueueDepth, maxDepth int) Option {
+	return func(c *Checker) {
+		c.queue = queue
+		c.maxQueueDepth = maxDepth
+	}
+}
+
+// WithProbe calls getStateInstance for the named registered instances, at
+// most once per interval however often readiness is polled.
+func WithProbe(client StateClient, resolver instance.Resolver, names []string, interval, timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.client = client
+		c.resolver = resolver
+		c.probeNames = names
+		c.probeInterval = interval
+		c.probeTimeout = timeout
+	}
+}
+
+func NewChecker(upstream Upstream, opts ...Option) *Checker {
+	c := &Checker{upstream: upstream, now: time.Now}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check reports upstream, queue and probe state. The service is not ready
+// when every known circuit breaker is open, the queue is over its limit or
+// cannot be read, or a probe failed.
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Ready: true, CheckedAt: c.now().UTC()}
+
+	upstream := c.upstream.Health()
+	report.Upstream = UpstreamReport{
+		LastSuccessAt: optionalTime(upstream.LastSuccessAt),
+		LastFailureAt: optionalTime(upstream.LastFailureAt),
+		LastError:     upstream.LastError,
+		Breakers:      upstream.Breakers,
+	}
+	if allOpen(upstream.Breakers) {
+		report.notReady("all circuit breakers are open")
+	}
+
+	if c.queue != nil {
+		report.Queue = c.checkQueue()
+		switch {
+		case report.Queue.Error != "":
+			report.notReady("queue is unavailable")
+		case c.maxQueueDepth > 0 && report.Queue.Depth > c.maxQueueDepth:
+			report.notReady(fmt.Sprintf("queue depth %d exceeds %d", report.Queue.Depth, c.maxQueueDepth))
+		}
+	}
+
+	if len(c.probeNames) > 0 {
+		report.Probes = c.probe(ctx)
+		for _, result := range report.Probes {
+			if !result.OK {
+				report.notReady(fmt.Sprintf("probe of instance %q failed", result.Instance))
+			}
+		}
+	}
+	return report
+}
+
+func (r *Report) notReady(reason string) {
+	r.Ready = false
+	r.Reasons = append(r.Reasons, reason)
+}
+
+func (c *Checker) checkQueue() *QueueReport {
+	depth, err := c.queue.Depth()
+	if err != nil {
+		return &QueueReport{MaxDepth: c.maxQueueDepth, Error: err.Error()}
+	}
+	return &QueueReport{Depth: depth, MaxDepth: c.maxQueueDepth}
+}
+
+// probe returns cached results while they are younger than the probe
+// interval. Concurrent callers wait for a single round of calls.
+func (c *Checker) probe(ctx context.Context) []ProbeResult {
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+
+	now := c.now()
+	if c.probeResult != nil && now.Sub(c.probedAt) < c.probeInterval {
+		return c.probeResult
+	}
+
+	results := make([]ProbeResult, len(c.probeNames))
+	var wg sync.WaitGroup
+	for i, name := range c.probeNames {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = c.probeInstance(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	c.probedAt = now
+	c.probeResult = results
+	return results
+}
+
+func (c *Checker) probeInstance(ctx context.Context, name string) ProbeResult {
+	result := ProbeResult{Instance: name, CheckedAt: c.now().UTC()}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.probeTimeout)
+	defer cancel()
+
+	creds, err := c.resolver.Resolve(ctx, name)
+	if err != nil {
+		result.Error = fmt.Sprintf("resolve instance: %v", err)
+		return result
+	}
+
+	resp, err := c.client.GetStateInstance(ctx, creds.IDInstance, creds.APITokenInstance)
+	if err != nil {
+		result.Error = greenapi.Redact(err.Error())
+		return result
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("upstream status %d", resp.StatusCode)
+		return result
+	}
+
+	var state struct {
+		StateInstance string `json:"stateInstance"`
+	}
+	if json.Unmarshal(resp.Body, &state) == nil {
+		result.State = state.StateInstance
+	}
+	result.OK = true
+	return result
+}
+
+func allOpen(breakers []greenapi.BreakerStatus) bool {
+	if len(breakers) == 0 {
+		return false
+	}
+	for _, breaker := range breakers {
+		if breaker.State != "open" {
+			return false
+		}
+	}
+	return true
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/instance"
+)
+
+type fakeUpstream struct {
+	health greenapi.Health
+}
+
+func (f fakeUpstream) Health() greenapi.Health {
+	return f.health
+}
+
+type fakeQueue struct {
+	depth int
+	err   error
+}
+
+func (f fakeQueue) Depth() (int, error) {
+	return f.depth, f.err
+}
+
+type fakeStateClient struct {
+	calls  int32
+	status int
+	err    error
+}
+
+func (f *fakeStateClient) GetStateInstance(_ context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if f.err != nil {
+		return greenapi.Response{}, f.err
+	}
+	return greenapi.Response{StatusCode: f.status, Body: []byte(`{"stateInstance":"authorized"}`)}, nil
+}
+
+func testResolver(t *testing.T) instance.Resolver {
+	t.Helper()
+
+	registry, err := instance.NewRegistry([]instance.Instance{{
+		Name:        "sales",
+		Credentials: instance.Credentials{IDInstance: "1101000001", APITokenInstance: "token"},
+	}})
+	require.NoError(t, err)
+	return registry
+}
+
+func TestChecker_ReadyWithoutTraffic(t *testing.T) {
+	t.Parallel()
+
+	report := NewChecker(fakeUpstream{health: greenapi.Health{Breakers: []greenapi.BreakerStatus{}}}).Check(context.Background())
+	require.True(t, report.Ready)
+	require.Empty(t, report.Reasons)
+	require.Nil(t, report.Upstream.LastSuccessAt)
+	require.Nil(t, report.Queue)
+}
+
+func TestChecker_NotReadyWhenAllBreakersOpen(t *testing.T) {
+	t.Parallel()
+
+	failedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	upstream := fakeUpstream{health: greenapi.Health{
+		LastFailureAt: failedAt,
+		LastError:     "upstream status 502",
+		Breakers: []greenapi.BreakerStatus{
+			{Name: "green-api:1", State: "open"},
+			{Name: "green-api:2", State: "open"},
+		},
+	}}
+
+	report := NewChecker(upstream).Check(context.Background())
+	require.False(t, report.Ready)
+	require.Equal(t, []string{"all circuit breakers are open"}, report.Reasons)
+	require.Equal(t, failedAt, *report.Upstream.LastFailureAt)
+
+	upstream.health.Breakers[1].State = "half-open"
+	require.True(t, NewChecker(upstream).Check(context.Background()).Ready)
+}
+
+func TestChecker_QueueDepth(t *testing.T) {
+	t.Parallel()
+
+	upstream := fakeUpstream{}
+
+	report := NewChecker(upstream, WithQueue(fakeQueue{depth: 3}, 0)).Check(context.Background())
+	require.True(t, report.Ready)
+	require.Equal(t, 3, report.Queue.Depth)
+
+	report = NewChecker(upstream, WithQueue(fakeQueue{depth: 11}, 10)).Check(context.Background())
+	require.False(t, report.Ready)
+	require.Equal(t, []string{"queue depth 11 exceeds 10"}, report.Reasons)
+
+	report = NewChecker(upstream, WithQueue(fakeQueue{err: errors.New("database not open")}, 0)).Check(context.Background())
+	require.False(t, report.Ready)
+	require.Equal(t, "database not open", report.Queue.Error)
+}
+
+func TestChecker_ProbeIsCachedForInterval(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeStateClient{status: http.StatusOK}
+	checker := NewChecker(fakeUpstream{}, WithProbe(client, testResolver(t), []string{"sales"}, time.Minute, time.Second))
+	now := time.Unix(0, 0)
+	checker.now = func() time.Time { return now }
+
+	report := checker.Check(context.Background())
+	require.True(t, report.Ready)
+	require.Equal(t, []ProbeResult{{Instance: "sales", OK: true, State: "authorized", CheckedAt: now.UTC()}}, report.Probes)
+
+	now = now.Add(30 * time.Second)
+	checker.Check(context.Background())
+	require.Equal(t, int32(1), atomic.LoadInt32(&client.calls))
+
+	now = now.Add(time.Minute)
+	checker.Check(context.Background())
+	require.Equal(t, int32(2), atomic.LoadInt32(&client.calls))
+}
+
+func TestChecker_FailedProbeIsRedacted(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeStateClient{err: errors.New(`Get "https://api.green-api.com/waInstance1101000001/getStateInstance/token": connection refused`)}
+	checker := NewChecker(fakeUpstream{}, WithProbe(client, testResolver(t), []string{"sales"}, time.Minute, time.Second))
+
+	report := checker.Check(context.Background())
+	require.False(t, report.Ready)
+	require.Equal(t, []string{`probe of instance "sales" failed`}, report.Reasons)
+	require.False(t, report.Probes[0].OK)
+	require.NotContains(t, report.Probes[0].Error, "/token")
+}
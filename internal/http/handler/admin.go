@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/auth"
+	"green-api/internal/middleware"
+	"green-api/internal/model"
+)
+
+type AdminHandler struct {
+	authenticator *auth.Authenticator
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
+
+type apiKeyResponse struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Scopes    []auth.Scope `json:"scopes"`
+	CreatedAt time.Time    `json:"createdAt"`
+	RevokedAt *time.Time   `json:"revokedAt,omitempty"`
+	Token     string       `json:"token,omitempty"`
+}
+
+func NewAdminHandler(authenticator *auth.Authenticator) *AdminHandler {
+	return &AdminHandler{authenticator: authenticator}
+}
+
+func (h *AdminHandler) RegisterRoutes(router gin.IRouter) {
+	admin := router.Group("/admin", middleware.RequireScope(auth.ScopeAdminKeys))
+	admin.POST("/keys", h.createKey)
+	admin.GET("/keys", h.listKeys)
+	admin.DELETE("/keys/:id", h.revokeKey)
+}
+
+func (h *AdminHandler) createKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "validation_error",
+			Message:    "invalid request payload",
+			Details:    map[string]string{"field": "scopes", "message": err.Error()},
+		})
+		return
+	}
+
+	key, token, err := h.authenticator.Issue(c.Request.Context(), req.Name, scopes)
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusInternalServerError,
+			Code:       "internal_error",
+			Message:    "create api key failed",
+		})
+		return
+	}
+
+	resp := toAPIKeyResponse(key)
+	resp.Token = token
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AdminHandler) listKeys(c *gin.Context) {
+	keys, err := h.authenticator.List(c.Request.Context())
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusInternalServerError,
+			Code:       "internal_error",
+			Message:    "list api keys failed",
+		})
+		return
+	}
+
+	items := make([]apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, toAPIKeyResponse(key))
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": items})
+}
+
+func (h *AdminHandler) revokeKey(c *gin.Context) {
+	err := h.authenticator.Revoke(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, auth.ErrNotFound):
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusNotFound,
+			Code:       "not_found",
+			Message:    "api key not found",
+		})
+		return
+	case err != nil:
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toAPIKeyResponse(key auth.Key) apiKeyResponse {
+	return apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/auth"
+	"green-api/internal/middleware"
+)
+
+func setupAdminRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+
+	id, token, hash, err := auth.NewToken()
+	require.NoError(t, err)
+	store, err := auth.NewStore("")
+	require.NoError(t, err)
+	authenticator := auth.NewAuthenticator(store, []auth.Key{{ID: id, Name: "admin", Hash: hash, Scopes: []auth.Scope{auth.ScopeAdminKeys}}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewAdminHandler(authenticator).RegisterRoutes(r.Group("/api/v1", middleware.APIKeyAuth(authenticator)))
+	return r, token
+}
+
+func adminRequest(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAdminKeys_CreateListRevoke(t *testing.T) {
+	t.Parallel()
+
+	r, adminToken := setupAdminRouter(t)
+
+	resp := adminRequest(r, http.MethodPost, "/api/v1/admin/keys", adminToken, `{"name":"ops","scopes":["message:send"]}`)
+	require.Equal(t, http.StatusCreated, resp.Code)
+
+	var created struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	require.NotEmpty(t, created.Token)
+	require.NotContains(t, resp.Body.String(), `"hash"`)
+
+	resp = adminRequest(r, http.MethodGet, "/api/v1/admin/keys", adminToken, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), created.ID)
+	require.NotContains(t, resp.Body.String(), created.Token)
+
+	// The new key authenticates but may not manage keys.
+	resp = adminRequest(r, http.MethodGet, "/api/v1/admin/keys", created.Token, "")
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = adminRequest(r, http.MethodDelete, "/api/v1/admin/keys/"+created.ID, adminToken, "")
+	require.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = adminRequest(r, http.MethodGet, "/api/v1/admin/keys", created.Token, "")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestAdminKeys_RejectsUnknownScope(t *testing.T) {
+	t.Parallel()
+
+	r, adminToken := setupAdminRouter(t)
+
+	resp := adminRequest(r, http.MethodPost, "/api/v1/admin/keys", adminToken, `{"name":"ops","scopes":["everything"]}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "validation_error")
+
+	resp = adminRequest(r, http.MethodDelete, "/api/v1/admin/keys/missing", adminToken, "")
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/auth"
+	"green-api/internal/middleware"
+	"green-api/internal/service"
+)
+
+// registerContactRoutes adds the endpoints that look up contacts and check
+// numbers for WhatsApp.
+func (h *GreenAPIHandler) registerContactRoutes(router gin.IRouter) {
+	read := middleware.RequireScope(auth.ScopeContactsRead)
+	router.POST("/check-whatsapp", read, h.checkWhatsapp)
+	router.POST("/contacts", read, h.getContacts)
+	router.POST("/contact-info", read, h.getContactInfo)
+}
+
+func (h *GreenAPIHandler) checkWhatsapp(c *gin.Context) {
+	var req service.CheckWhatsappRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	check, err := h.service.core.CheckWhatsapp(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, check)
+}
+
+func (h *GreenAPIHandler) getContacts(c *gin.Context) {
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.core.GetContacts(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+}
+
+func (h *GreenAPIHandler) getContactInfo(c *gin.Context) {
+	var req service.ContactInfoRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.core.GetContactInfo(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContacts_Endpoints(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "check whatsapp",
+			path:   "/api/v1/check-whatsapp",
+			body:   `{"idInstance":"1101000001","apiTokenInstance":"token","phoneNumber":"+7 999 123-45-67"}`,
+			status: http.StatusOK,
+			want:   `{"chatId":"79991234567@c.us","existsWhatsapp":true,"cached":false}`,
+		},
+		{
+			name:   "check whatsapp of a group",
+			path:   "/api/v1/check-whatsapp",
+			body:   `{"idInstance":"1101000001","apiTokenInstance":"token","phoneNumber":"120363043968066561@g.us"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "contacts",
+			path:   "/api/v1/contacts",
+			body:   `{"idInstance":"1101000001","apiTokenInstance":"token"}`,
+			status: http.StatusOK,
+			want:   `[{"id":"79990000000@c.us","name":"Ivan","contactName":"","type":"user"}]`,
+		},
+		{
+			name:   "contact info",
+			path:   "/api/v1/contact-info",
+			body:   `{"idInstance":"1101000001","apiTokenInstance":"token","chatId":"+79991234567"}`,
+			status: http.StatusOK,
+			want:   `{"chatId":"79991234567@c.us","name":"Ivan"}`,
+		},
+		{
+			name:   "contact info without chat",
+			path:   "/api/v1/contact-info",
+			body:   `{"idInstance":"1101000001","apiTokenInstance":"token"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range cases {
+		resp := postLifecycle(t, tc.path, tc.body)
+		require.Equal(t, tc.status, resp.Code, tc.name)
+		if tc.want != "" {
+			require.JSONEq(t, tc.want, resp.Body.String(), tc.name)
+		}
+	}
+}
@@ -5,6 +5,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"green-api/internal/auth"
+	"green-api/internal/config"
+	"green-api/internal/idempotency"
+	"green-api/internal/middleware"
 	"green-api/internal/model"
 	"green-api/internal/service"
 )
@@ -17,22 +21,46 @@ type Service interface {
 }
 
 type GreenAPIHandler struct {
-	service *HandlerService
+	service     *HandlerService
+	idempotency *idempotency.Store
+	uploads     config.UploadConfig
 }
 
 type HandlerService struct {
 	core *service.Service
 }
 
-func NewGreenAPIHandler(core *service.Service) *GreenAPIHandler {
-	return &GreenAPIHandler{service: &HandlerService{core: core}}
+func NewGreenAPIHandler(core *service.Service, idempotencyStore *idempotency.Store, uploads config.UploadConfig) *GreenAPIHandler {
+	return &GreenAPIHandler{service: &HandlerService{core: core}, idempotency: idempotencyStore, uploads: uploads}
 }
 
 func (h *GreenAPIHandler) RegisterRoutes(router gin.IRouter) {
-	router.POST("/settings", h.getSettings)
-	router.POST("/state", h.getState)
-	router.POST("/send-message", h.sendMessage)
-	router.POST("/send-file-by-url", h.sendFileByURL)
+	router.GET("/instances", middleware.RequireScope(auth.ScopeInstancesRead), h.listInstances)
+	router.POST("/settings", middleware.RequireScope(auth.ScopeSettingsRead), h.getSettings)
+	router.PUT("/settings", middleware.RequireScope(auth.ScopeSettingsWrite), h.setSettings)
+	router.POST("/state", middleware.RequireScope(auth.ScopeStateRead), h.getState)
+	router.POST("/send-message", middleware.RequireScope(auth.ScopeMessageSend), middleware.Idempotency(h.idempotency, "sendMessage"), h.sendMessage)
+	router.POST("/send-file-by-url", middleware.RequireScope(auth.ScopeFileSend), middleware.Idempotency(h.idempotency, "sendFileByUrl"), h.sendFileByURL)
+	// No idempotency here: the middleware buffers the request body.
+	router.POST("/send-file", middleware.RequireScope(auth.ScopeFileSend), h.sendFile)
+	router.GET("/jobs/:id", middleware.RequireScope(auth.ScopeMessageSend), h.getJob)
+	h.registerLifecycleRoutes(router)
+	h.registerContactRoutes(router)
+
+	schedules := router.Group("/schedules", middleware.RequireAnyScope(auth.ScopeMessageSend, auth.ScopeFileSend))
+	schedules.GET("", h.listSchedules)
+	schedules.GET("/:id", h.getSchedule)
+	schedules.PATCH("/:id", h.reschedule)
+	schedules.DELETE("/:id", h.cancelSchedule)
+}
+
+func (h *GreenAPIHandler) listInstances(c *gin.Context) {
+	instances, err := h.service.core.ListInstances(c.Request.Context())
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"instances": instances})
 }
 
 func (h *GreenAPIHandler) getSettings(c *gin.Context) {
@@ -49,6 +77,22 @@ func (h *GreenAPIHandler) getSettings(c *gin.Context) {
 	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
 }
 
+// setSettings answers with the changes against the current settings, made
+// or, with dryRun, only computed.
+func (h *GreenAPIHandler) setSettings(c *gin.Context) {
+	var req service.SetSettingsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.service.core.SetSettings(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *GreenAPIHandler) getState(c *gin.Context) {
 	var req service.CredentialsRequest
 	if !bindJSON(c, &req) {
@@ -64,11 +108,39 @@ func (h *GreenAPIHandler) getState(c *gin.Context) {
 }
 
 func (h *GreenAPIHandler) sendMessage(c *gin.Context) {
+	async, ok := asyncRequested(c)
+	if !ok {
+		return
+	}
 	var req service.SendMessageRequest
 	if !bindJSON(c, &req) {
 		return
 	}
 
+	if req.Spec != nil {
+		if async {
+			writeAPIError(c, asyncScheduleConflict())
+			return
+		}
+		sched, err := h.service.core.ScheduleSendMessage(c.Request.Context(), jobOwner(c), req)
+		if err != nil {
+			writeAPIError(c, err)
+			return
+		}
+		writeScheduleCreated(c, sched)
+		return
+	}
+
+	if async {
+		job, err := h.service.core.EnqueueSendMessage(c.Request.Context(), jobOwner(c), req)
+		if err != nil {
+			writeAPIError(c, err)
+			return
+		}
+		writeJobAccepted(c, job)
+		return
+	}
+
 	resp, err := h.service.core.SendMessage(c.Request.Context(), req)
 	if err != nil {
 		writeAPIError(c, err)
@@ -83,6 +155,16 @@ func (h *GreenAPIHandler) sendFileByURL(c *gin.Context) {
 		return
 	}
 
+	if req.Spec != nil {
+		sched, err := h.service.core.ScheduleSendFileByURL(c.Request.Context(), jobOwner(c), req)
+		if err != nil {
+			writeAPIError(c, err)
+			return
+		}
+		writeScheduleCreated(c, sched)
+		return
+	}
+
 	resp, err := h.service.core.SendFileByURL(c.Request.Context(), req)
 	if err != nil {
 		writeAPIError(c, err)
@@ -116,5 +198,10 @@ func writeAPIError(c *gin.Context, err *model.APIError) {
 	if status == 0 {
 		status = http.StatusInternalServerError
 	}
+	middleware.SetAPIError(c, err)
+	if c.GetBool(envelopeKey) {
+		c.JSON(status, model.Envelope{Error: err, RequestID: middleware.GetRequestID(c)})
+		return
+	}
 	c.JSON(status, model.ErrorResponse{Error: *err})
 }
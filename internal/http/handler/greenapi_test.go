@@ -2,29 +2,43 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 
+	"green-api/internal/config"
 	"green-api/internal/greenapi"
+	"green-api/internal/idempotency"
+	"green-api/internal/middleware"
 	"green-api/internal/service"
 )
 
-type mockClient struct{}
+type mockClient struct {
+	sendMessageCalls int32
+}
 
 func (m *mockClient) GetSettings(context.Context, string, string) (greenapi.Response, error) {
 	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"wid":"79990000000"}`), ContentType: "application/json"}, nil
 }
 
+func (m *mockClient) SetSettings(context.Context, string, string, greenapi.InstanceSettings) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"saveSettings":true}`), ContentType: "application/json"}, nil
+}
+
 func (m *mockClient) GetStateInstance(context.Context, string, string) (greenapi.Response, error) {
-	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"state":"authorized"}`), ContentType: "application/json"}, nil
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"stateInstance":"authorized"}`), ContentType: "application/json"}, nil
 }
 
-func (m *mockClient) SendMessage(context.Context, string, string, string, string) (greenapi.Response, error) {
+func (m *mockClient) SendMessage(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+	atomic.AddInt32(&m.sendMessageCalls, 1)
 	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"1"}`), ContentType: "application/json"}, nil
 }
 
@@ -32,12 +46,61 @@ func (m *mockClient) SendFileByURL(context.Context, string, string, string, stri
 	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"2"}`), ContentType: "application/json"}, nil
 }
 
+// SendFileByUpload echoes the upload so tests can check what was streamed.
+func (m *mockClient) SendFileByUpload(_ context.Context, _, _ string, params greenapi.UploadParams, file io.Reader) (greenapi.Response, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return greenapi.Response{}, err
+	}
+	body, _ := json.Marshal(map[string]any{"params": params, "size": len(data)})
+	return greenapi.Response{StatusCode: http.StatusOK, Body: body, ContentType: "application/json"}, nil
+}
+
+func (m *mockClient) Reboot(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"isReboot":true}`), ContentType: "application/json"}, nil
+}
+
+func (m *mockClient) Logout(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"isLogout":true}`), ContentType: "application/json"}, nil
+}
+
+// QR answers with a 1x1 PNG.
+func (m *mockClient) QR(context.Context, string, string) (greenapi.Response, error) {
+	body := `{"type":"qrCode","message":"` + testPNG + `"}`
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(body), ContentType: "application/json"}, nil
+}
+
+func (m *mockClient) GetAuthorizationCode(context.Context, string, string, int64) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"status":true,"code":"WZGRHKQ9"}`), ContentType: "application/json"}, nil
+}
+
+func (m *mockClient) CheckWhatsapp(context.Context, string, string, int64) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"existsWhatsapp":true}`), ContentType: "application/json"}, nil
+}
+
+func (m *mockClient) GetContacts(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`[{"id":"79990000000@c.us","name":"Ivan","contactName":"","type":"user"}]`), ContentType: "application/json"}, nil
+}
+
+// GetContactInfo echoes chatID so tests can check how it was normalized.
+func (m *mockClient) GetContactInfo(_ context.Context, _, _, chatID string) (greenapi.Response, error) {
+	body, _ := json.Marshal(map[string]string{"chatId": chatID, "name": "Ivan"})
+	return greenapi.Response{StatusCode: http.StatusOK, Body: body, ContentType: "application/json"}, nil
+}
+
 func setupHandlerRouter() *gin.Engine {
+	return setupHandlerRouterWithClient(&mockClient{})
+}
+
+func setupHandlerRouterWithClient(client *mockClient) *gin.Engine {
+	return setupHandlerRouterWithService(service.New(client))
+}
+
+func setupHandlerRouterWithService(svc *service.Service) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	svc := service.New(&mockClient{})
-	h := NewGreenAPIHandler(svc)
-	group := r.Group("/api/v1")
+	h := NewGreenAPIHandler(svc, idempotency.NewStore(time.Hour, 100), config.UploadConfig{})
+	group := r.Group("/api/v1", middleware.APIKeyAuth(nil))
 	h.RegisterRoutes(group)
 	return r
 }
@@ -70,3 +133,59 @@ func TestSendMessage_InvalidJSON(t *testing.T) {
 	require.Equal(t, http.StatusBadRequest, resp.Code)
 	require.Contains(t, resp.Body.String(), "bad_request")
 }
+
+func TestSendMessage_IdempotencyKeyReplaysResult(t *testing.T) {
+	t.Parallel()
+
+	client := &mockClient{}
+	r := setupHandlerRouterWithClient(client)
+	body := `{"idInstance":"1101000001","apiTokenInstance":"token","chatId":"77771234567","message":"hi"}`
+
+	send := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/send-message", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "order-42")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	first := send(body)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	second := send(body)
+	require.Equal(t, http.StatusOK, second.Code)
+	require.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	require.JSONEq(t, first.Body.String(), second.Body.String())
+	require.Equal(t, int32(1), atomic.LoadInt32(&client.sendMessageCalls))
+
+	reused := send(strings.Replace(body, "hi", "bye", 1))
+	require.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	require.Contains(t, reused.Body.String(), "idempotency_key_reused")
+}
+
+func TestSetSettings_DryRun(t *testing.T) {
+	t.Parallel()
+
+	r := setupHandlerRouter()
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := send(`{"idInstance":"1101000001","apiTokenInstance":"token","dryRun":true,"settings":{"webhookUrl":"https://example.com/hook"}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"dryRun":true,"applied":false,"changes":[{"field":"webhookUrl","from":"","to":"https://example.com/hook"}]}`, resp.Body.String())
+
+	resp = send(`{"idInstance":"1101000001","apiTokenInstance":"token","settings":{"webhookUrl":"https://example.com/hook"}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"applied":true`)
+
+	resp = send(`{"idInstance":"1101000001","apiTokenInstance":"token","settings":{"incomingWebhooks":"yes"}}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "incomingWebhooks")
+}
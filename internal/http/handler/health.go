@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/health"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// RegisterRoutes adds the readiness routes. Liveness (GET /health) does not
+// depend on GREEN-API and is registered by the router itself.
+func (h *HealthHandler) RegisterRoutes(router gin.IRouter) {
+	router.GET("/ready", h.ready)
+	router.GET("/health/details", h.details)
+}
+
+func (h *HealthHandler) ready(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+	if !report.Ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "reasons": report.Reasons})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+func (h *HealthHandler) details(c *gin.Context) {
+	c.JSON(http.StatusOK, h.checker.Check(c.Request.Context()))
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/health"
+)
+
+type staticUpstream greenapi.Health
+
+func (s staticUpstream) Health() greenapi.Health {
+	return greenapi.Health(s)
+}
+
+func setupHealthRouter(upstream staticUpstream) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewHealthHandler(health.NewChecker(upstream)).RegisterRoutes(r)
+	return r
+}
+
+func TestReady_OK(t *testing.T) {
+	t.Parallel()
+
+	r := setupHealthRouter(staticUpstream{Breakers: []greenapi.BreakerStatus{{Name: "green-api:1", State: "closed"}}})
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"status":"ready"}`, resp.Body.String())
+}
+
+func TestReady_ServiceUnavailableWhenBreakersOpen(t *testing.T) {
+	t.Parallel()
+
+	r := setupHealthRouter(staticUpstream{Breakers: []greenapi.BreakerStatus{{Name: "green-api:1", State: "open"}}})
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	require.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	require.JSONEq(t, `{"status":"not_ready","reasons":["all circuit breakers are open"]}`, resp.Body.String())
+}
+
+func TestHealthDetails_ReportsBreakers(t *testing.T) {
+	t.Parallel()
+
+	r := setupHealthRouter(staticUpstream{Breakers: []greenapi.BreakerStatus{{Name: "green-api:1", State: "open"}}})
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	var report health.Report
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	require.False(t, report.Ready)
+	require.Equal(t, []greenapi.BreakerStatus{{Name: "green-api:1", State: "open"}}, report.Upstream.Breakers)
+}
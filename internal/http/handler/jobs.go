@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/middleware"
+	"green-api/internal/model"
+	"green-api/internal/queue"
+)
+
+type jobResponse struct {
+	JobID         string          `json:"jobId"`
+	Status        queue.Status    `json:"status"`
+	Attempts      int             `json:"attempts"`
+	IDMessage     string          `json:"idMessage,omitempty"`
+	Error         *model.APIError `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+}
+
+// asyncRequested reports whether ?async=true was passed. An unparsable value
+// is answered with 400 and false is returned.
+func asyncRequested(c *gin.Context) (async bool, ok bool) {
+	raw := c.Query("async")
+	if raw == "" {
+		return false, true
+	}
+	async, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    "async must be true or false",
+		})
+		return false, false
+	}
+	return async, true
+}
+
+func (h *GreenAPIHandler) getJob(c *gin.Context) {
+	job, err := h.service.core.GetJob(c.Request.Context(), jobOwner(c), c.Param("id"))
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toJobResponse(job))
+}
+
+func writeJobAccepted(c *gin.Context, job queue.Job) {
+	c.Header("Location", "/api/v1/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, toJobResponse(job))
+}
+
+func jobOwner(c *gin.Context) string {
+	key, _ := middleware.GetAPIKey(c)
+	return key.ID
+}
+
+func toJobResponse(job queue.Job) jobResponse {
+	resp := jobResponse{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Attempts:  job.Attempts,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if job.Status == queue.StatusQueued {
+		next := job.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	if len(job.Result) > 0 {
+		var result struct {
+			IDMessage string `json:"idMessage"`
+		}
+		if json.Unmarshal(job.Result, &result) == nil {
+			resp.IDMessage = result.IDMessage
+		}
+	}
+	return resp
+}
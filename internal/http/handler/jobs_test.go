@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/instance"
+	"green-api/internal/queue"
+	"green-api/internal/service"
+)
+
+func TestSendMessage_AsyncReturnsJob(t *testing.T) {
+	t.Parallel()
+
+	q, err := queue.Open(filepath.Join(t.TempDir(), "queue.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { q.Close() })
+	registry, err := instance.NewRegistry([]instance.Instance{
+		{Name: "sales", Credentials: instance.Credentials{IDInstance: "1101000001", APITokenInstance: "token"}},
+	})
+	require.NoError(t, err)
+
+	client := &mockClient{}
+	svc := service.New(client, service.WithInstanceResolver(registry), service.WithQueue(q))
+	r := setupHandlerRouterWithService(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/send-message?async=true", strings.NewReader(`{"instance":"sales","chatId":"77771234567","message":"hi"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusAccepted, resp.Code)
+	require.Equal(t, int32(0), atomic.LoadInt32(&client.sendMessageCalls))
+
+	var accepted jobResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &accepted))
+	require.Equal(t, queue.StatusQueued, accepted.Status)
+	require.Equal(t, "/api/v1/jobs/"+accepted.JobID, resp.Header().Get("Location"))
+
+	job, err := q.Get(accepted.JobID)
+	require.NoError(t, err)
+	result, apiErr := svc.ProcessJob(context.Background(), job)
+	require.Nil(t, apiErr)
+	require.JSONEq(t, `{"idMessage":"1"}`, string(result))
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+accepted.JobID, nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"status":"queued"`)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/missing", nil))
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestSendMessage_InvalidAsyncFlag(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/send-message?async=maybe", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	setupHandlerRouter().ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestToJobResponse_ExtractsIDMessage(t *testing.T) {
+	t.Parallel()
+
+	resp := toJobResponse(queue.Job{ID: "job-1", Status: queue.StatusSucceeded, Result: json.RawMessage(`{"idMessage":"BAE5"}`)})
+	require.Equal(t, "BAE5", resp.IDMessage)
+	require.Nil(t, resp.NextAttemptAt)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/auth"
+	"green-api/internal/greenapi"
+	"green-api/internal/middleware"
+	"green-api/internal/model"
+	"green-api/internal/service"
+)
+
+// registerLifecycleRoutes adds the endpoints operators use to relink a phone.
+func (h *GreenAPIHandler) registerLifecycleRoutes(router gin.IRouter) {
+	manage := middleware.RequireScope(auth.ScopeInstanceManage)
+	router.POST("/reboot", manage, h.reboot)
+	router.POST("/logout", manage, h.logout)
+	router.POST("/qr", manage, h.qr)
+	router.POST("/authorization-code", manage, h.authorizationCode)
+	router.POST("/wait-authorized", manage, h.waitAuthorized)
+}
+
+// waitAuthorizedSlack covers the last getStateInstance call of a wait and
+// writing the answer.
+const waitAuthorizedSlack = 30 * time.Second
+
+func (h *GreenAPIHandler) reboot(c *gin.Context) {
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.core.Reboot(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+}
+
+func (h *GreenAPIHandler) logout(c *gin.Context) {
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.core.Logout(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+}
+
+// qr answers with {"type", "message"} or, with ?format=png, with the image
+// itself; 409 qr_unavailable when there is no image (already logged in).
+func (h *GreenAPIHandler) qr(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "png" {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    "format must be json or png",
+		})
+		return
+	}
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	code, err := h.service.core.QR(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, code)
+		return
+	}
+	if code.Type != greenapi.QRCode {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusConflict,
+			Code:       "qr_unavailable",
+			Message:    "no QR code was issued",
+			Details:    code,
+		})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", code.PNG)
+}
+
+func (h *GreenAPIHandler) authorizationCode(c *gin.Context) {
+	var req service.AuthorizationCodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.service.core.GetAuthorizationCode(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+}
+
+// waitAuthorized may hold the request for up to two minutes, longer than the
+// server write timeout, so it extends the deadlines like sendFile does.
+func (h *GreenAPIHandler) waitAuthorized(c *gin.Context) {
+	var req service.WaitAuthorizedRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	extendDeadlines(c, req.Wait()+waitAuthorizedSlack)
+
+	state, err := h.service.core.WaitAuthorized(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/service"
+)
+
+// testPNG is a base64 1x1 PNG.
+const testPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAACklEQVR4nGNgAAAAAgABSK+kcQAAAABJRU5ErkJggg=="
+
+func postLifecycle(t *testing.T, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	setupHandlerRouter().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestLifecycle_ProxiesGreenAPI(t *testing.T) {
+	t.Parallel()
+
+	creds := `{"idInstance":"1101000001","apiTokenInstance":"token"}`
+	cases := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{name: "reboot", path: "/api/v1/reboot", body: creds, want: `{"isReboot":true}`},
+		{name: "logout", path: "/api/v1/logout", body: creds, want: `{"isLogout":true}`},
+		{
+			name: "authorization code",
+			path: "/api/v1/authorization-code",
+			body: `{"idInstance":"1101000001","apiTokenInstance":"token","phoneNumber":"+79991234567"}`,
+			want: `{"status":true,"code":"WZGRHKQ9"}`,
+		},
+		{name: "wait authorized", path: "/api/v1/wait-authorized", body: creds, want: `{"stateInstance":"authorized"}`},
+		{name: "qr", path: "/api/v1/qr", body: creds, want: `{"type":"qrCode","message":"` + testPNG + `"}`},
+	}
+	for _, tc := range cases {
+		resp := postLifecycle(t, tc.path, tc.body)
+		require.Equal(t, http.StatusOK, resp.Code, tc.name)
+		require.JSONEq(t, tc.want, resp.Body.String(), tc.name)
+	}
+}
+
+func TestLifecycle_QRAsPNG(t *testing.T) {
+	t.Parallel()
+
+	resp := postLifecycle(t, "/api/v1/qr?format=png", `{"idInstance":"1101000001","apiTokenInstance":"token"}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+	require.True(t, bytes.HasPrefix(resp.Body.Bytes(), []byte("\x89PNG")))
+
+	resp = postLifecycle(t, "/api/v1/qr?format=svg", `{"idInstance":"1101000001","apiTokenInstance":"token"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestLifecycle_AuthorizationCodeNeedsPhoneNumber(t *testing.T) {
+	t.Parallel()
+
+	resp := postLifecycle(t, "/api/v1/authorization-code", `{"idInstance":"1101000001","apiTokenInstance":"token","phoneNumber":"120363043968066561@g.us"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "phoneNumber")
+}
+
+// lateAuthClient reports notAuthorized until authorizedAt.
+type lateAuthClient struct {
+	mockClient
+	authorizedAt time.Time
+}
+
+func (m *lateAuthClient) GetStateInstance(context.Context, string, string) (greenapi.Response, error) {
+	state := "notAuthorized"
+	if !time.Now().Before(m.authorizedAt) {
+		state = "authorized"
+	}
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"stateInstance":"` + state + `"}`), ContentType: "application/json"}, nil
+}
+
+func TestLifecycle_WaitAuthorizedOutlivesWriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := &lateAuthClient{authorizedAt: time.Now().Add(300 * time.Millisecond)}
+	svc := service.New(client, service.WithStatePollInterval(20*time.Millisecond))
+	server := httptest.NewUnstartedServer(setupHandlerRouterWithService(svc))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+"/api/v1/wait-authorized", "application/json",
+		strings.NewReader(`{"idInstance":"1101000001","apiTokenInstance":"token","timeoutSeconds":1}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.JSONEq(t, `{"stateInstance":"authorized"}`, string(body))
+}
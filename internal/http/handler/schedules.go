@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/model"
+	"green-api/internal/scheduler"
+)
+
+type scheduleResponse struct {
+	ScheduleID string           `json:"scheduleId"`
+	Kind       string           `json:"kind"`
+	Request    json.RawMessage  `json:"request"`
+	SendAt     *time.Time       `json:"sendAt,omitempty"`
+	Cron       string           `json:"cron,omitempty"`
+	Timezone   string           `json:"timezone,omitempty"`
+	Status     scheduler.Status `json:"status"`
+	NextRunAt  *time.Time       `json:"nextRunAt,omitempty"`
+	Runs       int              `json:"runs"`
+	LastRunAt  *time.Time       `json:"lastRunAt,omitempty"`
+	LastResult json.RawMessage  `json:"lastResult,omitempty"`
+	LastError  *model.APIError  `json:"lastError,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	UpdatedAt  time.Time        `json:"updatedAt"`
+}
+
+func (h *GreenAPIHandler) listSchedules(c *gin.Context) {
+	schedules, err := h.service.core.ListSchedules(c.Request.Context(), jobOwner(c))
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+
+	items := make([]scheduleResponse, 0, len(schedules))
+	for _, sched := range schedules {
+		items = append(items, toScheduleResponse(sched))
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": items})
+}
+
+func (h *GreenAPIHandler) getSchedule(c *gin.Context) {
+	sched, err := h.service.core.GetSchedule(c.Request.Context(), jobOwner(c), c.Param("id"))
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toScheduleResponse(sched))
+}
+
+func (h *GreenAPIHandler) reschedule(c *gin.Context) {
+	var spec scheduler.Spec
+	if !bindJSON(c, &spec) {
+		return
+	}
+
+	sched, err := h.service.core.Reschedule(c.Request.Context(), jobOwner(c), c.Param("id"), spec)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toScheduleResponse(sched))
+}
+
+func (h *GreenAPIHandler) cancelSchedule(c *gin.Context) {
+	sched, err := h.service.core.CancelSchedule(c.Request.Context(), jobOwner(c), c.Param("id"))
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toScheduleResponse(sched))
+}
+
+func writeScheduleCreated(c *gin.Context, sched scheduler.Schedule) {
+	c.Header("Location", "/api/v1/schedules/"+sched.ID)
+	c.JSON(http.StatusCreated, toScheduleResponse(sched))
+}
+
+func asyncScheduleConflict() *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "validation_error",
+		Message:    "invalid request payload",
+		Details:    map[string]string{"field": "async", "message": "scheduled sends cannot be combined with async=true"},
+	}
+}
+
+func toScheduleResponse(sched scheduler.Schedule) scheduleResponse {
+	return scheduleResponse{
+		ScheduleID: sched.ID,
+		Kind:       sched.Kind,
+		Request:    sched.Payload,
+		SendAt:     sched.Spec.SendAt,
+		Cron:       sched.Spec.Cron,
+		Timezone:   sched.Spec.Timezone,
+		Status:     sched.Status,
+		NextRunAt:  sched.NextRunAt,
+		Runs:       sched.Runs,
+		LastRunAt:  sched.LastRunAt,
+		LastResult: sched.LastResult,
+		LastError:  sched.LastError,
+		CreatedAt:  sched.CreatedAt,
+		UpdatedAt:  sched.UpdatedAt,
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/instance"
+	"green-api/internal/scheduler"
+	"green-api/internal/service"
+)
+
+func TestSendMessage_WithSendAtCreatesSchedule(t *testing.T) {
+	t.Parallel()
+
+	store, err := scheduler.Open(filepath.Join(t.TempDir(), "schedules.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	registry, err := instance.NewRegistry([]instance.Instance{
+		{Name: "sales", Credentials: instance.Credentials{IDInstance: "1101000001", APITokenInstance: "token"}},
+	})
+	require.NoError(t, err)
+
+	client := &mockClient{}
+	r := setupHandlerRouterWithService(service.New(client, service.WithInstanceResolver(registry), service.WithScheduler(store)))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	sendAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	resp := do(http.MethodPost, "/api/v1/send-message", `{"instance":"sales","chatId":"77771234567","message":"hi","sendAt":"`+sendAt+`"}`)
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	require.Equal(t, int32(0), atomic.LoadInt32(&client.sendMessageCalls))
+
+	var created scheduleResponse
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	require.Equal(t, scheduler.StatusActive, created.Status)
+
+	resp = do(http.MethodGet, "/api/v1/schedules", "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), created.ScheduleID)
+
+	resp = do(http.MethodPatch, "/api/v1/schedules/"+created.ScheduleID, `{"cron":"30 9 * * 1-5","timezone":"Europe/Moscow"}`)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"cron":"30 9 * * 1-5"`)
+
+	resp = do(http.MethodDelete, "/api/v1/schedules/"+created.ScheduleID, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"status":"cancelled"`)
+
+	resp = do(http.MethodPost, "/api/v1/send-message?async=true", `{"instance":"sales","chatId":"77771234567","message":"hi","sendAt":"`+sendAt+`"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/model"
+	"green-api/internal/service"
+)
+
+const (
+	// maxUploadFormBytes bounds everything in a send-file form but the file.
+	maxUploadFormBytes = 1 << 20
+	maxFormFieldBytes  = 64 << 10
+	maxFormFields      = 16
+)
+
+// sendFile reads the multipart form as a stream. Text fields must come
+// before the "file" part, which is handed to the service while it is still
+// arriving; anything after it is ignored.
+func (h *GreenAPIHandler) sendFile(c *gin.Context) {
+	timeout := h.uploads.Timeout()
+	extendDeadlines(c, timeout)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	if c.Request.ContentLength > h.uploads.MaxBytes()+maxUploadFormBytes {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Code:       "file_too_large",
+			Message:    fmt.Sprintf("file must not exceed %d bytes", h.uploads.MaxBytes()),
+		})
+		return
+	}
+
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		writeAPIError(c, badForm("expected a multipart/form-data body"))
+		return
+	}
+
+	var req service.SendFileByUploadRequest
+	for fields := 0; ; fields++ {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeAPIError(c, badForm(`the "file" part is missing`))
+			return
+		}
+		if err != nil {
+			writeAPIError(c, badForm("cannot read multipart body"))
+			return
+		}
+
+		if part.FormName() == "file" {
+			if req.FileName == "" {
+				req.FileName = part.FileName()
+			}
+			resp, apiErr := h.service.core.SendFileByUpload(ctx, req, part)
+			if apiErr != nil {
+				writeAPIError(c, apiErr)
+				return
+			}
+			proxyResponse(c, resp.StatusCode, resp.Body, resp.ContentType)
+			return
+		}
+
+		if fields == maxFormFields {
+			writeAPIError(c, badForm(fmt.Sprintf("at most %d fields may precede the file", maxFormFields)))
+			return
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes+1))
+		if err != nil {
+			writeAPIError(c, badForm("cannot read multipart body"))
+			return
+		}
+		if len(value) > maxFormFieldBytes {
+			writeAPIError(c, badForm(fmt.Sprintf("field %q is too long", part.FormName())))
+			return
+		}
+		setUploadField(&req, part.FormName(), string(value))
+	}
+}
+
+func setUploadField(req *service.SendFileByUploadRequest, name, value string) {
+	switch name {
+	case "instance":
+		req.Instance = value
+	case "idInstance":
+		req.IDInstance = value
+	case "apiTokenInstance":
+		req.APITokenInstance = value
+	case "chatId":
+		req.ChatID = value
+	case "fileName":
+		req.FileName = value
+	case "caption":
+		req.Caption = value
+	case "quotedMessageId":
+		req.QuotedMessageID = value
+	}
+}
+
+// extendDeadlines lets an upload outlive the server read and write timeouts,
+// which are sized for JSON requests.
+func extendDeadlines(c *gin.Context, timeout time.Duration) {
+	controller := http.NewResponseController(c.Writer)
+	deadline := time.Now().Add(timeout)
+	_ = controller.SetReadDeadline(deadline)
+	_ = controller.SetWriteDeadline(deadline)
+}
+
+func badForm(message string) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "bad_request",
+		Message:    message,
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var pngFile = append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 2048)...)
+
+type formPart struct {
+	name, fileName string
+	value          []byte
+}
+
+func multipartRequest(t *testing.T, parts ...formPart) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, part := range parts {
+		if part.fileName == "" {
+			require.NoError(t, form.WriteField(part.name, string(part.value)))
+			continue
+		}
+		w, err := form.CreateFormFile(part.name, part.fileName)
+		require.NoError(t, err)
+		_, err = w.Write(part.value)
+		require.NoError(t, err)
+	}
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/send-file", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestSendFile_StreamsFileWithFields(t *testing.T) {
+	t.Parallel()
+
+	r := setupHandlerRouter()
+	req := multipartRequest(t,
+		formPart{name: "idInstance", value: []byte("1101000001")},
+		formPart{name: "apiTokenInstance", value: []byte("token")},
+		formPart{name: "chatId", value: []byte("79991234567")},
+		formPart{name: "caption", value: []byte("Look")},
+		formPart{name: "file", fileName: "horse.png", value: pngFile},
+	)
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.JSONEq(t, `{
+		"params": {"ChatID":"79991234567@c.us","FileName":"horse.png","Caption":"Look","QuotedMessageID":"","ContentType":"image/png"},
+		"size": 2064
+	}`, resp.Body.String())
+}
+
+func TestSendFile_Errors(t *testing.T) {
+	t.Parallel()
+
+	credentials := []formPart{
+		{name: "idInstance", value: []byte("1101000001")},
+		{name: "apiTokenInstance", value: []byte("token")},
+		{name: "chatId", value: []byte("79991234567")},
+	}
+	file := formPart{name: "file", fileName: "horse.png", value: pngFile}
+
+	cases := []struct {
+		name   string
+		parts  []formPart
+		status int
+		code   string
+	}{
+		{name: "missing file", parts: credentials, status: http.StatusBadRequest, code: "bad_request"},
+		{name: "fields after file", parts: append([]formPart{file}, credentials...), status: http.StatusBadRequest, code: "validation_error"},
+		{name: "field too long", parts: append([]formPart{{name: "caption", value: make([]byte, maxFormFieldBytes+1)}}, file), status: http.StatusBadRequest, code: "bad_request"},
+	}
+
+	r := setupHandlerRouter()
+	for _, tc := range cases {
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, multipartRequest(t, tc.parts...))
+		require.Equal(t, tc.status, resp.Code, tc.name)
+		require.Contains(t, resp.Body.String(), `"code":"`+tc.code+`"`, tc.name)
+	}
+}
+
+func TestSendFile_RejectsJSONBody(t *testing.T) {
+	t.Parallel()
+
+	r := setupHandlerRouter()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/send-file", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestSendFile_RejectsOversizedContentLength(t *testing.T) {
+	t.Parallel()
+
+	r := setupHandlerRouter()
+	req := multipartRequest(t, formPart{name: "file", fileName: "horse.png", value: pngFile})
+	req.ContentLength = 200 << 20
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	require.Contains(t, resp.Body.String(), `"code":"file_too_large"`)
+}
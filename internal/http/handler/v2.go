@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/auth"
+	"green-api/internal/middleware"
+	"green-api/internal/model"
+	"green-api/internal/service"
+)
+
+// envelopeKey marks requests whose responses, errors included, are wrapped in
+// a model.Envelope.
+const envelopeKey = "envelope"
+
+// RegisterV2Routes serves the typed API: GREEN-API responses are decoded into
+// the greenapi models instead of being proxied. Jobs and schedules stay on
+// /api/v1.
+func (h *GreenAPIHandler) RegisterV2Routes(router gin.IRouter) {
+	router = router.Group("", func(c *gin.Context) { c.Set(envelopeKey, true) })
+
+	router.POST("/settings", middleware.RequireScope(auth.ScopeSettingsRead), h.getSettingsV2)
+	router.POST("/state", middleware.RequireScope(auth.ScopeStateRead), h.getStateV2)
+	router.POST("/send-message", middleware.RequireScope(auth.ScopeMessageSend), middleware.Idempotency(h.idempotency, "sendMessage"), h.sendMessageV2)
+	router.POST("/send-file-by-url", middleware.RequireScope(auth.ScopeFileSend), middleware.Idempotency(h.idempotency, "sendFileByUrl"), h.sendFileByURLV2)
+}
+
+func (h *GreenAPIHandler) getSettingsV2(c *gin.Context) {
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	settings, err := h.service.core.GetSettingsTyped(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	writeData(c, http.StatusOK, settings)
+}
+
+func (h *GreenAPIHandler) getStateV2(c *gin.Context) {
+	var req service.CredentialsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	state, err := h.service.core.GetStateTyped(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	writeData(c, http.StatusOK, state)
+}
+
+func (h *GreenAPIHandler) sendMessageV2(c *gin.Context) {
+	var req service.SendMessageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Spec != nil {
+		writeAPIError(c, scheduleOnV1())
+		return
+	}
+
+	result, err := h.service.core.SendMessageTyped(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	writeData(c, http.StatusOK, result)
+}
+
+func (h *GreenAPIHandler) sendFileByURLV2(c *gin.Context) {
+	var req service.SendFileByURLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Spec != nil {
+		writeAPIError(c, scheduleOnV1())
+		return
+	}
+
+	result, err := h.service.core.SendFileByURLTyped(c.Request.Context(), req)
+	if err != nil {
+		writeAPIError(c, err)
+		return
+	}
+	writeData(c, http.StatusOK, result)
+}
+
+func writeData(c *gin.Context, status int, data any) {
+	c.JSON(status, model.Envelope{Data: data, RequestID: middleware.GetRequestID(c)})
+}
+
+func scheduleOnV1() *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "bad_request",
+		Message:    "sendAt and cron are only supported by /api/v1",
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/config"
+	"green-api/internal/idempotency"
+	"green-api/internal/middleware"
+	"green-api/internal/service"
+)
+
+func setupV2Router() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestID())
+	h := NewGreenAPIHandler(service.New(&mockClient{}), idempotency.NewStore(time.Hour, 100), config.UploadConfig{})
+	h.RegisterV2Routes(r.Group("/api/v2", middleware.APIKeyAuth(nil)))
+	return r
+}
+
+func postV2(r *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "req-1")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestV2_WrapsTypedResponses(t *testing.T) {
+	t.Parallel()
+
+	r := setupV2Router()
+	creds := `{"idInstance":"1101000001","apiTokenInstance":"token"`
+
+	resp := postV2(r, "/api/v2/state", creds+`}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"data":{"stateInstance":"authorized"},"requestId":"req-1"}`, resp.Body.String())
+
+	resp = postV2(r, "/api/v2/send-message", creds+`,"chatId":"77771234567","message":"hi"}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.JSONEq(t, `{"data":{"idMessage":"1"},"requestId":"req-1"}`, resp.Body.String())
+
+	resp = postV2(r, "/api/v2/settings", creds+`}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `"wid":"79990000000"`)
+	require.Contains(t, resp.Body.String(), `"outgoingWebhook":false`)
+}
+
+func TestV2_WrapsErrors(t *testing.T) {
+	t.Parallel()
+
+	r := setupV2Router()
+
+	resp := postV2(r, "/api/v2/send-message", `{"idInstance":`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), `"requestId":"req-1"`)
+	require.Contains(t, resp.Body.String(), `"code":"bad_request"`)
+	require.NotContains(t, resp.Body.String(), `"data"`)
+
+	resp = postV2(r, "/api/v2/send-file-by-url", `{"idInstance":"1101000001","apiTokenInstance":"token","chatId":"77771234567","urlFile":"https://example.com/a.png","sendAt":"2030-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "/api/v1")
+}
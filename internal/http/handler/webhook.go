@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"green-api/internal/model"
+	"green-api/internal/notification"
+)
+
+const maxWebhookBodyBytes = 1 << 20
+
+type WebhookHandler struct {
+	dispatcher *notification.Dispatcher
+	tokens     map[string]string
+	logger     *zap.Logger
+}
+
+// NewWebhookHandler accepts notifications for the instances in tokens
+// (idInstance -> webhookUrlToken).
+func NewWebhookHandler(dispatcher *notification.Dispatcher, tokens map[string]string, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{dispatcher: dispatcher, tokens: tokens, logger: logger}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router gin.IRouter) {
+	router.POST("/webhooks/:idInstance", h.receive)
+}
+
+func (h *WebhookHandler) receive(c *gin.Context) {
+	idInstance := c.Param("idInstance")
+	expectedToken, ok := h.tokens[idInstance]
+	if !ok {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusNotFound,
+			Code:       "unknown_instance",
+			Message:    "webhooks are not configured for this instance",
+		})
+		return
+	}
+
+	if !validWebhookToken(c.GetHeader("Authorization"), expectedToken) {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusUnauthorized,
+			Code:       "unauthorized",
+			Message:    "invalid webhook token",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    "cannot read webhook payload",
+			Details:    err.Error(),
+		})
+		return
+	}
+
+	event, err := notification.Decode(body)
+	if err != nil {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    "invalid webhook payload",
+			Details:    err.Error(),
+		})
+		return
+	}
+	if event.Instance().ID() != idInstance {
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusBadRequest,
+			Code:       "bad_request",
+			Message:    "instanceData.idInstance does not match the webhook url",
+		})
+		return
+	}
+
+	if err := h.dispatcher.Dispatch(c.Request.Context(), event); err != nil {
+		h.logger.Error("webhook_dispatch_failed",
+			zap.String("id_instance", idInstance),
+			zap.String("type", string(event.Type())),
+			zap.Error(err),
+		)
+		writeAPIError(c, &model.APIError{
+			StatusCode: http.StatusInternalServerError,
+			Code:       "webhook_handler_error",
+			Message:    "notification was not processed",
+		})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func validWebhookToken(header, expected string) bool {
+	token := strings.TrimSpace(header)
+	if len(token) > len("Bearer ") && strings.EqualFold(token[:len("Bearer ")], "Bearer ") {
+		token = strings.TrimSpace(token[len("Bearer "):])
+	}
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/notification"
+)
+
+const stateChangedBody = `{"typeWebhook":"stateInstanceChanged","instanceData":{"idInstance":1101000001},"timestamp":1,"stateInstance":"authorized"}`
+
+func setupWebhookRouter(dispatcher *notification.Dispatcher) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewWebhookHandler(dispatcher, map[string]string{"1101000001": "hook-secret"}, zap.NewNop())
+	h.RegisterRoutes(r.Group("/api/v1"))
+	return r
+}
+
+func postWebhook(r *gin.Engine, path, authorization, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestWebhook_DispatchesTypedEvent(t *testing.T) {
+	t.Parallel()
+
+	var received *notification.StateChanged
+	dispatcher := notification.NewDispatcher()
+	dispatcher.On(notification.TypeStateInstanceChanged, notification.HandlerFunc(func(_ context.Context, event notification.Event) error {
+		received = event.(*notification.StateChanged)
+		return nil
+	}))
+
+	resp := postWebhook(setupWebhookRouter(dispatcher), "/api/v1/webhooks/1101000001", "Bearer hook-secret", stateChangedBody)
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.NotNil(t, received)
+	require.Equal(t, "authorized", received.StateInstance)
+}
+
+func TestWebhook_RejectsInvalidToken(t *testing.T) {
+	t.Parallel()
+
+	r := setupWebhookRouter(notification.NewDispatcher())
+
+	resp := postWebhook(r, "/api/v1/webhooks/1101000001", "Bearer wrong", stateChangedBody)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(r, "/api/v1/webhooks/1101000001", "", stateChangedBody)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = postWebhook(r, "/api/v1/webhooks/999", "Bearer hook-secret", stateChangedBody)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestWebhook_HandlerFailureReturns500(t *testing.T) {
+	t.Parallel()
+
+	dispatcher := notification.NewDispatcher()
+	dispatcher.OnAny(notification.HandlerFunc(func(context.Context, notification.Event) error {
+		return errors.New("storage down")
+	}))
+
+	resp := postWebhook(setupWebhookRouter(dispatcher), "/api/v1/webhooks/1101000001", "Bearer hook-secret", stateChangedBody)
+	require.Equal(t, http.StatusInternalServerError, resp.Code)
+	require.Contains(t, resp.Body.String(), "webhook_handler_error")
+	require.NotContains(t, resp.Body.String(), "storage down")
+}
+
+func TestWebhook_RejectsMismatchedInstance(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Replace(stateChangedBody, "1101000001", "42", 1)
+	resp := postWebhook(setupWebhookRouter(notification.NewDispatcher()), "/api/v1/webhooks/1101000001", "Bearer hook-secret", body)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -5,28 +5,92 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"green-api/internal/auth"
 	"green-api/internal/config"
 	"green-api/internal/docs"
+	"green-api/internal/health"
 	"green-api/internal/http/handler"
+	"green-api/internal/idempotency"
+	"green-api/internal/metrics"
 	"green-api/internal/middleware"
+	"green-api/internal/notification"
 	"green-api/internal/service"
 )
 
-func New(cfg config.Config, logger *zap.Logger, service *service.Service) *gin.Engine {
+// Option customizes the engine built by New.
+type Option func(*options)
+
+type options struct {
+	dispatcher    *notification.Dispatcher
+	authenticator *auth.Authenticator
+	metrics       *metrics.Metrics
+	tracer        trace.TracerProvider
+	health        *health.Checker
+}
+
+// WithNotificationDispatcher routes incoming webhook notifications to dispatcher.
+func WithNotificationDispatcher(dispatcher *notification.Dispatcher) Option {
+	return func(o *options) {
+		o.dispatcher = dispatcher
+	}
+}
+
+// WithAuthenticator requires API keys on /api/v1 routes. Without it every
+// request is treated as auth.Anonymous.
+func WithAuthenticator(authenticator *auth.Authenticator) Option {
+	return func(o *options) {
+		o.authenticator = authenticator
+	}
+}
+
+// WithMetrics records request metrics and serves them on GET /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithTracerProvider starts a server span for every request.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = provider
+	}
+}
+
+// WithHealthChecker serves GET /ready and GET /health/details from checker.
+func WithHealthChecker(checker *health.Checker) Option {
+	return func(o *options) {
+		o.health = checker
+	}
+}
+
+func New(cfg config.Config, logger *zap.Logger, service *service.Service, opts ...Option) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	engine := gin.New()
 	engine.Use(gin.Recovery())
 	engine.Use(middleware.RequestID())
+	if o.tracer != nil {
+		engine.Use(middleware.Tracing(o.tracer))
+	}
 	engine.Use(middleware.RequestLogger(logger))
+	if o.metrics != nil {
+		engine.Use(middleware.Metrics(o.metrics))
+	}
 
 	engine.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.AllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "X-Request-Id"},
-		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-Id", middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"X-Request-Id", "Location", middleware.IdempotentReplayedHeader},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -34,6 +98,12 @@ func New(cfg config.Config, logger *zap.Logger, service *service.Service) *gin.E
 	engine.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	if o.health != nil {
+		handler.NewHealthHandler(o.health).RegisterRoutes(engine)
+	}
+	if o.metrics != nil {
+		engine.GET("/metrics", gin.WrapH(o.metrics.Handler()))
+	}
 	engine.GET("/openapi.yaml", func(c *gin.Context) {
 		c.Data(200, "application/yaml; charset=utf-8", docs.OpenAPIYAML)
 	})
@@ -45,8 +115,28 @@ func New(cfg config.Config, logger *zap.Logger, service *service.Service) *gin.E
 	})
 
 	api := engine.Group("/api/v1")
-	h := handler.NewGreenAPIHandler(service)
-	h.RegisterRoutes(api)
+	secured := api.Group("", middleware.APIKeyAuth(o.authenticator))
+
+	h := handler.NewGreenAPIHandler(service, idempotency.NewStore(cfg.Idempotency.TTL(), cfg.Idempotency.Capacity()), cfg.Upload)
+	h.RegisterRoutes(secured)
+	h.RegisterV2Routes(engine.Group("/api/v2", middleware.APIKeyAuth(o.authenticator)))
+
+	if o.authenticator != nil {
+		handler.NewAdminHandler(o.authenticator).RegisterRoutes(secured)
+	}
+
+	if cfg.Webhooks.Enabled {
+		dispatcher := o.dispatcher
+		if dispatcher == nil {
+			dispatcher = notification.NewDispatcher()
+			dispatcher.OnAny(notification.LogHandler(logger))
+		}
+		tokens := make(map[string]string, len(cfg.Webhooks.Instances))
+		for _, instance := range cfg.Webhooks.Instances {
+			tokens[instance.IDInstance] = instance.URLToken
+		}
+		handler.NewWebhookHandler(dispatcher, tokens, logger).RegisterRoutes(api)
+	}
 
 	return engine
 }
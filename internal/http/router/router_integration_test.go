@@ -5,13 +5,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.uber.org/zap"
 
 	"green-api/internal/config"
 	"green-api/internal/greenapi"
+	"green-api/internal/greenapi/fake"
+	"green-api/internal/metrics"
+	"green-api/internal/model"
 	"green-api/internal/service"
 )
 
@@ -140,3 +146,206 @@ func TestRouter_DocsEndpointsAvailable(t *testing.T) {
 	require.Equal(t, http.StatusOK, docsResp.Code)
 	require.Contains(t, docsResp.Body.String(), "SwaggerUIBundle")
 }
+
+func TestRouter_CORSPreflightAllowsRouteMethods(t *testing.T) {
+	t.Parallel()
+
+	cfg := integrationConfig("http://127.0.0.1:1")
+	logger := zap.NewNop()
+	engine := New(cfg, logger, service.New(greenapi.NewClient(cfg.GreenAPI, logger)))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "set settings", method: http.MethodPut, path: "/api/v1/settings"},
+		{name: "reschedule", method: http.MethodPatch, path: "/api/v1/schedules/1"},
+		{name: "cancel schedule", method: http.MethodDelete, path: "/api/v1/schedules/1"},
+		{name: "send message", method: http.MethodPost, path: "/api/v1/send-message"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+		req.Header.Set("Origin", "http://localhost:5000")
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+
+		require.Equal(t, http.StatusNoContent, resp.Code, tc.name)
+		allowed := strings.Split(resp.Header().Get("Access-Control-Allow-Methods"), ",")
+		require.Contains(t, allowed, tc.method, tc.name)
+	}
+}
+
+func TestRouter_UpstreamErrorDoesNotLeakToken(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	cfg := integrationConfig(upstream.URL)
+	upstream.Close()
+
+	cfg.GreenAPI.Retry.MaxRetries = 0
+	logger := zap.NewNop()
+	client := greenapi.NewClient(cfg.GreenAPI, logger)
+	svc := service.New(client)
+	engine := New(cfg, logger, svc)
+
+	for _, path := range []string{"/api/v1/settings", "/api/v1/state", "/api/v1/send-message", "/api/v1/send-file-by-url"} {
+		body, _ := json.Marshal(map[string]string{
+			"idInstance":       "1101000001",
+			"apiTokenInstance": "super-secret-token",
+			"chatId":           "77771234567",
+			"message":          "hello",
+			"urlFile":          "https://my.site.com/img/horse.png",
+		})
+
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+
+		require.Contains(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable}, resp.Code, path)
+		require.NotContains(t, resp.Body.String(), "super-secret-token", path)
+
+		var errResp model.ErrorResponse
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &errResp))
+		require.Equal(t, "upstream_error", errResp.Error.Code)
+	}
+}
+
+func TestRouter_MetricsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"stateInstance":"authorized"}`))
+	}))
+	defer upstream.Close()
+
+	cfg := integrationConfig(upstream.URL)
+	logger := zap.NewNop()
+	m := metrics.New()
+	client := greenapi.NewClient(cfg.GreenAPI, logger, greenapi.WithObserver(m))
+	svc := service.New(client)
+	engine := New(cfg, logger, svc, WithMetrics(m))
+
+	body, _ := json.Marshal(map[string]string{
+		"idInstance":       "1101000001",
+		"apiTokenInstance": "token",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/state", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	resp := httptest.NewRecorder()
+	engine.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `green_api_backend_http_requests_total{method="POST",route="/api/v1/state",status="200"} 1`)
+	require.Contains(t, resp.Body.String(), `green_api_backend_upstream_attempts_total{method="getStateInstance",outcome="success"} 1`)
+	require.Contains(t, resp.Body.String(), `green_api_backend_circuit_breaker_state{breaker="integration-breaker:1101000001"} 0`)
+	require.NotContains(t, resp.Body.String(), "token")
+}
+
+func TestRouter_TracesRequestThroughServiceAndClient(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"idMessage":"1"}`))
+	}))
+	defer upstream.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	cfg := integrationConfig(upstream.URL)
+	logger := zap.NewNop()
+	client := greenapi.NewClient(cfg.GreenAPI, logger, greenapi.WithTracerProvider(provider))
+	svc := service.New(client, service.WithTracerProvider(provider))
+	engine := New(cfg, logger, svc, WithTracerProvider(provider))
+
+	body, _ := json.Marshal(map[string]string{
+		"idInstance":       "1101000001",
+		"apiTokenInstance": "secret-token",
+		"chatId":           "77771234567",
+		"message":          "hello",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/send-message", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	engine.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		byName[span.Name()] = span
+		for _, kv := range span.Attributes() {
+			require.NotContains(t, kv.Value.Emit(), "secret-token")
+		}
+	}
+
+	server := byName["POST /api/v1/send-message"]
+	call := byName["service.SendMessage"]
+	attempt := byName["greenapi.sendMessage"]
+	require.NotNil(t, server)
+	require.NotNil(t, call)
+	require.NotNil(t, attempt)
+	require.Equal(t, server.SpanContext().SpanID(), call.Parent().SpanID())
+	require.Equal(t, call.SpanContext().SpanID(), attempt.Parent().SpanID())
+	require.Equal(t, server.SpanContext().TraceID(), attempt.SpanContext().TraceID())
+}
+
+func TestRouter_RunsAgainstFakeGreenAPI(t *testing.T) {
+	t.Parallel()
+
+	fakeAPI := fake.New()
+	fakeAPI.AddInstance("1101000001", "token")
+	upstream := httptest.NewServer(fakeAPI)
+	defer upstream.Close()
+	defer fakeAPI.Close()
+
+	cfg := integrationConfig(upstream.URL)
+	cfg.GreenAPI.Retry = config.GreenAPIRetryConfig{MaxRetries: 1, DelayMS: 10}
+	logger := zap.NewNop()
+	client := greenapi.NewClient(cfg.GreenAPI, logger)
+	engine := New(cfg, logger, service.New(client, service.WithDecodeMode(greenapi.DecodeStrict)))
+
+	post := func(path string, payload map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		engine.ServeHTTP(resp, req)
+		return resp
+	}
+	send := map[string]string{
+		"idInstance":       "1101000001",
+		"apiTokenInstance": "token",
+		"chatId":           "77771234567",
+		"message":          "hello",
+	}
+
+	resp := post("/api/v2/send-message", send)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var envelope struct {
+		Data greenapi.SendResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &envelope))
+	sent := fakeAPI.Sent("1101000001")
+	require.Len(t, sent, 1)
+	require.Equal(t, envelope.Data.IDMessage, sent[0].IDMessage)
+	require.Equal(t, "77771234567@c.us", sent[0].ChatID)
+
+	fakeAPI.Inject(fake.Fault{Method: "getSettings", Status: http.StatusInternalServerError, Times: 1})
+	resp = post("/api/v2/settings", map[string]string{"idInstance": "1101000001", "apiTokenInstance": "token"})
+	require.Equal(t, http.StatusOK, resp.Code, "the injected 500 is retried")
+	require.Equal(t, 2, fakeAPI.Calls("getSettings"))
+
+	fakeAPI.Inject(fake.Fault{Method: "sendMessage", Status: http.StatusInternalServerError})
+	resp = post("/api/v2/send-message", send)
+	require.Equal(t, http.StatusBadGateway, resp.Code)
+	require.Contains(t, resp.Body.String(), "upstream_error")
+	require.Len(t, fakeAPI.Sent("1101000001"), 1)
+}
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInProgress = errors.New("request with this idempotency key is still in progress")
+	ErrKeyReused  = errors.New("idempotency key was already used with a different payload")
+	ErrStoreFull  = errors.New("idempotency store is full; retry later")
+)
+
+// Result is a completed response kept for replay.
+type Result struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store remembers the outcome of requests by idempotency key for a TTL.
+type Store struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*entry
+	now        func() time.Time
+}
+
+type entry struct {
+	fingerprint string
+	result      *Result
+	expiresAt   time.Time
+}
+
+func NewStore(ttl time.Duration, maxEntries int) *Store {
+	return &Store{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*entry),
+		now:        time.Now,
+	}
+}
+
+// Begin reserves key for a request whose payload hashes to fingerprint.
+// A cached result is returned when the same request already completed.
+func (s *Store) Begin(key, fingerprint string) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if existing, ok := s.entries[key]; ok && now.Before(existing.expiresAt) {
+		if existing.fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		if existing.result == nil {
+			return nil, ErrInProgress
+		}
+		return existing.result, nil
+	}
+
+	if len(s.entries) >= s.maxEntries {
+		s.evict(now)
+		// Live entries are never evicted: forgetting one, in progress or
+		// completed, would let its retry send again.
+		if len(s.entries) >= s.maxEntries {
+			return nil, ErrStoreFull
+		}
+	}
+
+	s.entries[key] = &entry{fingerprint: fingerprint, expiresAt: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// Complete stores the result of a reserved key.
+func (s *Store) Complete(key string, result Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	existing.result = &result
+	existing.expiresAt = s.now().Add(s.ttl)
+}
+
+// Release forgets a reserved key so the request can be submitted again.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// evict drops expired entries.
+func (s *Store) evict(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
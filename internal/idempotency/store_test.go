@@ -0,0 +1,94 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_ReplaysCompletedResult(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(time.Hour, 10)
+	cached, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	require.Nil(t, cached)
+
+	store.Complete("key", Result{StatusCode: 200, ContentType: "application/json", Body: []byte(`{"idMessage":"1"}`)})
+
+	cached, err = store.Begin("key", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+	require.Equal(t, `{"idMessage":"1"}`, string(cached.Body))
+}
+
+func TestStore_InProgressAndReusedKey(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(time.Hour, 10)
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+
+	_, err = store.Begin("key", "fp")
+	require.ErrorIs(t, err, ErrInProgress)
+
+	_, err = store.Begin("key", "other")
+	require.ErrorIs(t, err, ErrKeyReused)
+}
+
+func TestStore_ReleaseAllowsRetry(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(time.Hour, 10)
+	_, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	store.Release("key")
+
+	cached, err := store.Begin("key", "fp")
+	require.NoError(t, err)
+	require.Nil(t, cached)
+}
+
+func TestStore_ExpiresAfterTTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	store := NewStore(time.Minute, 10)
+	store.now = func() time.Time { return now }
+
+	_, _ = store.Begin("key", "fp")
+	store.Complete("key", Result{StatusCode: 200})
+
+	now = now.Add(2 * time.Minute)
+	cached, err := store.Begin("key", "other")
+	require.NoError(t, err)
+	require.Nil(t, cached)
+}
+
+func TestStore_FullUntilEntriesExpire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	store := NewStore(time.Minute, 2)
+	store.now = func() time.Time { return now }
+
+	_, err := store.Begin("a", "fp")
+	require.NoError(t, err)
+	_, err = store.Begin("b", "fp")
+	require.NoError(t, err)
+
+	_, err = store.Begin("c", "fp")
+	require.ErrorIs(t, err, ErrStoreFull)
+
+	store.Complete("a", Result{StatusCode: 200})
+	_, err = store.Begin("c", "fp")
+	require.ErrorIs(t, err, ErrStoreFull, "a completed result is kept for its whole TTL")
+	cached, err := store.Begin("a", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, cached)
+
+	now = now.Add(2 * time.Minute)
+	_, err = store.Begin("c", "fp")
+	require.NoError(t, err)
+}
@@ -0,0 +1,107 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrNotFound = errors.New("instance not found")
+
+// Credentials identify a GREEN-API instance.
+type Credentials struct {
+	IDInstance       string
+	APITokenInstance string
+}
+
+// Instance is a named set of credentials kept on the server side.
+type Instance struct {
+	Name string
+	Credentials
+}
+
+// Summary describes an instance without its token.
+type Summary struct {
+	Name       string `json:"name"`
+	IDInstance string `json:"idInstance"`
+}
+
+// Resolver looks up instances by name so that tokens never leave the server.
+type Resolver interface {
+	Resolve(ctx context.Context, name string) (Credentials, error)
+	List(ctx context.Context) ([]Summary, error)
+}
+
+// Registry is an in-memory Resolver, typically loaded from config.
+type Registry struct {
+	instances map[string]Instance
+}
+
+func NewRegistry(instances []Instance) (*Registry, error) {
+	registry := &Registry{instances: make(map[string]Instance, len(instances))}
+	for _, inst := range instances {
+		name := strings.TrimSpace(inst.Name)
+		if name == "" {
+			return nil, fmt.Errorf("instance name is required")
+		}
+		if _, exists := registry.instances[name]; exists {
+			return nil, fmt.Errorf("duplicate instance name %q", name)
+		}
+		inst.Name = name
+		registry.instances[name] = inst
+	}
+	return registry, nil
+}
+
+func (r *Registry) Resolve(_ context.Context, name string) (Credentials, error) {
+	inst, ok := r.instances[strings.TrimSpace(name)]
+	if !ok {
+		return Credentials{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return inst.Credentials, nil
+}
+
+func (r *Registry) List(context.Context) ([]Summary, error) {
+	summaries := make([]Summary, 0, len(r.instances))
+	for _, inst := range r.instances {
+		summaries = append(summaries, Summary{Name: inst.Name, IDInstance: inst.IDInstance})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+// Chain resolves names through several resolvers; the first match wins.
+type Chain []Resolver
+
+func (c Chain) Resolve(ctx context.Context, name string) (Credentials, error) {
+	for _, resolver := range c {
+		creds, err := resolver.Resolve(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return creds, err
+	}
+	return Credentials{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+func (c Chain) List(ctx context.Context) ([]Summary, error) {
+	seen := make(map[string]bool)
+	var summaries []Summary
+	for _, resolver := range c {
+		list, err := resolver.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range list {
+			if seen[summary.Name] {
+				continue
+			}
+			seen[summary.Name] = true
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
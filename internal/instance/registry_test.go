@@ -0,0 +1,36 @@
+package instance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ResolveAndList(t *testing.T) {
+	t.Parallel()
+
+	registry, err := NewRegistry([]Instance{
+		{Name: "support", Credentials: Credentials{IDInstance: "2", APITokenInstance: "token-2"}},
+		{Name: "sales", Credentials: Credentials{IDInstance: "1", APITokenInstance: "token-1"}},
+	})
+	require.NoError(t, err)
+
+	creds, err := registry.Resolve(context.Background(), "sales")
+	require.NoError(t, err)
+	require.Equal(t, Credentials{IDInstance: "1", APITokenInstance: "token-1"}, creds)
+
+	_, err = registry.Resolve(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	list, err := registry.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []Summary{{Name: "sales", IDInstance: "1"}, {Name: "support", IDInstance: "2"}}, list)
+}
+
+func TestRegistry_RejectsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRegistry([]Instance{{Name: "sales"}, {Name: "sales"}})
+	require.ErrorContains(t, err, "duplicate")
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	resp := httptest.NewRecorder()
+	m.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	return resp.Body.String()
+}
+
+func TestMetrics_ExposesRecordedSeries(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	m.ObserveHTTP(http.MethodPost, "/api/v1/send-message", http.StatusOK, 20*time.Millisecond)
+	m.ObserveAttempt("sendMessage", "success", 10*time.Millisecond)
+	m.ObserveRetry("getSettings", "status")
+	m.ObserveBreakerState("green-api:1101000001", "open")
+
+	body := scrape(t, m)
+	require.Contains(t, body, `green_api_backend_http_requests_total{method="POST",route="/api/v1/send-message",status="200"} 1`)
+	require.Contains(t, body, `green_api_backend_http_request_duration_seconds_count{method="POST",route="/api/v1/send-message",status="200"} 1`)
+	require.Contains(t, body, `green_api_backend_upstream_attempts_total{method="sendMessage",outcome="success"} 1`)
+	require.Contains(t, body, `green_api_backend_upstream_retries_total{method="getSettings",reason="status"} 1`)
+	require.Contains(t, body, `green_api_backend_circuit_breaker_state{breaker="green-api:1101000001"} 2`)
+	require.Contains(t, body, "go_goroutines")
+}
+
+func TestMetrics_ForgetBreakerDropsSeries(t *testing.T) {
+	t.Parallel()
+
+	m := New()
+	m.ObserveBreakerState("green-api:1101000001", "half-open")
+	m.ObserveBreakerState("green-api:1101000002", "unknown")
+	require.Contains(t, scrape(t, m), `green_api_backend_circuit_breaker_state{breaker="green-api:1101000001"} 1`)
+
+	m.ForgetBreaker("green-api:1101000001")
+	require.NotContains(t, scrape(t, m), "green_api_backend_circuit_breaker_state{")
+}
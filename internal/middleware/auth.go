@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/auth"
+)
+
+const apiKeyKey = "api_key"
+
+// APIKeyAuth authenticates "Authorization: Bearer <api key>" requests. With a
+// nil authenticator authentication is disabled and every request runs as
+// auth.Anonymous, which holds all scopes.
+func APIKeyAuth(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticator == nil {
+			c.Set(apiKeyKey, auth.Anonymous)
+			c.Next()
+			return
+		}
+
+		header := strings.TrimSpace(c.GetHeader("Authorization"))
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, http.StatusUnauthorized, "unauthorized", "missing bearer api key")
+			return
+		}
+
+		key, err := authenticator.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			abortWithError(c, http.StatusUnauthorized, "unauthorized", "invalid api key")
+			return
+		}
+
+		c.Set(apiKeyKey, key)
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose API key does not grant scope. It must
+// run after APIKeyAuth; requests without a principal are rejected.
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetAPIKey(c)
+		if !ok {
+			abortWithError(c, http.StatusUnauthorized, "unauthorized", "request is not authenticated")
+			return
+		}
+		if !key.Has(scope) {
+			abortWithError(c, http.StatusForbidden, "forbidden", "api key lacks scope "+string(scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireAnyScope is RequireScope for endpoints shared by several scopes.
+func RequireAnyScope(scopes ...auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetAPIKey(c)
+		if !ok {
+			abortWithError(c, http.StatusUnauthorized, "unauthorized", "request is not authenticated")
+			return
+		}
+		for _, scope := range scopes {
+			if key.Has(scope) {
+				c.Next()
+				return
+			}
+		}
+		abortWithError(c, http.StatusForbidden, "forbidden", "api key lacks the required scope")
+	}
+}
+
+func GetAPIKey(c *gin.Context) (auth.Key, bool) {
+	if v, ok := c.Get(apiKeyKey); ok {
+		if key, castOK := v.(auth.Key); castOK {
+			return key, true
+		}
+	}
+	return auth.Key{}, false
+}
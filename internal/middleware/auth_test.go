@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/auth"
+)
+
+func newAuthRouter(t *testing.T, authenticator *auth.Authenticator) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(APIKeyAuth(authenticator))
+	r.GET("/state", RequireScope(auth.ScopeStateRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestAPIKeyAuth_DisabledRunsAsAnonymous(t *testing.T) {
+	t.Parallel()
+
+	resp := httptest.NewRecorder()
+	newAuthRouter(t, nil).ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/state", nil))
+
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestAPIKeyAuth_EnforcesTokenAndScope(t *testing.T) {
+	t.Parallel()
+
+	store, err := auth.NewStore("")
+	require.NoError(t, err)
+	authenticator := auth.NewAuthenticator(store, nil)
+	_, readToken, err := authenticator.Issue(context.Background(), "reader", []auth.Scope{auth.ScopeStateRead})
+	require.NoError(t, err)
+	_, sendToken, err := authenticator.Issue(context.Background(), "sender", []auth.Scope{auth.ScopeMessageSend})
+	require.NoError(t, err)
+
+	r := newAuthRouter(t, authenticator)
+	cases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "missing", header: "", status: http.StatusUnauthorized},
+		{name: "invalid", header: "Bearer gak_0000_bad", status: http.StatusUnauthorized},
+		{name: "wrong scope", header: "Bearer " + sendToken, status: http.StatusForbidden},
+		{name: "allowed", header: "Bearer " + readToken, status: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/state", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		require.Equal(t, tc.status, resp.Code, tc.name)
+		if tc.status == http.StatusUnauthorized {
+			require.Equal(t, "Bearer", resp.Header().Get("WWW-Authenticate"), tc.name)
+		}
+	}
+}
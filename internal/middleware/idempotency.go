@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"green-api/internal/idempotency"
+	"green-api/internal/model"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+	apiErrorKey               = "api_error"
+)
+
+// Idempotency replays the cached response for repeated submissions carrying
+// the same Idempotency-Key header. Keys are scoped by operation and API key,
+// so one key covers the operation on every API version: a retry on another
+// version replays the first response as it was sent. Requests without the
+// header pass through. The key is released only when the request provably
+// did not reach GREEN-API (a Retryable APIError), so the client may safely
+// try again. Other server errors are cached like successes:
+// a timeout or 502 on a send may still have delivered the message.
+func Idempotency(store *idempotency.Store, operation string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, http.StatusBadRequest, "bad_request", "Idempotency-Key header is too long")
+			return
+		}
+
+		// One byte over the limit tells an oversized body from one that fits.
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "bad_request", "cannot read request body")
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			abortWithError(c, http.StatusRequestEntityTooLarge, "request_too_large", "request body exceeds 1 MiB")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scopedKey := operation + ":" + key
+		if apiKey, ok := GetAPIKey(c); ok {
+			scopedKey = apiKey.ID + ":" + scopedKey
+		}
+		sum := sha256.Sum256(body)
+
+		cached, err := store.Begin(scopedKey, hex.EncodeToString(sum[:]))
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			abortWithError(c, http.StatusConflict, "idempotency_conflict", err.Error())
+			return
+		case errors.Is(err, idempotency.ErrStoreFull):
+			abortWithError(c, http.StatusServiceUnavailable, "idempotency_store_full", err.Error())
+			return
+		case errors.Is(err, idempotency.ErrKeyReused):
+			abortWithError(c, http.StatusUnprocessableEntity, "idempotency_key_reused", err.Error())
+			return
+		case cached != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(cached.StatusCode, cached.ContentType, cached.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			if r := recover(); r != nil {
+				// gin.Recovery answers outside this middleware. The handler may
+				// have reached GREEN-API before panicking, so the key is settled
+				// like any other ambiguous server error rather than left in
+				// progress until the TTL ends.
+				if apiErr, ok := GetAPIError(c); ok && apiErr.Retryable {
+					store.Release(scopedKey)
+				} else {
+					store.Complete(scopedKey, panicResult())
+				}
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		// A 429, ours or GREEN-API's, rejected the request before it was
+		// processed; replaying it would block every retry until the TTL ends.
+		status := recorder.Status()
+		apiErr, ok := GetAPIError(c)
+		if status == http.StatusTooManyRequests || ok && apiErr.Retryable {
+			store.Release(scopedKey)
+			return
+		}
+		store.Complete(scopedKey, idempotency.Result{
+			StatusCode:  status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+	}
+}
+
+// panicResult is replayed for a key whose handler panicked.
+func panicResult() idempotency.Result {
+	body, _ := json.Marshal(model.ErrorResponse{Error: model.APIError{
+		StatusCode: http.StatusInternalServerError,
+		Code:       "internal_error",
+		Message:    "request failed; it may have reached GREEN-API",
+	}})
+	return idempotency.Result{
+		StatusCode:  http.StatusInternalServerError,
+		ContentType: "application/json; charset=utf-8",
+		Body:        body,
+	}
+}
+
+// SetAPIError remembers the error a handler answered with, so Idempotency can
+// tell failures that never reached GREEN-API from ambiguous ones.
+func SetAPIError(c *gin.Context, err *model.APIError) {
+	c.Set(apiErrorKey, err)
+}
+
+func GetAPIError(c *gin.Context) (*model.APIError, bool) {
+	if v, ok := c.Get(apiErrorKey); ok {
+		if err, castOK := v.(*model.APIError); castOK && err != nil {
+			return err, true
+		}
+	}
+	return nil, false
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *bodyRecorder) WriteString(data string) (int, error) {
+	r.body.WriteString(data)
+	return r.ResponseWriter.WriteString(data)
+}
+
+func abortWithError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, model.ErrorResponse{Error: model.APIError{
+		StatusCode: status,
+		Code:       code,
+		Message:    message,
+	}})
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/idempotency"
+	"green-api/internal/model"
+)
+
+// newIdempotentRouter answers POST /send with the error of the current call,
+// or 200 when it is nil, and counts the calls that reached the handler.
+func newIdempotentRouter(t *testing.T, errs ...*model.APIError) (*gin.Engine, *int32) {
+	t.Helper()
+
+	var calls int32
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/send", Idempotency(idempotency.NewStore(time.Hour, 10), "sendMessage"), func(c *gin.Context) {
+		call := int(atomic.AddInt32(&calls, 1)) - 1
+		if err := errs[min(call, len(errs)-1)]; err != nil {
+			SetAPIError(c, err)
+			c.JSON(err.StatusCode, model.ErrorResponse{Error: *err})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"idMessage": "1"})
+	})
+	return r, &calls
+}
+
+func postIdempotent(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, "order-42")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestIdempotency_ReleasesOnlyUnsentRequests(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		err      *model.APIError
+		released bool
+	}{
+		{name: "not sent", err: &model.APIError{StatusCode: 503, Code: "upstream_error", Retryable: true}, released: true},
+		{name: "rate limited", err: &model.APIError{StatusCode: 429, Code: "rate_limited", Retryable: true}, released: true},
+		{name: "upstream 429", err: &model.APIError{StatusCode: 429, Code: "upstream_error"}, released: true},
+		{name: "timeout", err: &model.APIError{StatusCode: 504, Code: "upstream_error"}},
+		{name: "bad gateway", err: &model.APIError{StatusCode: 502, Code: "upstream_error"}},
+	}
+	for _, tc := range cases {
+		r, calls := newIdempotentRouter(t, tc.err, nil)
+
+		first := postIdempotent(r, `{"message":"hi"}`)
+		require.Equal(t, tc.err.StatusCode, first.Code, tc.name)
+
+		second := postIdempotent(r, `{"message":"hi"}`)
+		if tc.released {
+			require.Equal(t, http.StatusOK, second.Code, tc.name)
+			require.Equal(t, int32(2), atomic.LoadInt32(calls), tc.name)
+			continue
+		}
+		require.Equal(t, tc.err.StatusCode, second.Code, tc.name)
+		require.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader), tc.name)
+		require.Equal(t, int32(1), atomic.LoadInt32(calls), tc.name)
+	}
+}
+
+func TestIdempotency_RejectsOversizedBody(t *testing.T) {
+	t.Parallel()
+
+	r, calls := newIdempotentRouter(t, nil)
+
+	resp := postIdempotent(r, `{"message":"`+strings.Repeat("a", maxIdempotentRequestBytes)+`"}`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	require.Contains(t, resp.Body.String(), "request_too_large")
+	require.Zero(t, atomic.LoadInt32(calls))
+
+	resp = postIdempotent(r, `{"message":"hi"}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestIdempotency_ScopesKeysByOperation(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	store := idempotency.NewStore(time.Hour, 10)
+	send := func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusOK, gin.H{"idMessage": "1"})
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/v1/send-message", Idempotency(store, "sendMessage"), send)
+	r.POST("/api/v2/send-message", Idempotency(store, "sendMessage"), send)
+	r.POST("/api/v1/send-file-by-url", Idempotency(store, "sendFileByUrl"), send)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"chatId":"79991234567@c.us"}`))
+		req.Header.Set(IdempotencyKeyHeader, "order-42")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, post("/api/v1/send-message").Code)
+	retried := post("/api/v2/send-message")
+	require.Equal(t, http.StatusOK, retried.Code)
+	require.Equal(t, "true", retried.Header().Get(IdempotentReplayedHeader), "a retry on another version is replayed")
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	other := post("/api/v1/send-file-by-url")
+	require.Empty(t, other.Header().Get(IdempotentReplayedHeader), "another operation has its own keys")
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotency_SettlesKeyWhenHandlerPanics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		err      *model.APIError
+		released bool
+	}{
+		{name: "ambiguous"},
+		{name: "not sent", err: &model.APIError{StatusCode: 503, Code: "upstream_error", Retryable: true}, released: true},
+	}
+	for _, tc := range cases {
+		var calls int32
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}))
+		r.POST("/send", Idempotency(idempotency.NewStore(time.Hour, 10), "sendMessage"), func(c *gin.Context) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				if tc.err != nil {
+					SetAPIError(c, tc.err)
+				}
+				panic("boom")
+			}
+			c.JSON(http.StatusOK, gin.H{"idMessage": "1"})
+		})
+
+		require.Equal(t, http.StatusInternalServerError, postIdempotent(r, `{"message":"hi"}`).Code, tc.name)
+
+		retried := postIdempotent(r, `{"message":"hi"}`)
+		if tc.released {
+			require.Equal(t, http.StatusOK, retried.Code, tc.name)
+			require.Equal(t, int32(2), atomic.LoadInt32(&calls), tc.name)
+			continue
+		}
+		require.Equal(t, http.StatusInternalServerError, retried.Code, tc.name)
+		require.Equal(t, "true", retried.Header().Get(IdempotentReplayedHeader), tc.name)
+		require.Contains(t, retried.Body.String(), "internal_error", tc.name)
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls), tc.name)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -17,12 +18,16 @@ func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
 			path = c.Request.URL.Path
 		}
 
-		logger.Info("http_request",
+		fields := []zap.Field{
 			zap.String("request_id", GetRequestID(c)),
 			zap.String("method", c.Request.Method),
 			zap.String("route", path),
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("latency", time.Since(started)),
-		)
+		}
+		if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.IsValid() {
+			fields = append(fields, zap.String("trace_id", spanCtx.TraceID().String()))
+		}
+		logger.Info("http_request", fields...)
 	}
 }
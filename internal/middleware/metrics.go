@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that
+// scanners cannot create a metric series per requested path.
+const unmatchedRoute = "unmatched"
+
+// HTTPObserver records inbound request metrics.
+type HTTPObserver interface {
+	ObserveHTTP(method, route string, status int, duration time.Duration)
+}
+
+func Metrics(observer HTTPObserver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		observer.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), time.Since(started))
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	method string
+	route  string
+	status int
+}
+
+type recordingHTTPObserver struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func (o *recordingHTTPObserver) ObserveHTTP(method, route string, status int, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.requests = append(o.requests, recordedRequest{method: method, route: route, status: status})
+}
+
+func TestMetrics_LabelsByRouteTemplate(t *testing.T) {
+	t.Parallel()
+
+	observer := &recordingHTTPObserver{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics(observer))
+	r.GET("/jobs/:id", func(c *gin.Context) {
+		c.Status(http.StatusAccepted)
+	})
+
+	for _, path := range []string{"/jobs/a", "/jobs/b", "/wp-login.php"} {
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, []recordedRequest{
+		{method: http.MethodGet, route: "/jobs/:id", status: http.StatusAccepted},
+		{method: http.MethodGet, route: "/jobs/:id", status: http.StatusAccepted},
+		{method: http.MethodGet, route: unmatchedRoute, status: http.StatusNotFound},
+	}, observer.requests)
+}
@@ -0,0 +1,9 @@
+package model
+
+// Envelope is the body of every /api/v2 response: Data on success, Error
+// otherwise.
+type Envelope struct {
+	Data      any       `json:"data,omitempty"`
+	Error     *APIError `json:"error,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+}
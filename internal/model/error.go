@@ -5,6 +5,9 @@ type APIError struct {
 	Code       string      `json:"code"`
 	Message    string      `json:"message"`
 	Details    interface{} `json:"details,omitempty"`
+	// Retryable marks failures that provably did not reach GREEN-API, so the
+	// queue may repeat the call without risking a duplicate send.
+	Retryable bool `json:"-"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Handler reacts to a notification. Returning an error tells the source
+// (webhook or polling worker) that the notification was not processed.
+type Handler interface {
+	Handle(ctx context.Context, event Event) error
+}
+
+type HandlerFunc func(ctx context.Context, event Event) error
+
+func (f HandlerFunc) Handle(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Dispatcher routes events to the handlers registered for their type.
+type Dispatcher struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	any      []Handler
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[Type][]Handler)}
+}
+
+// On registers h for events of type t.
+func (d *Dispatcher) On(t Type, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[t] = append(d.handlers[t], h)
+}
+
+// OnAny registers h for every event.
+func (d *Dispatcher) OnAny(h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.any = append(d.any, h)
+}
+
+// Dispatch runs every matching handler and joins their errors.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) error {
+	d.mu.RLock()
+	handlers := make([]Handler, 0, len(d.any)+len(d.handlers[event.Type()]))
+	handlers = append(handlers, d.any...)
+	handlers = append(handlers, d.handlers[event.Type()]...)
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, h := range handlers {
+		if err := h.Handle(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("dispatch %s: %w", event.Type(), errors.Join(errs...))
+	}
+	return nil
+}
+
+// LogHandler writes a structured log line for every event.
+func LogHandler(logger *zap.Logger) Handler {
+	return HandlerFunc(func(_ context.Context, event Event) error {
+		fields := []zap.Field{
+			zap.String("type", string(event.Type())),
+			zap.String("id_instance", event.Instance().ID()),
+			zap.Time("event_time", event.Time()),
+		}
+		switch e := event.(type) {
+		case *Message:
+			fields = append(fields, zap.String("id_message", e.IDMessage), zap.String("type_message", e.MessageData.TypeMessage))
+		case *MessageStatus:
+			fields = append(fields, zap.String("id_message", e.IDMessage), zap.String("status", e.Status))
+		case *StateChanged:
+			fields = append(fields, zap.String("state_instance", e.StateInstance))
+		}
+		logger.Info("green_api_notification", fields...)
+		return nil
+	})
+}
@@ -0,0 +1,185 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Type is the GREEN-API "typeWebhook" value.
+type Type string
+
+const (
+	TypeIncomingMessageReceived    Type = "incomingMessageReceived"
+	TypeOutgoingMessageReceived    Type = "outgoingMessageReceived"
+	TypeOutgoingAPIMessageReceived Type = "outgoingAPIMessageReceived"
+	TypeOutgoingMessageStatus      Type = "outgoingMessageStatus"
+	TypeStateInstanceChanged       Type = "stateInstanceChanged"
+	TypeIncomingCall               Type = "incomingCall"
+)
+
+// Event is a decoded GREEN-API notification. Concrete values are one of the
+// *Message, *MessageStatus, *StateChanged, *IncomingCall or *Unknown types.
+type Event interface {
+	Type() Type
+	Instance() InstanceData
+	Time() time.Time
+}
+
+type InstanceData struct {
+	IDInstance   int64  `json:"idInstance"`
+	WID          string `json:"wid"`
+	TypeInstance string `json:"typeInstance"`
+}
+
+// ID returns idInstance in the string form used by the rest of the backend.
+func (i InstanceData) ID() string {
+	return strconv.FormatInt(i.IDInstance, 10)
+}
+
+// Envelope holds the fields shared by every notification.
+type Envelope struct {
+	TypeWebhook  Type         `json:"typeWebhook"`
+	InstanceData InstanceData `json:"instanceData"`
+	Timestamp    int64        `json:"timestamp"`
+}
+
+func (e Envelope) Type() Type {
+	return e.TypeWebhook
+}
+
+func (e Envelope) Instance() InstanceData {
+	return e.InstanceData
+}
+
+func (e Envelope) Time() time.Time {
+	return time.Unix(e.Timestamp, 0).UTC()
+}
+
+// Message is sent for incomingMessageReceived, outgoingMessageReceived and
+// outgoingAPIMessageReceived notifications.
+type Message struct {
+	Envelope
+	IDMessage   string      `json:"idMessage"`
+	SenderData  SenderData  `json:"senderData"`
+	MessageData MessageData `json:"messageData"`
+}
+
+type SenderData struct {
+	ChatID            string `json:"chatId"`
+	ChatName          string `json:"chatName"`
+	Sender            string `json:"sender"`
+	SenderName        string `json:"senderName"`
+	SenderContactName string `json:"senderContactName"`
+}
+
+type MessageData struct {
+	TypeMessage             string                   `json:"typeMessage"`
+	TextMessageData         *TextMessageData         `json:"textMessageData,omitempty"`
+	ExtendedTextMessageData *ExtendedTextMessageData `json:"extendedTextMessageData,omitempty"`
+	FileMessageData         *FileMessageData         `json:"fileMessageData,omitempty"`
+	LocationMessageData     *LocationMessageData     `json:"locationMessageData,omitempty"`
+	QuotedMessage           json.RawMessage          `json:"quotedMessage,omitempty"`
+}
+
+// Text returns the message text for text and extended text messages, or the
+// caption of a file message.
+func (m MessageData) Text() string {
+	switch {
+	case m.TextMessageData != nil:
+		return m.TextMessageData.TextMessage
+	case m.ExtendedTextMessageData != nil:
+		return m.ExtendedTextMessageData.Text
+	case m.FileMessageData != nil:
+		return m.FileMessageData.Caption
+	}
+	return ""
+}
+
+type TextMessageData struct {
+	TextMessage string `json:"textMessage"`
+}
+
+type ExtendedTextMessageData struct {
+	Text        string `json:"text"`
+	Description string `json:"description"`
+	Title       string `json:"title"`
+	PreviewType string `json:"previewType"`
+	StanzaID    string `json:"stanzaId"`
+	Participant string `json:"participant"`
+}
+
+type FileMessageData struct {
+	DownloadURL string `json:"downloadUrl"`
+	Caption     string `json:"caption"`
+	FileName    string `json:"fileName"`
+	MimeType    string `json:"mimeType"`
+}
+
+type LocationMessageData struct {
+	NameLocation string  `json:"nameLocation"`
+	Address      string  `json:"address"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+}
+
+// MessageStatus is sent for outgoingMessageStatus notifications.
+type MessageStatus struct {
+	Envelope
+	ChatID      string `json:"chatId"`
+	IDMessage   string `json:"idMessage"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	SendByAPI   bool   `json:"sendByApi"`
+}
+
+// StateChanged is sent for stateInstanceChanged notifications.
+type StateChanged struct {
+	Envelope
+	StateInstance string `json:"stateInstance"`
+}
+
+// IncomingCall is sent for incomingCall notifications.
+type IncomingCall struct {
+	Envelope
+	From      string `json:"from"`
+	Status    string `json:"status"`
+	IDMessage string `json:"idMessage"`
+}
+
+// Unknown carries notifications of types this backend does not model yet.
+type Unknown struct {
+	Envelope
+	Body json.RawMessage `json:"-"`
+}
+
+// Decode parses a GREEN-API notification body into its typed event.
+func Decode(body []byte) (Event, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("decode notification envelope: %w", err)
+	}
+	if envelope.TypeWebhook == "" {
+		return nil, fmt.Errorf("notification has no typeWebhook")
+	}
+
+	var event Event
+	switch envelope.TypeWebhook {
+	case TypeIncomingMessageReceived, TypeOutgoingMessageReceived, TypeOutgoingAPIMessageReceived:
+		event = &Message{}
+	case TypeOutgoingMessageStatus:
+		event = &MessageStatus{}
+	case TypeStateInstanceChanged:
+		event = &StateChanged{}
+	case TypeIncomingCall:
+		event = &IncomingCall{}
+	default:
+		return &Unknown{Envelope: envelope, Body: append(json.RawMessage(nil), body...)}, nil
+	}
+
+	if err := json.Unmarshal(body, event); err != nil {
+		return nil, fmt.Errorf("decode %s notification: %w", envelope.TypeWebhook, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const incomingTextMessage = `{
+  "typeWebhook": "incomingMessageReceived",
+  "instanceData": {"idInstance": 1101000001, "wid": "79876543210@c.us", "typeInstance": "whatsapp"},
+  "timestamp": 1588091580,
+  "idMessage": "F7AEC1B7086ECDC7E6E45923F5EDB825",
+  "senderData": {"chatId": "79001234568@c.us", "sender": "79001234568@c.us", "senderName": "Green API"},
+  "messageData": {"typeMessage": "textMessage", "textMessageData": {"textMessage": "hello"}}
+}`
+
+func TestDecode_IncomingMessage(t *testing.T) {
+	t.Parallel()
+
+	event, err := Decode([]byte(incomingTextMessage))
+	require.NoError(t, err)
+
+	message, ok := event.(*Message)
+	require.True(t, ok)
+	require.Equal(t, TypeIncomingMessageReceived, message.Type())
+	require.Equal(t, "1101000001", message.Instance().ID())
+	require.Equal(t, "79001234568@c.us", message.SenderData.ChatID)
+	require.Equal(t, "hello", message.MessageData.Text())
+	require.Equal(t, int64(1588091580), message.Time().Unix())
+}
+
+func TestDecode_StatusAndStateChanged(t *testing.T) {
+	t.Parallel()
+
+	event, err := Decode([]byte(`{"typeWebhook":"outgoingMessageStatus","instanceData":{"idInstance":1},"idMessage":"abc","status":"delivered"}`))
+	require.NoError(t, err)
+	status, ok := event.(*MessageStatus)
+	require.True(t, ok)
+	require.Equal(t, "delivered", status.Status)
+
+	event, err = Decode([]byte(`{"typeWebhook":"stateInstanceChanged","instanceData":{"idInstance":1},"stateInstance":"authorized"}`))
+	require.NoError(t, err)
+	state, ok := event.(*StateChanged)
+	require.True(t, ok)
+	require.Equal(t, "authorized", state.StateInstance)
+}
+
+func TestDecode_UnknownTypeKeepsBody(t *testing.T) {
+	t.Parallel()
+
+	body := `{"typeWebhook":"deviceInfo","instanceData":{"idInstance":1},"deviceData":{"battery":50}}`
+	event, err := Decode([]byte(body))
+	require.NoError(t, err)
+
+	unknown, ok := event.(*Unknown)
+	require.True(t, ok)
+	require.JSONEq(t, body, string(unknown.Body))
+}
+
+func TestDecode_RejectsMissingType(t *testing.T) {
+	t.Parallel()
+
+	_, err := Decode([]byte(`{"instanceData":{"idInstance":1}}`))
+	require.Error(t, err)
+}
+
+func TestDispatcher_RoutesByTypeAndJoinsErrors(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	d := NewDispatcher()
+	d.OnAny(HandlerFunc(func(_ context.Context, event Event) error {
+		seen = append(seen, "any:"+string(event.Type()))
+		return nil
+	}))
+	d.On(TypeIncomingMessageReceived, HandlerFunc(func(context.Context, Event) error {
+		seen = append(seen, "incoming")
+		return errors.New("boom")
+	}))
+	d.On(TypeOutgoingMessageStatus, HandlerFunc(func(context.Context, Event) error {
+		seen = append(seen, "status")
+		return nil
+	}))
+
+	event, err := Decode([]byte(incomingTextMessage))
+	require.NoError(t, err)
+
+	err = d.Dispatch(context.Background(), event)
+	require.ErrorContains(t, err, "boom")
+	require.Equal(t, []string{"any:incomingMessageReceived", "incoming"}, seen)
+}
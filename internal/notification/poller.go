@@ -0,0 +1,143 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"green-api/internal/greenapi"
+)
+
+// PollingClient is the subset of greenapi.Client used by Poller.
+type PollingClient interface {
+	ReceiveNotification(ctx context.Context, idInstance, apiTokenInstance string, receiveTimeout time.Duration) (greenapi.Response, error)
+	DeleteNotification(ctx context.Context, idInstance, apiTokenInstance string, receiptID int64) (greenapi.Response, error)
+}
+
+type Credentials struct {
+	IDInstance       string
+	APITokenInstance string
+}
+
+// Poller feeds notifications from receiveNotification into a Dispatcher for
+// deployments that cannot expose a public webhook URL. A notification is
+// acknowledged with deleteNotification only after all handlers succeeded.
+type Poller struct {
+	client         PollingClient
+	dispatcher     *Dispatcher
+	instances      []Credentials
+	receiveTimeout time.Duration
+	errorDelay     time.Duration
+	logger         *zap.Logger
+}
+
+type receivedNotification struct {
+	ReceiptID int64           `json:"receiptId"`
+	Body      json.RawMessage `json:"body"`
+}
+
+func NewPoller(client PollingClient, dispatcher *Dispatcher, instances []Credentials, receiveTimeout, errorDelay time.Duration, logger *zap.Logger) *Poller {
+	return &Poller{
+		client:         client,
+		dispatcher:     dispatcher,
+		instances:      instances,
+		receiveTimeout: receiveTimeout,
+		errorDelay:     errorDelay,
+		logger:         logger,
+	}
+}
+
+// Run polls every instance until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, instance := range p.instances {
+		wg.Add(1)
+		go func(creds Credentials) {
+			defer wg.Done()
+			p.pollInstance(ctx, creds)
+		}(instance)
+	}
+	wg.Wait()
+}
+
+func (p *Poller) pollInstance(ctx context.Context, creds Credentials) {
+	p.logger.Info("notification_polling_started", zap.String("id_instance", creds.IDInstance))
+	defer p.logger.Info("notification_polling_stopped", zap.String("id_instance", creds.IDInstance))
+
+	for ctx.Err() == nil {
+		if err := p.pollOnce(ctx, creds); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.logger.Warn("notification_polling_failed",
+				zap.String("id_instance", creds.IDInstance),
+				zap.Error(err),
+			)
+			sleep(ctx, p.errorDelay)
+		}
+	}
+}
+
+func (p *Poller) pollOnce(ctx context.Context, creds Credentials) error {
+	resp, err := p.client.ReceiveNotification(ctx, creds.IDInstance, creds.APITokenInstance, p.receiveTimeout)
+	if err != nil {
+		return fmt.Errorf("receive notification: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("receive notification: unexpected status %d", resp.StatusCode)
+	}
+
+	body := bytes.TrimSpace(resp.Body)
+	if len(body) == 0 || bytes.Equal(body, []byte("null")) {
+		return nil
+	}
+
+	var received receivedNotification
+	if err := json.Unmarshal(body, &received); err != nil {
+		return fmt.Errorf("decode received notification: %w", err)
+	}
+
+	event, err := Decode(received.Body)
+	if err != nil {
+		// A notification that cannot be decoded would block the queue forever,
+		// so it is dropped; there are no handlers that could have processed it.
+		p.logger.Error("notification_dropped_undecodable",
+			zap.String("id_instance", creds.IDInstance),
+			zap.Int64("receipt_id", received.ReceiptID),
+			zap.Error(err),
+		)
+		return p.ack(ctx, creds, received.ReceiptID)
+	}
+
+	if err := p.dispatcher.Dispatch(ctx, event); err != nil {
+		return err
+	}
+	return p.ack(ctx, creds, received.ReceiptID)
+}
+
+func (p *Poller) ack(ctx context.Context, creds Credentials, receiptID int64) error {
+	resp, err := p.client.DeleteNotification(ctx, creds.IDInstance, creds.APITokenInstance, receiptID)
+	if err != nil {
+		return fmt.Errorf("delete notification %d: %w", receiptID, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("delete notification %d: unexpected status %d", receiptID, resp.StatusCode)
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
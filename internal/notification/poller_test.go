@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/greenapi"
+)
+
+type fakePollingClient struct {
+	mu       sync.Mutex
+	queue    []string
+	deleted  []int64
+	received int
+}
+
+func (f *fakePollingClient) ReceiveNotification(ctx context.Context, _, _ string, _ time.Duration) (greenapi.Response, error) {
+	f.mu.Lock()
+	f.received++
+	if len(f.queue) == 0 {
+		f.mu.Unlock()
+		<-ctx.Done()
+		return greenapi.Response{}, ctx.Err()
+	}
+	body := f.queue[0]
+	f.mu.Unlock()
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(body)}, nil
+}
+
+func (f *fakePollingClient) DeleteNotification(_ context.Context, _, _ string, receiptID int64) (greenapi.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, receiptID)
+	f.queue = f.queue[1:]
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"result":true}`)}, nil
+}
+
+func (f *fakePollingClient) snapshot() ([]int64, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.deleted...), f.received
+}
+
+func newTestPoller(client PollingClient, dispatcher *Dispatcher) *Poller {
+	return NewPoller(client, dispatcher, []Credentials{{IDInstance: "1", APITokenInstance: "token"}}, time.Second, time.Millisecond, zap.NewNop())
+}
+
+func TestPoller_DispatchesThenAcknowledges(t *testing.T) {
+	t.Parallel()
+
+	client := &fakePollingClient{queue: []string{
+		`{"receiptId":7,"body":{"typeWebhook":"stateInstanceChanged","instanceData":{"idInstance":1},"stateInstance":"authorized"}}`,
+	}}
+
+	var got []Type
+	dispatcher := NewDispatcher()
+	dispatcher.OnAny(HandlerFunc(func(_ context.Context, event Event) error {
+		deleted, _ := client.snapshot()
+		require.Empty(t, deleted, "notification acknowledged before handlers ran")
+		got = append(got, event.Type())
+		return nil
+	}))
+
+	require.NoError(t, newTestPoller(client, dispatcher).pollOnce(context.Background(), Credentials{IDInstance: "1"}))
+
+	deleted, _ := client.snapshot()
+	require.Equal(t, []int64{7}, deleted)
+	require.Equal(t, []Type{TypeStateInstanceChanged}, got)
+}
+
+func TestPoller_DoesNotAcknowledgeWhenHandlerFails(t *testing.T) {
+	t.Parallel()
+
+	client := &fakePollingClient{queue: []string{
+		`{"receiptId":7,"body":{"typeWebhook":"stateInstanceChanged","instanceData":{"idInstance":1}}}`,
+	}}
+	dispatcher := NewDispatcher()
+	dispatcher.OnAny(HandlerFunc(func(context.Context, Event) error {
+		return errors.New("handler failed")
+	}))
+
+	err := newTestPoller(client, dispatcher).pollOnce(context.Background(), Credentials{IDInstance: "1"})
+	require.ErrorContains(t, err, "handler failed")
+
+	deleted, _ := client.snapshot()
+	require.Empty(t, deleted)
+}
+
+func TestPoller_EmptyQueueIsNotAnError(t *testing.T) {
+	t.Parallel()
+
+	client := &emptyPollingClient{}
+	require.NoError(t, newTestPoller(client, NewDispatcher()).pollOnce(context.Background(), Credentials{IDInstance: "1"}))
+}
+
+func TestPoller_RunStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	client := &fakePollingClient{}
+	poller := newTestPoller(client, NewDispatcher())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		poller.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, received := client.snapshot()
+		return received > 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("poller did not stop after cancel")
+	}
+}
+
+type emptyPollingClient struct{}
+
+func (emptyPollingClient) ReceiveNotification(context.Context, string, string, time.Duration) (greenapi.Response, error) {
+	return greenapi.Response{StatusCode: http.StatusOK, Body: []byte("null")}, nil
+}
+
+func (emptyPollingClient) DeleteNotification(context.Context, string, string, int64) (greenapi.Response, error) {
+	return greenapi.Response{}, errors.New("must not be called")
+}
@@ -0,0 +1,322 @@
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"green-api/internal/model"
+)
+
+// Status is the lifecycle state of a Job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+var ErrNotFound = errors.New("job not found")
+
+var (
+	jobsBucket  = []byte("jobs")
+	readyBucket = []byte("ready")
+)
+
+// Job is a persisted unit of outbound work. Payload is interpreted by the
+// Processor registered for Kind; Result holds the upstream response body.
+type Job struct {
+	ID            string          `json:"id"`
+	Owner         string          `json:"owner,omitempty"`
+	Kind          string          `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        Status          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	Result        json.RawMessage `json:"result,omitempty"`
+	Error         *model.APIError `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+}
+
+// Queue is a durable job queue stored in a bbolt file. Jobs live in the jobs
+// bucket; the ready bucket indexes queued jobs by their next attempt time so
+// that claiming the next due job is a single cursor seek.
+type Queue struct {
+	db    *bolt.DB
+	ready chan struct{}
+	now   func() time.Time
+}
+
+// Open opens or creates the queue file at path. Jobs that were running when
+// the process stopped are marked failed: a send may already have reached
+// GREEN-API, and repeating it could deliver the message twice.
+func Open(path string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create queue dir: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open queue: %w", err)
+	}
+
+	q := &Queue{db: db, ready: make(chan struct{}, 1), now: time.Now}
+	if err := q.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+func (q *Queue) init() error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		jobs, err := tx.CreateBucketIfNotExists(jobsBucket)
+		if err != nil {
+			return fmt.Errorf("create jobs bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists(readyBucket); err != nil {
+			return fmt.Errorf("create ready bucket: %w", err)
+		}
+
+		var interrupted []Job
+		err = jobs.ForEach(func(_, value []byte) error {
+			var job Job
+			if err := json.Unmarshal(value, &job); err != nil {
+				return fmt.Errorf("decode job: %w", err)
+			}
+			if job.Status == StatusRunning {
+				interrupted = append(interrupted, job)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, job := range interrupted {
+			job.Status = StatusFailed
+			job.UpdatedAt = q.now().UTC()
+			job.Error = &model.APIError{
+				Code:    "delivery_unknown",
+				Message: "service stopped while the job was being delivered; it may or may not have been sent",
+			}
+			if err := putJob(tx, job); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Enqueue stores a new job that is due immediately.
+func (q *Queue) Enqueue(owner, kind string, payload any) (Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return Job{}, fmt.Errorf("encode job payload: %w", err)
+	}
+
+	now := q.now().UTC()
+	job := Job{
+		ID:            uuid.NewString(),
+		Owner:         owner,
+		Kind:          kind,
+		Payload:       encoded,
+		Status:        StatusQueued,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		if err := putJob(tx, job); err != nil {
+			return err
+		}
+		return tx.Bucket(readyBucket).Put(readyKey(job), []byte(job.ID))
+	})
+	if err != nil {
+		return Job{}, err
+	}
+
+	q.signal()
+	return job, nil
+}
+
+func (q *Queue) Get(id string) (Job, error) {
+	var job Job
+	err := q.db.View(func(tx *bolt.Tx) error {
+		var err error
+		job, err = getJob(tx, id)
+		return err
+	})
+	return job, err
+}
+
+// Depth returns the number of jobs waiting to be delivered.
+func (q *Queue) Depth() (int, error) {
+	var depth int
+	err := q.db.View(func(tx *bolt.Tx) error {
+		depth = tx.Bucket(readyBucket).Stats().KeyN
+		return nil
+	})
+	return depth, err
+}
+
+// Purge removes finished jobs last updated before cutoff.
+func (q *Queue) Purge(cutoff time.Time) (int, error) {
+	var purged int
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		jobs := tx.Bucket(jobsBucket)
+		// Deleting under a cursor makes bbolt skip the next key, so the keys
+		// are collected first.
+		var expired [][]byte
+		err := jobs.ForEach(func(key, value []byte) error {
+			var job Job
+			if err := json.Unmarshal(value, &job); err != nil {
+				return fmt.Errorf("decode job: %w", err)
+			}
+			finished := job.Status == StatusSucceeded || job.Status == StatusFailed
+			if finished && job.UpdatedAt.Before(cutoff) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := jobs.Delete(key); err != nil {
+				return err
+			}
+		}
+		purged = len(expired)
+		return nil
+	})
+	return purged, err
+}
+
+// claim marks the earliest due job as running and returns it.
+func (q *Queue) claim() (Job, bool, error) {
+	now := q.now().UTC()
+	var job Job
+	var found bool
+
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(readyBucket).Cursor()
+		key, id := cursor.First()
+		if key == nil || readyTime(key).After(now) {
+			return nil
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+
+		var err error
+		job, err = getJob(tx, string(id))
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		job.Status = StatusRunning
+		job.Attempts++
+		job.UpdatedAt = now
+		found = true
+		return putJob(tx, job)
+	})
+	return job, found, err
+}
+
+func (q *Queue) complete(id string, result json.RawMessage) error {
+	return q.update(id, func(tx *bolt.Tx, job *Job) error {
+		job.Status = StatusSucceeded
+		job.Result = result
+		job.Error = nil
+		return nil
+	})
+}
+
+func (q *Queue) fail(id string, apiErr *model.APIError) error {
+	return q.update(id, func(tx *bolt.Tx, job *Job) error {
+		job.Status = StatusFailed
+		job.Error = apiErr
+		return nil
+	})
+}
+
+// retry puts the job back into the ready index after delay.
+func (q *Queue) retry(id string, apiErr *model.APIError, delay time.Duration) error {
+	return q.update(id, func(tx *bolt.Tx, job *Job) error {
+		job.Status = StatusQueued
+		job.Error = apiErr
+		job.NextAttemptAt = q.now().UTC().Add(delay)
+		return tx.Bucket(readyBucket).Put(readyKey(*job), []byte(job.ID))
+	})
+}
+
+func (q *Queue) update(id string, mutate func(tx *bolt.Tx, job *Job) error) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		job, err := getJob(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := mutate(tx, &job); err != nil {
+			return err
+		}
+		job.UpdatedAt = q.now().UTC()
+		return putJob(tx, job)
+	})
+}
+
+// signal wakes one idle worker without blocking.
+func (q *Queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func getJob(tx *bolt.Tx, id string) (Job, error) {
+	value := tx.Bucket(jobsBucket).Get([]byte(id))
+	if value == nil {
+		return Job{}, ErrNotFound
+	}
+	var job Job
+	if err := json.Unmarshal(value, &job); err != nil {
+		return Job{}, fmt.Errorf("decode job: %w", err)
+	}
+	return job, nil
+}
+
+func putJob(tx *bolt.Tx, job Job) error {
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("encode job: %w", err)
+	}
+	return tx.Bucket(jobsBucket).Put([]byte(job.ID), encoded)
+}
+
+// readyKey orders the ready index by due time, then by job ID.
+func readyKey(job Job) []byte {
+	key := make([]byte, 8, 8+len(job.ID))
+	binary.BigEndian.PutUint64(key, uint64(job.NextAttemptAt.UnixNano()))
+	return append(key, job.ID...)
+}
+
+func readyTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
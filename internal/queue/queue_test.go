@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/model"
+)
+
+func openTestQueue(t *testing.T) (*Queue, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "queue.db")
+	q, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { q.Close() })
+	return q, path
+}
+
+func TestQueue_ClaimsDueJobsInOrder(t *testing.T) {
+	t.Parallel()
+
+	q, _ := openTestQueue(t)
+	first, err := q.Enqueue("key-1", "sendMessage", map[string]string{"n": "1"})
+	require.NoError(t, err)
+	_, err = q.Enqueue("key-1", "sendMessage", map[string]string{"n": "2"})
+	require.NoError(t, err)
+
+	depth, err := q.Depth()
+	require.NoError(t, err)
+	require.Equal(t, 2, depth)
+
+	job, ok, err := q.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, first.ID, job.ID)
+	require.Equal(t, StatusRunning, job.Status)
+	require.Equal(t, 1, job.Attempts)
+
+	require.NoError(t, q.retry(job.ID, &model.APIError{Code: "upstream_error"}, time.Hour))
+	next, ok, err := q.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotEqual(t, first.ID, next.ID)
+
+	_, ok, err = q.claim()
+	require.NoError(t, err)
+	require.False(t, ok, "retried job is not due yet")
+
+	stored, err := q.Get(first.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusQueued, stored.Status)
+	require.Equal(t, "upstream_error", stored.Error.Code)
+}
+
+func TestQueue_ReopenFailsInterruptedJobs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "queue.db")
+	q, err := Open(path)
+	require.NoError(t, err)
+	queued, err := q.Enqueue("key-1", "sendMessage", nil)
+	require.NoError(t, err)
+	_, ok, err := q.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, q.Close())
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	job, err := reopened.Get(queued.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, job.Status)
+	require.Equal(t, "delivery_unknown", job.Error.Code)
+
+	_, err = reopened.Get("missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQueue_PurgeRemovesOldFinishedJobs(t *testing.T) {
+	t.Parallel()
+
+	q, _ := openTestQueue(t)
+	done, err := q.Enqueue("key-1", "sendMessage", nil)
+	require.NoError(t, err)
+	_, _, err = q.claim()
+	require.NoError(t, err)
+	require.NoError(t, q.complete(done.ID, json.RawMessage(`{}`)))
+	pending, err := q.Enqueue("key-1", "sendMessage", nil)
+	require.NoError(t, err)
+
+	purged, err := q.Purge(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	_, err = q.Get(done.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = q.Get(pending.ID)
+	require.NoError(t, err)
+}
+
+func TestQueue_PurgeRemovesAdjacentJobs(t *testing.T) {
+	t.Parallel()
+
+	q, _ := openTestQueue(t)
+	var ids []string
+	for range 50 {
+		job, err := q.Enqueue("key-1", "sendMessage", nil)
+		require.NoError(t, err)
+		_, _, err = q.claim()
+		require.NoError(t, err)
+		require.NoError(t, q.complete(job.ID, json.RawMessage(`{}`)))
+		ids = append(ids, job.ID)
+	}
+
+	purged, err := q.Purge(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, len(ids), purged)
+	for _, id := range ids {
+		_, err = q.Get(id)
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
+type processorFunc func(ctx context.Context, job Job) (json.RawMessage, *model.APIError)
+
+func (f processorFunc) ProcessJob(ctx context.Context, job Job) (json.RawMessage, *model.APIError) {
+	return f(ctx, job)
+}
+
+func TestWorker_RetriesRetryableErrors(t *testing.T) {
+	t.Parallel()
+
+	q, _ := openTestQueue(t)
+	var mu sync.Mutex
+	calls := 0
+	processor := processorFunc(func(context.Context, Job) (json.RawMessage, *model.APIError) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			return nil, &model.APIError{Code: "upstream_error", Retryable: true}
+		}
+		return json.RawMessage(`{"idMessage":"BAE5"}`), nil
+	})
+
+	worker := NewWorker(q, processor, config.QueueConfig{Workers: 1, PollIntervalSeconds: 1}, zap.NewNop())
+	worker.backoff, worker.maxBackoff = time.Millisecond, time.Millisecond
+
+	job, err := q.Enqueue("key-1", "sendMessage", nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		stored, err := q.Get(job.ID)
+		return err == nil && stored.Status == StatusSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	stored, err := q.Get(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, stored.Attempts)
+	require.JSONEq(t, `{"idMessage":"BAE5"}`, string(stored.Result))
+	require.Nil(t, stored.Error)
+}
+
+func TestWorker_FailsNonRetryableErrorsAndExhaustedJobs(t *testing.T) {
+	t.Parallel()
+
+	q, _ := openTestQueue(t)
+	worker := NewWorker(q, processorFunc(func(_ context.Context, job Job) (json.RawMessage, *model.APIError) {
+		return nil, &model.APIError{Code: job.Kind, Retryable: job.Kind == "retryable"}
+	}), config.QueueConfig{MaxAttempts: 1}, zap.NewNop())
+
+	for _, kind := range []string{"permanent", "retryable"} {
+		job, err := q.Enqueue("key-1", kind, nil)
+		require.NoError(t, err)
+
+		processed, err := worker.runOnce(context.Background())
+		require.NoError(t, err)
+		require.True(t, processed)
+
+		stored, err := q.Get(job.ID)
+		require.NoError(t, err)
+		require.Equal(t, StatusFailed, stored.Status, kind)
+	}
+}
+
+func TestWorker_DelayDoublesUpToMax(t *testing.T) {
+	t.Parallel()
+
+	worker := &Worker{backoff: time.Second, maxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, worker.delay(1))
+	require.Equal(t, 2*time.Second, worker.delay(2))
+	require.Equal(t, 4*time.Second, worker.delay(3))
+	require.Equal(t, 5*time.Second, worker.delay(4))
+	require.Equal(t, 5*time.Second, worker.delay(40))
+}
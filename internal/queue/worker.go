@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/model"
+)
+
+// Processor delivers a job. A retryable error puts the job back into the
+// queue with backoff; any other error fails it.
+type Processor interface {
+	ProcessJob(ctx context.Context, job Job) (json.RawMessage, *model.APIError)
+}
+
+const purgeInterval = 10 * time.Minute
+
+// Worker runs a pool of goroutines that claim due jobs and hand them to the
+// Processor.
+type Worker struct {
+	queue        *Queue
+	processor    Processor
+	concurrency  int
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	retention    time.Duration
+	logger       *zap.Logger
+}
+
+func NewWorker(queue *Queue, processor Processor, cfg config.QueueConfig, logger *zap.Logger) *Worker {
+	return &Worker{
+		queue:        queue,
+		processor:    processor,
+		concurrency:  cfg.WorkerCount(),
+		maxAttempts:  cfg.Attempts(),
+		backoff:      cfg.Backoff(),
+		maxBackoff:   cfg.MaxBackoff(),
+		pollInterval: cfg.PollInterval(),
+		retention:    cfg.Retention(),
+		logger:       logger,
+	}
+}
+
+// Run delivers jobs until ctx is cancelled. Jobs already being delivered are
+// allowed to finish so that their outcome is recorded.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	w.purge()
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			w.purge()
+		}
+	}
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := w.runOnce(ctx)
+		if err != nil {
+			w.logger.Error("queue_job_store_failed", zap.Error(err))
+		}
+		if processed && err == nil {
+			continue
+		}
+
+		timer := time.NewTimer(w.pollInterval)
+		select {
+		case <-ctx.Done():
+		case <-w.queue.ready:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// runOnce delivers the next due job, if any.
+func (w *Worker) runOnce(ctx context.Context) (bool, error) {
+	job, ok, err := w.queue.claim()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	result, apiErr := w.processor.ProcessJob(context.WithoutCancel(ctx), job)
+	fields := []zap.Field{
+		zap.String("job_id", job.ID),
+		zap.String("kind", job.Kind),
+		zap.Int("attempt", job.Attempts),
+	}
+
+	switch {
+	case apiErr == nil:
+		w.logger.Info("queue_job_succeeded", fields...)
+		return true, w.queue.complete(job.ID, result)
+	case apiErr.Retryable && job.Attempts < w.maxAttempts:
+		delay := w.delay(job.Attempts)
+		w.logger.Warn("queue_job_retry_scheduled", append(fields, zap.String("code", apiErr.Code), zap.Duration("delay", delay))...)
+		return true, w.queue.retry(job.ID, apiErr, delay)
+	default:
+		w.logger.Warn("queue_job_failed", append(fields, zap.String("code", apiErr.Code))...)
+		return true, w.queue.fail(job.ID, apiErr)
+	}
+}
+
+// delay doubles the backoff after every attempt, up to maxBackoff.
+func (w *Worker) delay(attempts int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempts && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.maxBackoff {
+		return w.maxBackoff
+	}
+	return delay
+}
+
+func (w *Worker) purge() {
+	purged, err := w.queue.Purge(w.queue.now().Add(-w.retention))
+	if err != nil {
+		w.logger.Error("queue_purge_failed", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		w.logger.Info("queue_jobs_purged", zap.Int("count", purged))
+	}
+}
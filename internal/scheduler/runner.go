@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/model"
+)
+
+// Executor performs the request stored in a schedule.
+type Executor interface {
+	ExecuteSchedule(ctx context.Context, sched Schedule) (json.RawMessage, *model.APIError)
+}
+
+// Runner executes due schedules with a small pool of goroutines.
+type Runner struct {
+	store        *Store
+	executor     Executor
+	concurrency  int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	logger       *zap.Logger
+}
+
+func NewRunner(store *Store, executor Executor, cfg config.SchedulerConfig, logger *zap.Logger) *Runner {
+	return &Runner{
+		store:        store,
+		executor:     executor,
+		concurrency:  cfg.WorkerCount(),
+		pollInterval: cfg.PollInterval(),
+		maxAttempts:  cfg.Attempts(),
+		backoff:      cfg.Backoff(),
+		maxBackoff:   cfg.MaxBackoff(),
+		logger:       logger,
+	}
+}
+
+// Run executes schedules until ctx is cancelled. Runs already in progress
+// are allowed to finish so that their outcome is recorded.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < r.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		ran, err := r.runOnce(ctx)
+		if err != nil {
+			r.logger.Error("schedule_store_failed", zap.Error(err))
+		}
+		if ran && err == nil {
+			continue
+		}
+
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-ctx.Done():
+		case <-r.store.changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// runOnce executes the next due schedule, if any.
+func (r *Runner) runOnce(ctx context.Context) (bool, error) {
+	sched, ok, err := r.store.claim()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	result, apiErr := r.executor.ExecuteSchedule(context.WithoutCancel(ctx), sched)
+	fields := []zap.Field{
+		zap.String("schedule_id", sched.ID),
+		zap.String("kind", sched.Kind),
+		zap.Int("run", sched.Runs),
+	}
+	switch {
+	case apiErr == nil:
+		r.logger.Info("schedule_run_succeeded", fields...)
+	case apiErr.Retryable && sched.Spec.OneShot() && sched.Runs < r.maxAttempts:
+		// Nothing reached GREEN-API, so the reminder is still owed.
+		delay := r.delay(sched.Runs)
+		r.logger.Warn("schedule_retry_scheduled", append(fields, zap.String("code", apiErr.Code), zap.Duration("delay", delay))...)
+		return true, r.store.retry(sched.ID, apiErr, delay)
+	default:
+		r.logger.Warn("schedule_run_failed", append(fields, zap.String("code", apiErr.Code))...)
+	}
+	return true, r.store.record(sched.ID, result, apiErr)
+}
+
+// delay doubles the backoff after every run, up to maxBackoff.
+func (r *Runner) delay(runs int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < runs && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		return r.maxBackoff
+	}
+	return delay
+}
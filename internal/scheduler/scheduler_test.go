@@ -0,0 +1,277 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/model"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "schedules.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store, path
+}
+
+func TestSpec_Validate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	cases := []struct {
+		name string
+		spec Spec
+		next time.Time
+		err  string
+	}{
+		{name: "empty", spec: Spec{}, err: "either sendAt or cron"},
+		{name: "both", spec: Spec{SendAt: &future, Cron: "* * * * *"}, err: "not both"},
+		{name: "past", spec: Spec{SendAt: &past}, err: "future"},
+		{name: "timezone with sendAt", spec: Spec{SendAt: &future, Timezone: "Europe/Moscow"}, err: "cron only"},
+		{name: "bad cron", spec: Spec{Cron: "every day"}, err: "invalid cron"},
+		{name: "tz inside cron", spec: Spec{Cron: "CRON_TZ=UTC 0 9 * * *"}, err: "timezone field"},
+		{name: "bad timezone", spec: Spec{Cron: "0 9 * * *", Timezone: "Mars/Base"}, err: "unknown timezone"},
+		{name: "one shot", spec: Spec{SendAt: &future}, next: future},
+		{name: "cron in utc", spec: Spec{Cron: "0 9 * * *"}, next: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+		{name: "cron in zone", spec: Spec{Cron: "0 9 * * *", Timezone: "Europe/Moscow"}, next: time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		next, err := tc.spec.Validate(now)
+		if tc.err != "" {
+			require.ErrorContains(t, err, tc.err, tc.name)
+			continue
+		}
+		require.NoError(t, err, tc.name)
+		require.True(t, tc.next.Equal(next), "%s: got %s", tc.name, next)
+	}
+}
+
+func TestStore_OneShotRunsOnce(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	sendAt := time.Now().Add(time.Hour)
+	sched, err := store.Create("key-1", "sendMessage", map[string]string{"message": "hi"}, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, sched.Status)
+
+	_, ok, err := store.claim()
+	require.NoError(t, err)
+	require.False(t, ok, "not due yet")
+
+	store.now = func() time.Time { return sendAt.Add(time.Second) }
+	claimed, ok, err := store.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, StatusRunning, claimed.Status)
+	require.NoError(t, store.record(claimed.ID, json.RawMessage(`{"idMessage":"1"}`), nil))
+
+	_, ok, err = store.claim()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	stored, err := store.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, stored.Status)
+	require.Equal(t, 1, stored.Runs)
+	require.Nil(t, stored.NextRunAt)
+}
+
+func TestStore_CronAdvancesBeforeRunning(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	start := time.Date(2026, 3, 1, 8, 59, 0, 0, time.UTC)
+	store.now = func() time.Time { return start }
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{Cron: "0 9 * * *"})
+	require.NoError(t, err)
+
+	// The service was down for two days: one catch-up run, then the next 09:00.
+	store.now = func() time.Time { return start.Add(48 * time.Hour) }
+	claimed, ok, err := store.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, StatusActive, claimed.Status)
+	require.Equal(t, time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC), *claimed.NextRunAt)
+	require.NoError(t, store.record(sched.ID, nil, &model.APIError{Code: "upstream_error"}))
+
+	stored, err := store.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, stored.Status, "a failed cron run does not stop the schedule")
+	require.Equal(t, "upstream_error", stored.LastError.Code)
+}
+
+func TestStore_CancelAndReschedule(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	sendAt := time.Now().Add(time.Hour)
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+
+	later := sendAt.Add(time.Hour)
+	rescheduled, err := store.Reschedule(sched.ID, Spec{SendAt: &later})
+	require.NoError(t, err)
+	require.True(t, later.Equal(*rescheduled.NextRunAt))
+
+	store.now = func() time.Time { return sendAt.Add(time.Minute) }
+	_, ok, err := store.claim()
+	require.NoError(t, err)
+	require.False(t, ok, "the old run time was removed from the index")
+
+	cancelled, err := store.Cancel(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusCancelled, cancelled.Status)
+
+	_, err = store.Reschedule(sched.ID, Spec{SendAt: &later})
+	require.ErrorIs(t, err, ErrConflict)
+
+	list, err := store.List("key-1")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	list, err = store.List("key-2")
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestStore_ReopenFailsInterruptedOneShot(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "schedules.db")
+	store, err := Open(path)
+	require.NoError(t, err)
+	sendAt := time.Now().Add(time.Hour)
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+	store.now = func() time.Time { return sendAt }
+	_, ok, err := store.claim()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, store.Close())
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	stored, err := reopened.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, stored.Status)
+	require.Equal(t, "delivery_unknown", stored.LastError.Code)
+}
+
+type executorFunc func(ctx context.Context, sched Schedule) (json.RawMessage, *model.APIError)
+
+func (f executorFunc) ExecuteSchedule(ctx context.Context, sched Schedule) (json.RawMessage, *model.APIError) {
+	return f(ctx, sched)
+}
+
+func TestRunner_ExecutesDueSchedules(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	executed := make(chan Schedule, 1)
+	runner := NewRunner(store, executorFunc(func(_ context.Context, sched Schedule) (json.RawMessage, *model.APIError) {
+		executed <- sched
+		return json.RawMessage(`{"idMessage":"1"}`), nil
+	}), config.SchedulerConfig{Workers: 1}, zap.NewNop())
+
+	sendAt := time.Now().Add(50 * time.Millisecond)
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case got := <-executed:
+		require.Equal(t, sched.ID, got.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("schedule was not executed")
+	}
+	require.Eventually(t, func() bool {
+		stored, err := store.Get(sched.ID)
+		return err == nil && stored.Status == StatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestRunner_RetriesOneShotUntilSent(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	var runs []int
+	runner := NewRunner(store, executorFunc(func(_ context.Context, sched Schedule) (json.RawMessage, *model.APIError) {
+		runs = append(runs, sched.Runs)
+		if sched.Runs == 1 {
+			return nil, &model.APIError{StatusCode: 503, Code: "upstream_error", Retryable: true}
+		}
+		return json.RawMessage(`{"idMessage":"1"}`), nil
+	}), config.SchedulerConfig{Workers: 1}, zap.NewNop())
+
+	sendAt := time.Now().Add(time.Hour)
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+	store.now = func() time.Time { return sendAt }
+
+	ran, err := runner.runOnce(context.Background())
+	require.NoError(t, err)
+	require.True(t, ran)
+	stored, err := store.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusActive, stored.Status)
+	require.NotNil(t, stored.NextRunAt)
+	require.Equal(t, "upstream_error", stored.LastError.Code)
+
+	ran, err = runner.runOnce(context.Background())
+	require.NoError(t, err)
+	require.False(t, ran, "the retry waits for its backoff")
+
+	store.now = func() time.Time { return sendAt.Add(time.Minute) }
+	ran, err = runner.runOnce(context.Background())
+	require.NoError(t, err)
+	require.True(t, ran)
+	stored, err = store.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusCompleted, stored.Status)
+	require.Nil(t, stored.LastError)
+	require.Equal(t, []int{1, 2}, runs)
+}
+
+func TestRunner_FailsOneShotAfterLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	store, _ := openTestStore(t)
+	runner := NewRunner(store, executorFunc(func(context.Context, Schedule) (json.RawMessage, *model.APIError) {
+		return nil, &model.APIError{StatusCode: 503, Code: "upstream_error", Retryable: true}
+	}), config.SchedulerConfig{Workers: 1, MaxAttempts: 1}, zap.NewNop())
+
+	sendAt := time.Now().Add(time.Hour)
+	sched, err := store.Create("key-1", "sendMessage", nil, Spec{SendAt: &sendAt})
+	require.NoError(t, err)
+	store.now = func() time.Time { return sendAt }
+
+	_, err = runner.runOnce(context.Background())
+	require.NoError(t, err)
+	stored, err := store.Get(sched.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, stored.Status)
+}
@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Alpine images ship without a zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Spec says when a scheduled request runs: once at SendAt, or repeatedly by
+// a standard five-field Cron expression evaluated in Timezone (UTC when empty).
+type Spec struct {
+	SendAt   *time.Time `json:"sendAt,omitempty"`
+	Cron     string     `json:"cron,omitempty"`
+	Timezone string     `json:"timezone,omitempty"`
+}
+
+// OneShot reports whether the spec runs only once.
+func (s Spec) OneShot() bool {
+	return s.SendAt != nil
+}
+
+// Validate checks the spec and returns the first run time after now.
+func (s Spec) Validate(now time.Time) (time.Time, error) {
+	cronExpr := strings.TrimSpace(s.Cron)
+	switch {
+	case s.SendAt == nil && cronExpr == "":
+		return time.Time{}, fmt.Errorf("either sendAt or cron is required")
+	case s.SendAt != nil && cronExpr != "":
+		return time.Time{}, fmt.Errorf("use either sendAt or cron, not both")
+	case s.SendAt != nil && s.Timezone != "":
+		return time.Time{}, fmt.Errorf("timezone applies to cron only; sendAt carries its own offset")
+	case s.SendAt != nil && !s.SendAt.After(now):
+		return time.Time{}, fmt.Errorf("sendAt must be in the future")
+	}
+
+	next, ok, err := s.Next(now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !ok {
+		return time.Time{}, fmt.Errorf("cron expression never fires")
+	}
+	return next, nil
+}
+
+// Next returns the first run time strictly after after. ok is false when a
+// one-shot spec has already fired.
+func (s Spec) Next(after time.Time) (next time.Time, ok bool, err error) {
+	if s.SendAt != nil {
+		return s.SendAt.UTC(), s.SendAt.After(after), nil
+	}
+
+	schedule, loc, err := s.parseCron()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	next = schedule.Next(after.In(loc))
+	return next.UTC(), !next.IsZero(), nil
+}
+
+func (s Spec) parseCron() (cron.Schedule, *time.Location, error) {
+	expr := strings.TrimSpace(s.Cron)
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		return nil, nil, fmt.Errorf("set the time zone with the timezone field, not inside cron")
+	}
+
+	loc := time.UTC
+	if s.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("unknown timezone %q", s.Timezone)
+		}
+	}
+
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	return schedule, loc, nil
+}
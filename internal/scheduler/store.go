@@ -0,0 +1,386 @@
+package scheduler
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"green-api/internal/model"
+)
+
+// Status is the lifecycle state of a Schedule.
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+var (
+	ErrNotFound = errors.New("schedule not found")
+	// ErrConflict is returned when a schedule cannot change in its current status.
+	ErrConflict = errors.New("schedule cannot be changed in its current status")
+)
+
+var (
+	schedulesBucket = []byte("schedules")
+	dueBucket       = []byte("due")
+)
+
+// Schedule is a persisted request that runs at Spec's times. Payload is
+// interpreted by the Executor according to Kind.
+type Schedule struct {
+	ID         string          `json:"id"`
+	Owner      string          `json:"owner,omitempty"`
+	Kind       string          `json:"kind"`
+	Payload    json.RawMessage `json:"payload"`
+	Spec       Spec            `json:"spec"`
+	Status     Status          `json:"status"`
+	NextRunAt  *time.Time      `json:"nextRunAt,omitempty"`
+	Runs       int             `json:"runs"`
+	LastRunAt  *time.Time      `json:"lastRunAt,omitempty"`
+	LastResult json.RawMessage `json:"lastResult,omitempty"`
+	LastError  *model.APIError `json:"lastError,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+// Store keeps schedules in a bbolt file. The due bucket indexes active
+// schedules by their next run time.
+type Store struct {
+	db      *bolt.DB
+	changed chan struct{}
+	now     func() time.Time
+}
+
+// Open opens or creates the schedule file at path. One-shot schedules that
+// were running when the process stopped are marked failed, as a send may
+// already have reached GREEN-API.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create scheduler dir: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open scheduler: %w", err)
+	}
+
+	store := &Store{db: db, changed: make(chan struct{}, 1), now: time.Now}
+	if err := store.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) init() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(schedulesBucket)
+		if err != nil {
+			return fmt.Errorf("create schedules bucket: %w", err)
+		}
+		if _, err := tx.CreateBucketIfNotExists(dueBucket); err != nil {
+			return fmt.Errorf("create due bucket: %w", err)
+		}
+
+		var interrupted []Schedule
+		err = bucket.ForEach(func(_, value []byte) error {
+			var sched Schedule
+			if err := json.Unmarshal(value, &sched); err != nil {
+				return fmt.Errorf("decode schedule: %w", err)
+			}
+			if sched.Status == StatusRunning {
+				interrupted = append(interrupted, sched)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, sched := range interrupted {
+			sched.Status = StatusFailed
+			sched.UpdatedAt = s.now().UTC()
+			sched.LastError = &model.APIError{
+				Code:    "delivery_unknown",
+				Message: "service stopped while the schedule was running; it may or may not have been sent",
+			}
+			if err := putSchedule(tx, sched); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Create validates spec and stores a new active schedule.
+func (s *Store) Create(owner, kind string, payload any, spec Spec) (Schedule, error) {
+	now := s.now().UTC()
+	next, err := spec.Validate(now)
+	if err != nil {
+		return Schedule{}, err
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("encode schedule payload: %w", err)
+	}
+
+	sched := Schedule{
+		ID:        uuid.NewString(),
+		Owner:     owner,
+		Kind:      kind,
+		Payload:   encoded,
+		Spec:      spec,
+		Status:    StatusActive,
+		NextRunAt: &next,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := putSchedule(tx, sched); err != nil {
+			return err
+		}
+		return tx.Bucket(dueBucket).Put(dueKey(next, sched.ID), []byte(sched.ID))
+	})
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	s.signal()
+	return sched, nil
+}
+
+func (s *Store) Get(id string) (Schedule, error) {
+	var sched Schedule
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		sched, err = getSchedule(tx, id)
+		return err
+	})
+	return sched, err
+}
+
+// List returns the schedules of owner, oldest first.
+func (s *Store) List(owner string) ([]Schedule, error) {
+	schedules := []Schedule{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).ForEach(func(_, value []byte) error {
+			var sched Schedule
+			if err := json.Unmarshal(value, &sched); err != nil {
+				return fmt.Errorf("decode schedule: %w", err)
+			}
+			if sched.Owner == owner {
+				schedules = append(schedules, sched)
+			}
+			return nil
+		})
+	})
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].CreatedAt.Before(schedules[j].CreatedAt) })
+	return schedules, err
+}
+
+// Cancel stops a schedule from running again.
+func (s *Store) Cancel(id string) (Schedule, error) {
+	return s.update(id, func(tx *bolt.Tx, sched *Schedule) error {
+		if sched.Status == StatusRunning {
+			return ErrConflict
+		}
+		if err := unindex(tx, *sched); err != nil {
+			return err
+		}
+		sched.Status = StatusCancelled
+		sched.NextRunAt = nil
+		return nil
+	})
+}
+
+// Reschedule replaces the spec of a schedule that is not running or
+// cancelled and makes it active again.
+func (s *Store) Reschedule(id string, spec Spec) (Schedule, error) {
+	next, err := spec.Validate(s.now().UTC())
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	sched, err := s.update(id, func(tx *bolt.Tx, sched *Schedule) error {
+		if sched.Status == StatusRunning || sched.Status == StatusCancelled {
+			return ErrConflict
+		}
+		if err := unindex(tx, *sched); err != nil {
+			return err
+		}
+		sched.Spec = spec
+		sched.Status = StatusActive
+		sched.NextRunAt = &next
+		return tx.Bucket(dueBucket).Put(dueKey(next, sched.ID), []byte(sched.ID))
+	})
+	if err == nil {
+		s.signal()
+	}
+	return sched, err
+}
+
+// claim takes the earliest due schedule. A one-shot schedule becomes running;
+// a cron schedule is advanced to its next run before it executes, so an
+// interrupted run is skipped rather than repeated.
+func (s *Store) claim() (Schedule, bool, error) {
+	now := s.now().UTC()
+	var sched Schedule
+	var found bool
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(dueBucket).Cursor()
+		key, id := cursor.First()
+		if key == nil || dueTime(key).After(now) {
+			return nil
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+
+		var err error
+		sched, err = getSchedule(tx, string(id))
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		sched.Runs++
+		sched.LastRunAt = &now
+		sched.UpdatedAt = now
+		if sched.Spec.OneShot() {
+			sched.Status = StatusRunning
+			sched.NextRunAt = nil
+		} else {
+			next, ok, err := sched.Spec.Next(now)
+			if err != nil || !ok {
+				sched.Status = StatusCompleted
+				sched.NextRunAt = nil
+			} else {
+				sched.NextRunAt = &next
+				if err := tx.Bucket(dueBucket).Put(dueKey(next, sched.ID), []byte(sched.ID)); err != nil {
+					return err
+				}
+			}
+		}
+		found = true
+		return putSchedule(tx, sched)
+	})
+	return sched, found, err
+}
+
+// record stores the outcome of a run.
+func (s *Store) record(id string, result json.RawMessage, apiErr *model.APIError) error {
+	_, err := s.update(id, func(_ *bolt.Tx, sched *Schedule) error {
+		sched.LastResult = result
+		sched.LastError = apiErr
+		if sched.Status != StatusRunning {
+			return nil
+		}
+		sched.Status = StatusCompleted
+		if apiErr != nil {
+			sched.Status = StatusFailed
+		}
+		return nil
+	})
+	return err
+}
+
+// retry puts a one-shot schedule whose run failed back in the due index, to
+// run again after delay. A schedule cancelled meanwhile stays cancelled.
+func (s *Store) retry(id string, apiErr *model.APIError, delay time.Duration) error {
+	_, err := s.update(id, func(tx *bolt.Tx, sched *Schedule) error {
+		sched.LastError = apiErr
+		if sched.Status != StatusRunning {
+			return nil
+		}
+		next := s.now().UTC().Add(delay)
+		sched.Status = StatusActive
+		sched.NextRunAt = &next
+		return tx.Bucket(dueBucket).Put(dueKey(next, sched.ID), []byte(sched.ID))
+	})
+	if err == nil {
+		s.signal()
+	}
+	return err
+}
+
+func (s *Store) update(id string, mutate func(tx *bolt.Tx, sched *Schedule) error) (Schedule, error) {
+	var sched Schedule
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		sched, err = getSchedule(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := mutate(tx, &sched); err != nil {
+			return err
+		}
+		sched.UpdatedAt = s.now().UTC()
+		return putSchedule(tx, sched)
+	})
+	return sched, err
+}
+
+// signal wakes the runner so that it recomputes its sleep.
+func (s *Store) signal() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func unindex(tx *bolt.Tx, sched Schedule) error {
+	if sched.NextRunAt == nil {
+		return nil
+	}
+	return tx.Bucket(dueBucket).Delete(dueKey(*sched.NextRunAt, sched.ID))
+}
+
+func getSchedule(tx *bolt.Tx, id string) (Schedule, error) {
+	value := tx.Bucket(schedulesBucket).Get([]byte(id))
+	if value == nil {
+		return Schedule{}, ErrNotFound
+	}
+	var sched Schedule
+	if err := json.Unmarshal(value, &sched); err != nil {
+		return Schedule{}, fmt.Errorf("decode schedule: %w", err)
+	}
+	return sched, nil
+}
+
+func putSchedule(tx *bolt.Tx, sched Schedule) error {
+	encoded, err := json.Marshal(sched)
+	if err != nil {
+		return fmt.Errorf("encode schedule: %w", err)
+	}
+	return tx.Bucket(schedulesBucket).Put([]byte(sched.ID), encoded)
+}
+
+// dueKey orders the due index by run time, then by schedule ID.
+func dueKey(at time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, uint64(at.UnixNano()))
+	return append(key, id...)
+}
+
+func dueTime(key []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(key[:8])))
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"green-api/internal/chatid"
+	"green-api/internal/greenapi"
+	"green-api/internal/instance"
+	"green-api/internal/model"
+)
+
+// CheckWhatsappRequest asks whether PhoneNumber has a WhatsApp account.
+type CheckWhatsappRequest struct {
+	CredentialsRequest
+	PhoneNumber string `json:"phoneNumber" validate:"required"`
+}
+
+// ContactInfoRequest asks for the contact behind ChatID.
+type ContactInfoRequest struct {
+	CredentialsRequest
+	ChatID string `json:"chatId" validate:"required"`
+}
+
+// RecipientCheck is the answer of CheckWhatsapp. Cached is set when it came
+// from the check cache rather than from GREEN-API.
+type RecipientCheck struct {
+	ChatID         string `json:"chatId"`
+	ExistsWhatsapp bool   `json:"existsWhatsapp"`
+	Cached         bool   `json:"cached"`
+}
+
+// WithWhatsappCache remembers checkWhatsapp answers per instance and phone for
+// ttl, keeping at most maxEntries of them.
+func WithWhatsappCache(ttl time.Duration, maxEntries int) Option {
+	return func(s *Service) {
+		s.whatsappCache = newWhatsappCache(ttl, maxEntries)
+	}
+}
+
+// WithRecipientCheck makes SendMessage check personal chats with
+// checkWhatsapp first and refuse numbers without WhatsApp with 422
+// recipient_not_on_whatsapp.
+func WithRecipientCheck() Option {
+	return func(s *Service) {
+		s.recipientCheck = true
+	}
+}
+
+// CheckWhatsapp reports whether req.PhoneNumber, in any notation chatid.Parse
+// accepts for personal chats, has a WhatsApp account.
+func (s *Service) CheckWhatsapp(ctx context.Context, req CheckWhatsappRequest) (result RecipientCheck, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "CheckWhatsapp")
+	defer func() { endSpan(span, apiErr) }()
+
+	if err := s.validate.Struct(req); err != nil {
+		return RecipientCheck{}, validationError(err)
+	}
+	phone, number, apiErr := parsePhoneNumber("phoneNumber", req.PhoneNumber)
+	if apiErr != nil {
+		return RecipientCheck{}, apiErr
+	}
+	creds, apiErr := s.resolveCredentials(ctx, req.CredentialsRequest)
+	if apiErr != nil {
+		return RecipientCheck{}, apiErr
+	}
+	return s.checkRecipient(ctx, creds, phone, number)
+}
+
+func (s *Service) GetContacts(ctx context.Context, req CredentialsRequest) (greenapi.Response, *model.APIError) {
+	return s.instanceCall(ctx, "GetContacts", req, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.GetContacts(ctx, creds.IDInstance, creds.APITokenInstance)
+	})
+}
+
+// GetContactInfo describes the contact of req.ChatID, normalized by
+// chatid.Parse.
+func (s *Service) GetContactInfo(ctx context.Context, req ContactInfoRequest) (greenapi.Response, *model.APIError) {
+	if err := s.validate.Struct(req); err != nil {
+		return greenapi.Response{}, validationError(err)
+	}
+	chatID, err := chatid.Parse(req.ChatID)
+	if err != nil {
+		return greenapi.Response{}, invalidInput("chatId", err.Error())
+	}
+
+	return s.instanceCall(ctx, "GetContactInfo", req.CredentialsRequest, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.GetContactInfo(ctx, creds.IDInstance, creds.APITokenInstance, chatID.String())
+	})
+}
+
+// requireWhatsapp refuses to send to a personal chat without WhatsApp. It
+// does nothing unless WithRecipientCheck is set. Nothing is sent before the
+// check, so a failed check is retryable unless GREEN-API refused it with a
+// 4xx other than 429.
+func (s *Service) requireWhatsapp(ctx context.Context, creds instance.Credentials, rawChatID string) *model.APIError {
+	if !s.recipientCheck {
+		return nil
+	}
+	chatID, err := chatid.Parse(rawChatID)
+	if err != nil || chatID.Kind() != chatid.Personal {
+		return nil
+	}
+	number, err := strconv.ParseInt(chatID.User(), 10, 64)
+	if err != nil {
+		return invalidInput("chatId", "chatId must be a phone number")
+	}
+
+	check, apiErr := s.checkRecipient(ctx, creds, chatID, number)
+	if apiErr != nil {
+		failed := *apiErr
+		failed.Retryable = apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+		return &failed
+	}
+	if !check.ExistsWhatsapp {
+		return &model.APIError{
+			StatusCode: 422,
+			Code:       "recipient_not_on_whatsapp",
+			Message:    "recipient has no WhatsApp account",
+			Details:    map[string]string{"chatId": chatID.String()},
+		}
+	}
+	return nil
+}
+
+// checkRecipient answers from the cache when it can and calls checkWhatsapp
+// otherwise. Only successful checks are cached.
+func (s *Service) checkRecipient(ctx context.Context, creds instance.Credentials, phone chatid.ID, number int64) (RecipientCheck, *model.APIError) {
+	key := creds.IDInstance + "/" + phone.User()
+	if exists, ok := s.whatsappCache.get(key); ok {
+		return RecipientCheck{ChatID: phone.String(), ExistsWhatsapp: exists, Cached: true}, nil
+	}
+
+	resp, callErr := s.client.CheckWhatsapp(ctx, creds.IDInstance, creds.APITokenInstance, number)
+	if callErr != nil {
+		return RecipientCheck{}, mapUpstreamError(callErr)
+	}
+	check, apiErr := decodeResponse[greenapi.CheckWhatsappResult](resp, nil, s.decodeMode)
+	if apiErr != nil {
+		return RecipientCheck{}, apiErr
+	}
+	s.whatsappCache.put(key, check.ExistsWhatsapp)
+	return RecipientCheck{ChatID: phone.String(), ExistsWhatsapp: check.ExistsWhatsapp}, nil
+}
+
+// parsePhoneNumber reads raw as a personal chat and returns it together with
+// its number, as GREEN-API takes phone numbers.
+func parsePhoneNumber(field, raw string) (chatid.ID, int64, *model.APIError) {
+	phone, err := chatid.Parse(raw)
+	if err != nil {
+		return chatid.ID{}, 0, invalidInput(field, err.Error())
+	}
+	if phone.Kind() != chatid.Personal {
+		return chatid.ID{}, 0, invalidInput(field, field+" must be a phone number")
+	}
+	number, err := strconv.ParseInt(phone.User(), 10, 64)
+	if err != nil {
+		return chatid.ID{}, 0, invalidInput(field, field+" must be a phone number")
+	}
+	return phone, number, nil
+}
+
+// whatsappCache remembers checkWhatsapp answers for a TTL. A nil cache
+// remembers nothing.
+type whatsappCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]whatsappEntry
+	now        func() time.Time
+}
+
+type whatsappEntry struct {
+	exists    bool
+	expiresAt time.Time
+}
+
+func newWhatsappCache(ttl time.Duration, maxEntries int) *whatsappCache {
+	return &whatsappCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]whatsappEntry),
+		now:        time.Now,
+	}
+}
+
+func (c *whatsappCache) get(key string) (exists, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, found := c.entries[key]
+	if !found || !c.now().Before(e.expiresAt) {
+		return false, false
+	}
+	return e.exists, true
+}
+
+func (c *whatsappCache) put(key string, exists bool) {
+	if c == nil || c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, found := c.entries[key]; !found && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = whatsappEntry{exists: exists, expiresAt: now.Add(c.ttl)}
+}
+
+// evict drops expired entries and, if the cache is still full, the one that
+// expires first.
+func (c *whatsappCache) evict(now time.Time) {
+	var (
+		oldestKey    string
+		oldestExpiry time.Time
+	)
+	for key, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || e.expiresAt.Before(oldestExpiry) {
+			oldestKey = key
+			oldestExpiry = e.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
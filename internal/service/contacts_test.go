@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+)
+
+// checkClient answers checkWhatsapp with exists and counts the calls.
+func checkClient(exists bool) (*mockClient, *int32) {
+	var checks int32
+	body := `{"existsWhatsapp":false}`
+	if exists {
+		body = `{"existsWhatsapp":true}`
+	}
+	return &mockClient{
+		checkFn: func(context.Context, string, string, int64) (greenapi.Response, error) {
+			atomic.AddInt32(&checks, 1)
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(body)}, nil
+		},
+	}, &checks
+}
+
+func TestCheckWhatsapp_CachesPerInstance(t *testing.T) {
+	t.Parallel()
+
+	client, checks := checkClient(true)
+	svc := New(client, WithWhatsappCache(time.Hour, 10))
+	ctx := context.Background()
+
+	check, apiErr := svc.CheckWhatsapp(ctx, CheckWhatsappRequest{CredentialsRequest: testCredentials, PhoneNumber: "+7 999 123-45-67"})
+	require.Nil(t, apiErr)
+	require.Equal(t, RecipientCheck{ChatID: "79991234567@c.us", ExistsWhatsapp: true}, check)
+
+	check, apiErr = svc.CheckWhatsapp(ctx, CheckWhatsappRequest{CredentialsRequest: testCredentials, PhoneNumber: "79991234567@c.us"})
+	require.Nil(t, apiErr)
+	require.True(t, check.Cached)
+	require.Equal(t, int32(1), atomic.LoadInt32(checks))
+
+	other := CredentialsRequest{IDInstance: "1101000002", APITokenInstance: "token"}
+	check, apiErr = svc.CheckWhatsapp(ctx, CheckWhatsappRequest{CredentialsRequest: other, PhoneNumber: "79991234567"})
+	require.Nil(t, apiErr)
+	require.False(t, check.Cached)
+	require.Equal(t, int32(2), atomic.LoadInt32(checks))
+
+	_, apiErr = svc.CheckWhatsapp(ctx, CheckWhatsappRequest{CredentialsRequest: testCredentials, PhoneNumber: "120363043968066561@g.us"})
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+}
+
+func TestSendMessage_RecipientCheck(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		chatID string
+		check  bool
+		exists bool
+		code   string
+		checks int32
+		sends  int32
+	}{
+		{name: "check off", chatID: "79991234567", sends: 1},
+		{name: "on whatsapp", chatID: "79991234567", check: true, exists: true, checks: 1, sends: 1},
+		{name: "not on whatsapp", chatID: "79991234567", check: true, code: "recipient_not_on_whatsapp", checks: 1},
+		{name: "group", chatID: "120363043968066561@g.us", check: true, sends: 1},
+	}
+	for _, tc := range cases {
+		client, checks := checkClient(tc.exists)
+		var sends int32
+		client.sendMessageFn = func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+			atomic.AddInt32(&sends, 1)
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"1"}`)}, nil
+		}
+		var opts []Option
+		if tc.check {
+			opts = append(opts, WithRecipientCheck())
+		}
+
+		_, apiErr := New(client, opts...).SendMessage(context.Background(), SendMessageRequest{CredentialsRequest: testCredentials, ChatID: tc.chatID, Message: "hi"})
+		if tc.code != "" {
+			require.NotNil(t, apiErr, tc.name)
+			require.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode, tc.name)
+			require.Equal(t, tc.code, apiErr.Code, tc.name)
+			require.Equal(t, map[string]string{"chatId": "79991234567@c.us"}, apiErr.Details, tc.name)
+		} else {
+			require.Nil(t, apiErr, tc.name)
+		}
+		require.Equal(t, tc.checks, atomic.LoadInt32(checks), tc.name)
+		require.Equal(t, tc.sends, sends, tc.name)
+	}
+}
+
+func TestSendMessage_FailedRecipientCheckRetryableUnlessRefused(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		resp      greenapi.Response
+		err       error
+		retryable bool
+	}{
+		{name: "breaker open", err: greenapi.ErrCircuitBreakerOpen, retryable: true},
+		{name: "connection reset", err: errors.New("connection reset"), retryable: true},
+		{name: "server error", resp: greenapi.Response{StatusCode: http.StatusInternalServerError, Body: []byte(`{"message":"internal"}`)}, retryable: true},
+		{name: "too many requests", resp: greenapi.Response{StatusCode: http.StatusTooManyRequests}, retryable: true},
+		{name: "invalid response", resp: greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`not json`)}, retryable: true},
+		{name: "bad credentials", resp: greenapi.Response{StatusCode: http.StatusUnauthorized}},
+		{name: "rejected", resp: greenapi.Response{StatusCode: http.StatusBadRequest, Body: []byte(`{"message":"bad phoneNumber"}`)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &mockClient{
+				checkFn: func(context.Context, string, string, int64) (greenapi.Response, error) {
+					return tc.resp, tc.err
+				},
+				sendMessageFn: func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+					t.Error("sendMessage must not be called")
+					return greenapi.Response{}, nil
+				},
+			}
+
+			_, apiErr := New(client, WithRecipientCheck()).SendMessage(context.Background(), SendMessageRequest{CredentialsRequest: testCredentials, ChatID: "79991234567", Message: "hi"})
+			require.NotNil(t, apiErr)
+			require.Equal(t, tc.retryable, apiErr.Retryable)
+		})
+	}
+}
+
+func TestWhatsappCache_ExpiresAndEvicts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	cache := newWhatsappCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", true)
+	now = now.Add(time.Second)
+	cache.put("b", false)
+	cache.put("c", true)
+	_, ok := cache.get("a")
+	require.False(t, ok, "the entry expiring first is evicted")
+	exists, ok := cache.get("b")
+	require.True(t, ok)
+	require.False(t, exists)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("c")
+	require.False(t, ok)
+
+	var disabled *whatsappCache
+	disabled.put("a", true)
+	_, ok = disabled.get("a")
+	require.False(t, ok)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"green-api/internal/chatid"
+	"green-api/internal/greenapi"
+	"green-api/internal/model"
+	"green-api/internal/queue"
+)
+
+// Kinds of stored requests run by the queue worker and the scheduler.
+const (
+	JobSendMessage   = "sendMessage"
+	JobSendFileByURL = "sendFileByUrl"
+)
+
+// WithQueue enables asynchronous sends stored in q.
+func WithQueue(q *queue.Queue) Option {
+	return func(s *Service) {
+		s.queue = q
+	}
+}
+
+// EnqueueSendMessage validates req and stores it for delivery by the queue
+// worker.
+func (s *Service) EnqueueSendMessage(ctx context.Context, owner string, req SendMessageRequest) (job queue.Job, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "EnqueueSendMessage")
+	defer func() { endSpan(span, apiErr) }()
+
+	if s.queue == nil {
+		return queue.Job{}, invalidInput("async", "asynchronous sends are not enabled")
+	}
+	stored, apiErr := s.storableSendMessage(ctx, req)
+	if apiErr != nil {
+		return queue.Job{}, apiErr
+	}
+
+	job, err := s.queue.Enqueue(owner, JobSendMessage, stored)
+	if err != nil {
+		return queue.Job{}, internalError("enqueue message", err)
+	}
+	return job, nil
+}
+
+// GetJob returns a job created by owner. Jobs of other API keys are reported
+// as missing.
+func (s *Service) GetJob(_ context.Context, owner, id string) (queue.Job, *model.APIError) {
+	if s.queue == nil {
+		return queue.Job{}, jobNotFound(id)
+	}
+
+	job, err := s.queue.Get(id)
+	if errors.Is(err, queue.ErrNotFound) || (err == nil && job.Owner != owner) {
+		return queue.Job{}, jobNotFound(id)
+	}
+	if err != nil {
+		return queue.Job{}, internalError("get job", err)
+	}
+	return job, nil
+}
+
+// ProcessJob implements queue.Processor.
+func (s *Service) ProcessJob(ctx context.Context, job queue.Job) (result json.RawMessage, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "ProcessJob", AttributeJobID.String(job.ID))
+	defer func() { endSpan(span, apiErr) }()
+
+	return s.execute(ctx, job.Kind, job.Payload)
+}
+
+// storableSendMessage validates req and returns the form that is written to
+// disk. Only registered instances are accepted: stored requests must not
+// contain apiTokenInstance, so credentials are resolved again when they run.
+func (s *Service) storableSendMessage(ctx context.Context, req SendMessageRequest) (SendMessageRequest, *model.APIError) {
+	if err := s.validate.Struct(req); err != nil {
+		return SendMessageRequest{}, validationError(err)
+	}
+	if apiErr := s.requireRegisteredInstance(ctx, req.CredentialsRequest); apiErr != nil {
+		return SendMessageRequest{}, apiErr
+	}
+
+	params, apiErr := messageParams(req)
+	if apiErr != nil {
+		return SendMessageRequest{}, apiErr
+	}
+	return SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: strings.TrimSpace(req.Instance)},
+		ChatID:             params.ChatID,
+		Message:            params.Message,
+		QuotedMessageID:    params.QuotedMessageID,
+		LinkPreview:        params.LinkPreview,
+		Mentions:           params.Mentions,
+	}, nil
+}
+
+func (s *Service) storableSendFileByURL(ctx context.Context, req SendFileByURLRequest) (SendFileByURLRequest, *model.APIError) {
+	if err := s.validate.Struct(req); err != nil {
+		return SendFileByURLRequest{}, validationError(err)
+	}
+	if apiErr := s.requireRegisteredInstance(ctx, req.CredentialsRequest); apiErr != nil {
+		return SendFileByURLRequest{}, apiErr
+	}
+
+	chatID, err := chatid.Parse(req.ChatID)
+	if err != nil {
+		return SendFileByURLRequest{}, invalidInput("chatId", err.Error())
+	}
+	urlFile := strings.TrimSpace(req.URLFile)
+	if err := validateURLFile(urlFile); err != nil {
+		return SendFileByURLRequest{}, invalidInput("urlFile", err.Error())
+	}
+	// With the pre-flight check the fileName is derived when the file is sent.
+	if s.preflight == nil {
+		if _, err := ExtractFileName(urlFile); err != nil {
+			return SendFileByURLRequest{}, invalidInput("urlFile", err.Error())
+		}
+	}
+	return SendFileByURLRequest{
+		CredentialsRequest: CredentialsRequest{Instance: strings.TrimSpace(req.Instance)},
+		ChatID:             chatID.String(),
+		URLFile:            urlFile,
+	}, nil
+}
+
+func (s *Service) requireRegisteredInstance(ctx context.Context, req CredentialsRequest) *model.APIError {
+	if strings.TrimSpace(req.Instance) == "" {
+		return invalidInput("instance", "stored requests require a registered instance name")
+	}
+	_, apiErr := s.resolveCredentials(ctx, req)
+	return apiErr
+}
+
+// execute runs a stored request of the given kind.
+func (s *Service) execute(ctx context.Context, kind string, payload json.RawMessage) (json.RawMessage, *model.APIError) {
+	var resp greenapi.Response
+	var apiErr *model.APIError
+
+	switch kind {
+	case JobSendMessage:
+		var req SendMessageRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, internalError("decode stored request", err)
+		}
+		resp, apiErr = s.SendMessage(ctx, req)
+	case JobSendFileByURL:
+		var req SendFileByURLRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, internalError("decode stored request", err)
+		}
+		resp, apiErr = s.SendFileByURL(ctx, req)
+	default:
+		return nil, internalError("run stored request", fmt.Errorf("unknown kind %q", kind))
+	}
+
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, upstreamStatusError(resp.StatusCode)
+	}
+	return resultBody(resp.Body), nil
+}
+
+// resultBody is what a stored request keeps of a successful response. A body
+// that is not JSON, such as an empty one, is kept as a JSON string so the job
+// or schedule holding it can still be saved.
+func resultBody(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// upstreamStatusError reports a non-2xx GREEN-API response to a stored send.
+// Only 429 is retried: GREEN-API rejected the call without processing it.
+func upstreamStatusError(statusCode int) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusBadGateway,
+		Code:       "upstream_error",
+		Message:    fmt.Sprintf("green-api responded with status %d", statusCode),
+		Details:    map[string]int{"status": statusCode},
+		Retryable:  statusCode == http.StatusTooManyRequests,
+	}
+}
+
+func jobNotFound(id string) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusNotFound,
+		Code:       "job_not_found",
+		Message:    fmt.Sprintf("job %q not found", id),
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+	"green-api/internal/greenapi"
+	"green-api/internal/queue"
+)
+
+func testQueue(t *testing.T) *queue.Queue {
+	t.Helper()
+
+	q, err := queue.Open(filepath.Join(t.TempDir(), "queue.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestEnqueueSendMessage_StoresNoToken(t *testing.T) {
+	t.Parallel()
+
+	q := testQueue(t)
+	svc := New(&mockClient{}, WithInstanceResolver(testRegistry(t)), WithQueue(q))
+
+	job, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		ChatID:             "77771234567",
+		Message:            " hi ",
+	})
+	require.Nil(t, apiErr)
+	require.Equal(t, queue.StatusQueued, job.Status)
+	require.JSONEq(t, `{"instance":"sales","chatId":"77771234567@c.us","message":"hi"}`, string(job.Payload))
+	require.NotContains(t, string(job.Payload), "server-token")
+
+	_, apiErr = svc.GetJob(context.Background(), "key-2", job.ID)
+	require.Equal(t, "job_not_found", apiErr.Code, "jobs are private to the API key that created them")
+}
+
+func TestEnqueueSendMessage_KeepsMessageOptions(t *testing.T) {
+	t.Parallel()
+
+	var sent greenapi.SendMessageParams
+	client := &mockClient{
+		sendMessageFn: func(_ context.Context, _, _ string, params greenapi.SendMessageParams) (greenapi.Response, error) {
+			sent = params
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"BAE5"}`)}, nil
+		},
+	}
+	svc := New(client, WithInstanceResolver(testRegistry(t)), WithQueue(testQueue(t)))
+
+	noPreview := false
+	job, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		ChatID:             "120363043968066561@g.us",
+		Message:            "@79991234567 hi",
+		QuotedMessageID:    "BAE5F4886F6F2D05",
+		LinkPreview:        &noPreview,
+		Mentions:           []string{"+7 999 123 45 67"},
+	})
+	require.Nil(t, apiErr)
+	require.JSONEq(t, `{
+		"instance":"sales",
+		"chatId":"120363043968066561@g.us",
+		"message":"@79991234567 hi",
+		"quotedMessageId":"BAE5F4886F6F2D05",
+		"linkPreview":false,
+		"mentions":["79991234567@c.us"]
+	}`, string(job.Payload))
+
+	_, apiErr = svc.ProcessJob(context.Background(), job)
+	require.Nil(t, apiErr)
+	require.Equal(t, []string{"79991234567@c.us"}, sent.Mentions)
+	require.Equal(t, "BAE5F4886F6F2D05", sent.QuotedMessageID)
+	require.False(t, *sent.LinkPreview)
+}
+
+func TestEnqueueSendMessage_RejectsRawCredentials(t *testing.T) {
+	t.Parallel()
+
+	svc := New(&mockClient{}, WithQueue(testQueue(t)))
+
+	_, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"},
+		ChatID:             "77771234567",
+		Message:            "hi",
+	})
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	_, apiErr = New(&mockClient{}).EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{})
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode, "queue is not configured")
+}
+
+func TestProcessJob_RetriesOnlyUnprocessedSends(t *testing.T) {
+	t.Parallel()
+
+	status := http.StatusTooManyRequests
+	client := &mockClient{
+		sendMessageFn: func(_ context.Context, _, apiTokenInstance string, _ greenapi.SendMessageParams) (greenapi.Response, error) {
+			require.Equal(t, "server-token", apiTokenInstance)
+			return greenapi.Response{StatusCode: status, Body: []byte(`{"idMessage":"BAE5"}`)}, nil
+		},
+	}
+	q := testQueue(t)
+	svc := New(client, WithInstanceResolver(testRegistry(t)), WithQueue(q))
+	job, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		ChatID:             "77771234567",
+		Message:            "hi",
+	})
+	require.Nil(t, apiErr)
+
+	_, apiErr = svc.ProcessJob(context.Background(), job)
+	require.True(t, apiErr.Retryable)
+
+	status = http.StatusInternalServerError
+	_, apiErr = svc.ProcessJob(context.Background(), job)
+	require.False(t, apiErr.Retryable, "a 5xx send may have been processed")
+
+	status = http.StatusOK
+	result, apiErr := svc.ProcessJob(context.Background(), job)
+	require.Nil(t, apiErr)
+	require.JSONEq(t, `{"idMessage":"BAE5"}`, string(result))
+}
+
+func TestProcessJob_KeepsNonJSONResultAsString(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "json", body: `{"idMessage":"BAE5"}`, want: `{"idMessage":"BAE5"}`},
+		{name: "empty", body: "", want: `""`},
+		{name: "text", body: "OK", want: `"OK"`},
+	}
+	for _, tc := range cases {
+		client := &mockClient{
+			sendMessageFn: func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+				return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(tc.body)}, nil
+			},
+		}
+		q := testQueue(t)
+		svc := New(client, WithInstanceResolver(testRegistry(t)), WithQueue(q))
+		job, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+			CredentialsRequest: CredentialsRequest{Instance: "sales"},
+			ChatID:             "77771234567",
+			Message:            "hi",
+		})
+		require.Nil(t, apiErr, tc.name)
+
+		result, apiErr := svc.ProcessJob(context.Background(), job)
+		require.Nil(t, apiErr, tc.name)
+		require.JSONEq(t, tc.want, string(result), tc.name)
+	}
+}
+
+func TestWorker_RetriesJobAfterFailedRecipientCheck(t *testing.T) {
+	t.Parallel()
+
+	var checks, sends int32
+	client := &mockClient{
+		checkFn: func(context.Context, string, string, int64) (greenapi.Response, error) {
+			if atomic.AddInt32(&checks, 1) == 1 {
+				return greenapi.Response{StatusCode: http.StatusInternalServerError, Body: []byte(`{"message":"internal"}`)}, nil
+			}
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"existsWhatsapp":true}`)}, nil
+		},
+		sendMessageFn: func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+			atomic.AddInt32(&sends, 1)
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"BAE5"}`)}, nil
+		},
+	}
+	q := testQueue(t)
+	svc := New(client, WithInstanceResolver(testRegistry(t)), WithQueue(q), WithRecipientCheck())
+	job, apiErr := svc.EnqueueSendMessage(context.Background(), "key-1", SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		ChatID:             "77771234567",
+		Message:            "hi",
+	})
+	require.Nil(t, apiErr)
+
+	worker := queue.NewWorker(q, svc, config.QueueConfig{Workers: 1, BackoffSeconds: 1, MaxBackoffSeconds: 1, PollIntervalSeconds: 1}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		stored, err := q.Get(job.ID)
+		return err == nil && stored.Status == queue.StatusSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	stored, err := q.Get(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, stored.Attempts)
+	require.EqualValues(t, 2, atomic.LoadInt32(&checks))
+	require.EqualValues(t, 1, atomic.LoadInt32(&sends))
+}
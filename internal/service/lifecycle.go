@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/instance"
+	"green-api/internal/model"
+)
+
+const (
+	defaultStatePollInterval = 2 * time.Second
+	defaultAuthorizedWait    = 10 * time.Second
+)
+
+// AuthorizationCodeRequest asks for a code that links the phone PhoneNumber
+// to the instance without scanning a QR code.
+type AuthorizationCodeRequest struct {
+	CredentialsRequest
+	PhoneNumber string `json:"phoneNumber" validate:"required"`
+}
+
+// WaitAuthorizedRequest waits until the instance is authorized, for at most
+// TimeoutSeconds (10 by default).
+type WaitAuthorizedRequest struct {
+	CredentialsRequest
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" validate:"omitempty,min=1,max=120"`
+}
+
+// Wait is how long WaitAuthorized polls for r.
+func (r WaitAuthorizedRequest) Wait() time.Duration {
+	if r.TimeoutSeconds > 0 {
+		return time.Duration(r.TimeoutSeconds) * time.Second
+	}
+	return defaultAuthorizedWait
+}
+
+// QRCode is a decoded qr response. Raw is the message as GREEN-API sent it:
+// the base64 image for greenapi.QRCode, an explanation otherwise. PNG is only
+// set for greenapi.QRCode.
+type QRCode struct {
+	Type greenapi.QRType `json:"type"`
+	Raw  string          `json:"message"`
+	PNG  []byte          `json:"-"`
+}
+
+// WithStatePollInterval sets how often WaitAuthorized calls getStateInstance.
+// The default is 2s.
+func WithStatePollInterval(interval time.Duration) Option {
+	return func(s *Service) {
+		s.statePollInterval = interval
+	}
+}
+
+func (s *Service) Reboot(ctx context.Context, req CredentialsRequest) (greenapi.Response, *model.APIError) {
+	return s.instanceCall(ctx, "Reboot", req, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.Reboot(ctx, creds.IDInstance, creds.APITokenInstance)
+	})
+}
+
+func (s *Service) Logout(ctx context.Context, req CredentialsRequest) (greenapi.Response, *model.APIError) {
+	return s.instanceCall(ctx, "Logout", req, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.Logout(ctx, creds.IDInstance, creds.APITokenInstance)
+	})
+}
+
+// QR returns the current QR code of the instance with its image decoded.
+func (s *Service) QR(ctx context.Context, req CredentialsRequest) (QRCode, *model.APIError) {
+	resp, apiErr := s.instanceCall(ctx, "QR", req, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.QR(ctx, creds.IDInstance, creds.APITokenInstance)
+	})
+	qr, apiErr := decodeResponse[greenapi.QR](resp, apiErr, s.decodeMode)
+	if apiErr != nil {
+		return QRCode{}, apiErr
+	}
+
+	code := QRCode{Type: qr.Type, Raw: qr.Message}
+	if qr.Type == greenapi.QRCode {
+		image, err := qr.PNG()
+		if err != nil {
+			return QRCode{}, &model.APIError{
+				StatusCode: 502,
+				Code:       "upstream_invalid_response",
+				Message:    err.Error(),
+			}
+		}
+		code.PNG = image
+	}
+	return code, nil
+}
+
+// GetAuthorizationCode requests a pairing code for req.PhoneNumber, which may
+// be written in any notation chatid.Parse accepts for personal chats.
+func (s *Service) GetAuthorizationCode(ctx context.Context, req AuthorizationCodeRequest) (greenapi.Response, *model.APIError) {
+	if err := s.validate.Struct(req); err != nil {
+		return greenapi.Response{}, validationError(err)
+	}
+	_, number, apiErr := parsePhoneNumber("phoneNumber", req.PhoneNumber)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
+	}
+
+	return s.instanceCall(ctx, "GetAuthorizationCode", req.CredentialsRequest, func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error) {
+		return s.client.GetAuthorizationCode(ctx, creds.IDInstance, creds.APITokenInstance, number)
+	})
+}
+
+// WaitAuthorized polls getStateInstance until the instance is authorized, for
+// example after the QR code was scanned. A blocked instance ends the wait at
+// once with 409 instance_blocked; running out of time, or ctx reaching its
+// deadline, ends it with 504 authorization_timeout, and a cancelled ctx with
+// 499 request_canceled. All of them report the last stateInstance in Details.
+// Neither end of a wait is an upstream failure.
+func (s *Service) WaitAuthorized(ctx context.Context, req WaitAuthorizedRequest) (greenapi.State, *model.APIError) {
+	if err := s.validate.Struct(req); err != nil {
+		return greenapi.State{}, validationError(err)
+	}
+	wait := req.Wait()
+	interval := s.statePollInterval
+	if interval <= 0 {
+		interval = defaultStatePollInterval
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last greenapi.State
+	for {
+		state, apiErr := s.GetStateTyped(ctx, req.CredentialsRequest)
+		if apiErr != nil {
+			if ctx.Err() != nil {
+				return last, waitEnded(ctx, wait, last)
+			}
+			return greenapi.State{}, apiErr
+		}
+		last = state
+		switch state.StateInstance {
+		case greenapi.StateAuthorized:
+			return state, nil
+		case greenapi.StateBlocked:
+			return state, &model.APIError{
+				StatusCode: 409,
+				Code:       "instance_blocked",
+				Message:    "instance is blocked and cannot be authorized",
+				Details:    map[string]any{"stateInstance": state.StateInstance},
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return state, waitEnded(ctx, wait, state)
+		case <-ctx.Done():
+			return state, waitEnded(ctx, wait, state)
+		}
+	}
+}
+
+// waitEnded reports a WaitAuthorized that ran out of time or whose caller
+// went away, with the last state observed, if any.
+func waitEnded(ctx context.Context, wait time.Duration, last greenapi.State) *model.APIError {
+	var details map[string]any
+	if last.StateInstance != "" {
+		details = map[string]any{"stateInstance": last.StateInstance}
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return &model.APIError{
+			StatusCode: 499,
+			Code:       "request_canceled",
+			Message:    "request was canceled while waiting for authorization",
+			Details:    details,
+		}
+	}
+	return &model.APIError{
+		StatusCode: 504,
+		Code:       "authorization_timeout",
+		Message:    fmt.Sprintf("instance was not authorized within %s", wait),
+		Details:    details,
+		Retryable:  true,
+	}
+}
+
+// instanceCall resolves the credentials of req and makes one traced
+// GREEN-API call with them.
+func (s *Service) instanceCall(
+	ctx context.Context,
+	name string,
+	req CredentialsRequest,
+	call func(ctx context.Context, creds instance.Credentials) (greenapi.Response, error),
+) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, name)
+	defer func() { endSpan(span, apiErr) }()
+
+	creds, apiErr := s.resolveCredentials(ctx, req)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
+	}
+
+	resp, callErr := call(ctx, creds)
+	if callErr != nil {
+		return greenapi.Response{}, mapUpstreamError(callErr)
+	}
+	return resp, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+)
+
+var testCredentials = CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"}
+
+func TestQR_DecodesImage(t *testing.T) {
+	t.Parallel()
+
+	const png = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAAAAAA6fptVAAAACklEQVR4nGNgAAAAAgABSK+kcQAAAABJRU5ErkJggg=="
+	cases := []struct {
+		name   string
+		body   string
+		image  bool
+		status int
+	}{
+		{name: "qr code", body: `{"type":"qrCode","message":"` + png + `"}`, image: true},
+		{name: "already logged", body: `{"type":"alreadyLogged","message":"instance account already authorized"}`},
+		{name: "broken image", body: `{"type":"qrCode","message":"!!"}`, status: http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		client := &mockClient{
+			qrFn: func(context.Context, string, string) (greenapi.Response, error) {
+				return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(tc.body)}, nil
+			},
+		}
+
+		code, apiErr := New(client).QR(context.Background(), testCredentials)
+		if tc.status != 0 {
+			require.NotNil(t, apiErr, tc.name)
+			require.Equal(t, tc.status, apiErr.StatusCode, tc.name)
+			require.Equal(t, "upstream_invalid_response", apiErr.Code, tc.name)
+			continue
+		}
+		require.Nil(t, apiErr, tc.name)
+		require.NotEmpty(t, code.Raw, tc.name)
+		require.Equal(t, tc.image, len(code.PNG) > 0, tc.name)
+	}
+}
+
+func TestGetAuthorizationCode_NormalizesPhoneNumber(t *testing.T) {
+	t.Parallel()
+
+	var sent int64
+	client := &mockClient{
+		authCodeFn: func(_ context.Context, _, _ string, phoneNumber int64) (greenapi.Response, error) {
+			sent = phoneNumber
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"status":true,"code":"WZGRHKQ9"}`)}, nil
+		},
+	}
+	svc := New(client)
+
+	_, apiErr := svc.GetAuthorizationCode(context.Background(), AuthorizationCodeRequest{CredentialsRequest: testCredentials, PhoneNumber: "+7 (999) 123-45-67"})
+	require.Nil(t, apiErr)
+	require.Equal(t, int64(79991234567), sent)
+
+	for _, phone := range []string{"", "120363043968066561@g.us", "call me"} {
+		_, apiErr = svc.GetAuthorizationCode(context.Background(), AuthorizationCodeRequest{CredentialsRequest: testCredentials, PhoneNumber: phone})
+		require.NotNil(t, apiErr, phone)
+		require.Equal(t, http.StatusBadRequest, apiErr.StatusCode, phone)
+	}
+}
+
+func TestWaitAuthorized(t *testing.T) {
+	t.Parallel()
+
+	stateClient := func(states ...string) (*mockClient, *int32) {
+		var calls int32
+		return &mockClient{
+			stateFn: func(context.Context, string, string) (greenapi.Response, error) {
+				call := int(atomic.AddInt32(&calls, 1)) - 1
+				state := states[min(call, len(states)-1)]
+				return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"stateInstance":"` + state + `"}`)}, nil
+			},
+		}, &calls
+	}
+
+	client, calls := stateClient("notAuthorized", "starting", "authorized")
+	state, apiErr := New(client, WithStatePollInterval(time.Millisecond)).WaitAuthorized(context.Background(), WaitAuthorizedRequest{CredentialsRequest: testCredentials})
+	require.Nil(t, apiErr)
+	require.Equal(t, greenapi.StateAuthorized, state.StateInstance)
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	client, _ = stateClient("notAuthorized")
+	_, apiErr = New(client, WithStatePollInterval(10*time.Millisecond)).WaitAuthorized(context.Background(), WaitAuthorizedRequest{CredentialsRequest: testCredentials, TimeoutSeconds: 1})
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusGatewayTimeout, apiErr.StatusCode)
+	require.Equal(t, "authorization_timeout", apiErr.Code)
+	require.Equal(t, map[string]any{"stateInstance": greenapi.StateNotAuthorized}, apiErr.Details)
+
+	client, calls = stateClient("blocked")
+	_, apiErr = New(client, WithStatePollInterval(time.Millisecond)).WaitAuthorized(context.Background(), WaitAuthorizedRequest{CredentialsRequest: testCredentials})
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	_, apiErr = New(client).WaitAuthorized(context.Background(), WaitAuthorizedRequest{CredentialsRequest: testCredentials, TimeoutSeconds: 1000})
+	require.NotNil(t, apiErr)
+	require.Equal(t, "validation_error", apiErr.Code)
+}
+
+func TestWaitAuthorized_EndedByCaller(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		cancel bool
+		status int
+		code   string
+	}{
+		{name: "deadline", status: http.StatusGatewayTimeout, code: "authorization_timeout"},
+		{name: "canceled", cancel: true, status: 499, code: "request_canceled"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			var calls int32
+			client := &mockClient{
+				stateFn: func(ctx context.Context, _, _ string) (greenapi.Response, error) {
+					if atomic.AddInt32(&calls, 1) == 1 {
+						return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"stateInstance":"notAuthorized"}`)}, nil
+					}
+					// The next poll is in flight when the caller goes away.
+					if tc.cancel {
+						cancel()
+					}
+					<-ctx.Done()
+					return greenapi.Response{}, ctx.Err()
+				},
+			}
+
+			_, apiErr := New(client, WithStatePollInterval(time.Millisecond)).WaitAuthorized(ctx, WaitAuthorizedRequest{CredentialsRequest: testCredentials})
+			require.NotNil(t, apiErr)
+			require.Equal(t, tc.status, apiErr.StatusCode)
+			require.Equal(t, tc.code, apiErr.Code)
+			require.Equal(t, map[string]any{"stateInstance": greenapi.StateNotAuthorized}, apiErr.Details)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"green-api/internal/model"
+	"green-api/internal/urlcheck"
+)
+
+// URLChecker inspects urlFile before it is handed to GREEN-API.
+type URLChecker interface {
+	Check(ctx context.Context, rawURL string) (urlcheck.Result, error)
+}
+
+// WithURLPreflight checks every urlFile with checker before sending it. The
+// file must fit the upload limits, and its fileName is taken from the response
+// headers when the URL path has no usable name.
+func WithURLPreflight(checker URLChecker) Option {
+	return func(s *Service) {
+		s.preflight = checker
+	}
+}
+
+// urlFileName returns the fileName sent along with urlFile.
+func (s *Service) urlFileName(ctx context.Context, urlFile string) (string, *model.APIError) {
+	if s.preflight == nil {
+		fileName, err := ExtractFileName(urlFile)
+		if err != nil {
+			return "", invalidInput("urlFile", err.Error())
+		}
+		return fileName, nil
+	}
+
+	result, err := s.preflight.Check(ctx, urlFile)
+	var checkErr *urlcheck.Error
+	if errors.As(err, &checkErr) {
+		return "", invalidInput("urlFile", checkErr.Message)
+	}
+	if err != nil {
+		return "", internalError("check urlFile", err)
+	}
+
+	if s.maxUploadBytes > 0 && result.ContentLength > s.maxUploadBytes {
+		return "", fileTooLarge(s.maxUploadBytes)
+	}
+	if !s.allowsType(result.ContentType) {
+		return "", unsupportedMediaType(result.ContentType)
+	}
+	if result.FileName == "" {
+		return "", invalidInput("urlFile", "cannot derive fileName from urlFile")
+	}
+	return result.FileName, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/urlcheck"
+)
+
+type stubChecker struct {
+	result urlcheck.Result
+	err    error
+}
+
+func (c stubChecker) Check(context.Context, string) (urlcheck.Result, error) {
+	return c.result, c.err
+}
+
+func fileByURLRequest(urlFile string) SendFileByURLRequest {
+	return SendFileByURLRequest{
+		CredentialsRequest: CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"},
+		ChatID:             "77771234567",
+		URLFile:            urlFile,
+	}
+}
+
+func TestSendFileByURL_PreflightDerivesFileName(t *testing.T) {
+	t.Parallel()
+
+	var sentName string
+	client := &mockClient{
+		sendFileByURLFn: func(_ context.Context, _, _, _, _, fileName string) (greenapi.Response, error) {
+			sentName = fileName
+			return greenapi.Response{StatusCode: http.StatusOK}, nil
+		},
+	}
+	checker := stubChecker{result: urlcheck.Result{ContentType: "application/pdf", ContentLength: 1024, FileName: "report.pdf"}}
+	svc := New(client, WithURLPreflight(checker), WithUploadLimits(2048, []string{"application/pdf"}))
+
+	_, apiErr := svc.SendFileByURL(context.Background(), fileByURLRequest("https://example.com/download?id=7"))
+	require.Nil(t, apiErr)
+	require.Equal(t, "report.pdf", sentName)
+}
+
+func TestSendFileByURL_PreflightRejections(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		checker stubChecker
+		status  int
+		code    string
+	}{
+		{name: "private address", checker: stubChecker{err: &urlcheck.Error{Message: "urlFile must not point to a private or local address"}}, status: http.StatusBadRequest, code: "validation_error"},
+		{name: "too large", checker: stubChecker{result: urlcheck.Result{ContentType: "image/png", ContentLength: 4096, FileName: "a.png"}}, status: http.StatusRequestEntityTooLarge, code: "file_too_large"},
+		{name: "type not allowed", checker: stubChecker{result: urlcheck.Result{ContentType: "text/html", ContentLength: 10, FileName: "a.html"}}, status: http.StatusUnsupportedMediaType, code: "unsupported_media_type"},
+		{name: "type missing", checker: stubChecker{result: urlcheck.Result{ContentLength: 10, FileName: "a.png"}}, status: http.StatusUnsupportedMediaType, code: "unsupported_media_type"},
+	}
+
+	for _, tc := range cases {
+		called := false
+		client := &mockClient{
+			sendFileByURLFn: func(context.Context, string, string, string, string, string) (greenapi.Response, error) {
+				called = true
+				return greenapi.Response{}, nil
+			},
+		}
+		svc := New(client, WithURLPreflight(tc.checker), WithUploadLimits(2048, []string{"image/"}))
+
+		_, apiErr := svc.SendFileByURL(context.Background(), fileByURLRequest("https://example.com/a.png"))
+		require.NotNil(t, apiErr, tc.name)
+		require.Equal(t, tc.status, apiErr.StatusCode, tc.name)
+		require.Equal(t, tc.code, apiErr.Code, tc.name)
+		require.False(t, called, tc.name)
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"green-api/internal/model"
+	"green-api/internal/scheduler"
+)
+
+// WithScheduler enables sendAt/cron requests stored in store.
+func WithScheduler(store *scheduler.Store) Option {
+	return func(s *Service) {
+		s.schedules = store
+	}
+}
+
+// ScheduleSendMessage stores req to be sent at the times given by req.Spec.
+func (s *Service) ScheduleSendMessage(ctx context.Context, owner string, req SendMessageRequest) (sched scheduler.Schedule, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "ScheduleSendMessage")
+	defer func() { endSpan(span, apiErr) }()
+
+	if apiErr := s.requireScheduler(req.Spec); apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	stored, apiErr := s.storableSendMessage(ctx, req)
+	if apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	return s.createSchedule(owner, JobSendMessage, stored, *req.Spec)
+}
+
+// ScheduleSendFileByURL stores req to be sent at the times given by req.Spec.
+func (s *Service) ScheduleSendFileByURL(ctx context.Context, owner string, req SendFileByURLRequest) (sched scheduler.Schedule, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "ScheduleSendFileByURL")
+	defer func() { endSpan(span, apiErr) }()
+
+	if apiErr := s.requireScheduler(req.Spec); apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	stored, apiErr := s.storableSendFileByURL(ctx, req)
+	if apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	return s.createSchedule(owner, JobSendFileByURL, stored, *req.Spec)
+}
+
+func (s *Service) ListSchedules(_ context.Context, owner string) ([]scheduler.Schedule, *model.APIError) {
+	if s.schedules == nil {
+		return []scheduler.Schedule{}, nil
+	}
+	schedules, err := s.schedules.List(owner)
+	if err != nil {
+		return nil, internalError("list schedules", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a schedule created by owner. Schedules of other API
+// keys are reported as missing.
+func (s *Service) GetSchedule(_ context.Context, owner, id string) (scheduler.Schedule, *model.APIError) {
+	if s.schedules == nil {
+		return scheduler.Schedule{}, scheduleNotFound(id)
+	}
+	sched, err := s.schedules.Get(id)
+	if errors.Is(err, scheduler.ErrNotFound) || (err == nil && sched.Owner != owner) {
+		return scheduler.Schedule{}, scheduleNotFound(id)
+	}
+	if err != nil {
+		return scheduler.Schedule{}, internalError("get schedule", err)
+	}
+	return sched, nil
+}
+
+func (s *Service) CancelSchedule(ctx context.Context, owner, id string) (scheduler.Schedule, *model.APIError) {
+	if _, apiErr := s.GetSchedule(ctx, owner, id); apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	sched, err := s.schedules.Cancel(id)
+	if err != nil {
+		return scheduler.Schedule{}, scheduleError("cancel schedule", id, err)
+	}
+	return sched, nil
+}
+
+// Reschedule replaces the times at which a schedule runs.
+func (s *Service) Reschedule(ctx context.Context, owner, id string, spec scheduler.Spec) (scheduler.Schedule, *model.APIError) {
+	if _, apiErr := s.GetSchedule(ctx, owner, id); apiErr != nil {
+		return scheduler.Schedule{}, apiErr
+	}
+	if _, err := spec.Validate(s.now()); err != nil {
+		return scheduler.Schedule{}, invalidInput("schedule", err.Error())
+	}
+	sched, err := s.schedules.Reschedule(id, spec)
+	if err != nil {
+		return scheduler.Schedule{}, scheduleError("reschedule", id, err)
+	}
+	return sched, nil
+}
+
+// ExecuteSchedule implements scheduler.Executor.
+func (s *Service) ExecuteSchedule(ctx context.Context, sched scheduler.Schedule) (result json.RawMessage, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "ExecuteSchedule", AttributeScheduleID.String(sched.ID))
+	defer func() { endSpan(span, apiErr) }()
+
+	return s.execute(ctx, sched.Kind, sched.Payload)
+}
+
+func (s *Service) requireScheduler(spec *scheduler.Spec) *model.APIError {
+	if s.schedules == nil {
+		return invalidInput("schedule", "scheduled sends are not enabled")
+	}
+	if _, err := spec.Validate(s.now()); err != nil {
+		return invalidInput("schedule", err.Error())
+	}
+	return nil
+}
+
+func (s *Service) createSchedule(owner, kind string, payload any, spec scheduler.Spec) (scheduler.Schedule, *model.APIError) {
+	sched, err := s.schedules.Create(owner, kind, payload, spec)
+	if err != nil {
+		return scheduler.Schedule{}, internalError("create schedule", err)
+	}
+	return sched, nil
+}
+
+func scheduleError(operation, id string, err error) *model.APIError {
+	switch {
+	case errors.Is(err, scheduler.ErrNotFound):
+		return scheduleNotFound(id)
+	case errors.Is(err, scheduler.ErrConflict):
+		return &model.APIError{
+			StatusCode: http.StatusConflict,
+			Code:       "schedule_conflict",
+			Message:    err.Error(),
+		}
+	default:
+		return internalError(operation, err)
+	}
+}
+
+func scheduleNotFound(id string) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusNotFound,
+		Code:       "schedule_not_found",
+		Message:    fmt.Sprintf("schedule %q not found", id),
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/scheduler"
+)
+
+func testSchedules(t *testing.T) *scheduler.Store {
+	t.Helper()
+
+	store, err := scheduler.Open(filepath.Join(t.TempDir(), "schedules.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestScheduleSendFileByURL_ValidatesAndExecutes(t *testing.T) {
+	t.Parallel()
+
+	client := &mockClient{
+		sendFileByURLFn: func(_ context.Context, _, apiTokenInstance, chatID, urlFile, fileName string) (greenapi.Response, error) {
+			require.Equal(t, "server-token", apiTokenInstance)
+			require.Equal(t, "77771234567@c.us", chatID)
+			require.Equal(t, "horse.png", fileName)
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"F1"}`)}, nil
+		},
+	}
+	svc := New(client, WithInstanceResolver(testRegistry(t)), WithScheduler(testSchedules(t)))
+
+	sendAt := time.Now().Add(time.Hour)
+	sched, apiErr := svc.ScheduleSendFileByURL(context.Background(), "key-1", SendFileByURLRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		Spec:               &scheduler.Spec{SendAt: &sendAt},
+		ChatID:             "77771234567",
+		URLFile:            "https://my.site.com/img/horse.png",
+	})
+	require.Nil(t, apiErr)
+	require.JSONEq(t, `{"instance":"sales","chatId":"77771234567@c.us","urlFile":"https://my.site.com/img/horse.png"}`, string(sched.Payload))
+
+	result, apiErr := svc.ExecuteSchedule(context.Background(), sched)
+	require.Nil(t, apiErr)
+	require.JSONEq(t, `{"idMessage":"F1"}`, string(result))
+}
+
+func TestScheduleSendMessage_Errors(t *testing.T) {
+	t.Parallel()
+
+	svc := New(&mockClient{}, WithInstanceResolver(testRegistry(t)), WithScheduler(testSchedules(t)))
+	req := SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		Spec:               &scheduler.Spec{Cron: "not a cron"},
+		ChatID:             "77771234567",
+		Message:            "hi",
+	}
+
+	_, apiErr := svc.ScheduleSendMessage(context.Background(), "key-1", req)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	req.Spec = &scheduler.Spec{Cron: "0 9 * * 1-5", Timezone: "Europe/Moscow"}
+	sched, apiErr := svc.ScheduleSendMessage(context.Background(), "key-1", req)
+	require.Nil(t, apiErr)
+
+	_, apiErr = svc.CancelSchedule(context.Background(), "key-2", sched.ID)
+	require.Equal(t, "schedule_not_found", apiErr.Code)
+
+	_, apiErr = svc.CancelSchedule(context.Background(), "key-1", sched.ID)
+	require.Nil(t, apiErr)
+	_, apiErr = svc.Reschedule(context.Background(), "key-1", sched.ID, scheduler.Spec{Cron: "0 10 * * *"})
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	_, apiErr = New(&mockClient{}).ScheduleSendMessage(context.Background(), "key-1", req)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode, "scheduler is not configured")
+}
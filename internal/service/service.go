@@ -4,112 +4,244 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"path"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
+	"green-api/internal/chatid"
 	"green-api/internal/greenapi"
+	"green-api/internal/instance"
 	"green-api/internal/model"
+	"green-api/internal/queue"
+	"green-api/internal/scheduler"
 )
 
 type GreenAPIClient interface {
 	GetSettings(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	SetSettings(ctx context.Context, idInstance, apiTokenInstance string, settings greenapi.InstanceSettings) (greenapi.Response, error)
 	GetStateInstance(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
-	SendMessage(ctx context.Context, idInstance, apiTokenInstance, chatID, message string) (greenapi.Response, error)
+	SendMessage(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.SendMessageParams) (greenapi.Response, error)
 	SendFileByURL(ctx context.Context, idInstance, apiTokenInstance, chatID, urlFile, fileName string) (greenapi.Response, error)
+	SendFileByUpload(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.UploadParams, file io.Reader) (greenapi.Response, error)
+	Reboot(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	Logout(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	QR(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	GetAuthorizationCode(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error)
+	CheckWhatsapp(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error)
+	GetContacts(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	GetContactInfo(ctx context.Context, idInstance, apiTokenInstance, chatID string) (greenapi.Response, error)
 }
 
 type Service struct {
-	client   GreenAPIClient
-	validate *validator.Validate
+	client         GreenAPIClient
+	validate       *validator.Validate
+	instances      instance.Resolver
+	registeredOnly bool
+	queue          *queue.Queue
+	schedules      *scheduler.Store
+	now            func() time.Time
+	tracer         trace.Tracer
+	maxUploadBytes int64
+	allowedTypes   []string
+	preflight      URLChecker
+	decodeMode     greenapi.DecodeMode
+	// statePollInterval paces WaitAuthorized.
+	statePollInterval time.Duration
+	whatsappCache     *whatsappCache
+	recipientCheck    bool
 }
 
+// Option customizes a Service built by New.
+type Option func(*Service)
+
+// CredentialsRequest names a registered instance or carries raw credentials.
 type CredentialsRequest struct {
-	IDInstance       string `json:"idInstance" validate:"required"`
-	APITokenInstance string `json:"apiTokenInstance" validate:"required"`
+	Instance         string `json:"instance,omitempty"`
+	IDInstance       string `json:"idInstance,omitempty" validate:"required_without=Instance"`
+	APITokenInstance string `json:"apiTokenInstance,omitempty" validate:"required_without=Instance"`
 }
 
+// SendMessageRequest is sent immediately unless it carries a schedule
+// (sendAt or cron), in which case it is stored and sent later.
 type SendMessageRequest struct {
 	CredentialsRequest
-	ChatID  string `json:"chatId" validate:"required"`
-	Message string `json:"message" validate:"required"`
+	*scheduler.Spec
+	ChatID          string   `json:"chatId" validate:"required"`
+	Message         string   `json:"message" validate:"required"`
+	QuotedMessageID string   `json:"quotedMessageId,omitempty" validate:"omitempty,max=128,printascii"`
+	LinkPreview     *bool    `json:"linkPreview,omitempty"`
+	Mentions        []string `json:"mentions,omitempty" validate:"max=1024,dive,required"`
 }
 
 type SendFileByURLRequest struct {
 	CredentialsRequest
+	*scheduler.Spec
 	ChatID  string `json:"chatId" validate:"required"`
 	URLFile string `json:"urlFile" validate:"required,url"`
 }
 
-var digitsOnly = regexp.MustCompile(`^\d+$`)
+// WithInstanceResolver lets requests refer to server-side instances by name.
+func WithInstanceResolver(resolver instance.Resolver) Option {
+	return func(s *Service) {
+		s.instances = resolver
+	}
+}
+
+// WithRegisteredInstancesOnly rejects raw idInstance/apiTokenInstance in
+// requests, so tokens only ever live on the server.
+func WithRegisteredInstancesOnly() Option {
+	return func(s *Service) {
+		s.registeredOnly = true
+	}
+}
 
-func New(client GreenAPIClient) *Service {
+func New(client GreenAPIClient, opts ...Option) *Service {
 	validate := validator.New()
-	return &Service{client: client, validate: validate}
+	svc := &Service{
+		client:   client,
+		validate: validate,
+		now:      time.Now,
+		tracer:   noop.NewTracerProvider().Tracer(tracerName),
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+func (s *Service) ListInstances(ctx context.Context) ([]instance.Summary, *model.APIError) {
+	if s.instances == nil {
+		return []instance.Summary{}, nil
+	}
+
+	summaries, err := s.instances.List(ctx)
+	if err != nil {
+		return nil, internalError("list instances", err)
+	}
+	return summaries, nil
 }
 
-func (s *Service) GetSettings(ctx context.Context, req CredentialsRequest) (greenapi.Response, *model.APIError) {
-	if err := s.validateCredentials(req); err != nil {
-		return greenapi.Response{}, err
+func (s *Service) GetSettings(ctx context.Context, req CredentialsRequest) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "GetSettings")
+	defer func() { endSpan(span, apiErr) }()
+
+	creds, apiErr := s.resolveCredentials(ctx, req)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
-	resp, callErr := s.client.GetSettings(ctx, strings.TrimSpace(req.IDInstance), strings.TrimSpace(req.APITokenInstance))
+	resp, callErr := s.client.GetSettings(ctx, creds.IDInstance, creds.APITokenInstance)
 	if callErr != nil {
 		return greenapi.Response{}, mapUpstreamError(callErr)
 	}
 	return resp, nil
 }
 
-func (s *Service) GetState(ctx context.Context, req CredentialsRequest) (greenapi.Response, *model.APIError) {
-	if err := s.validateCredentials(req); err != nil {
-		return greenapi.Response{}, err
+func (s *Service) GetState(ctx context.Context, req CredentialsRequest) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "GetState")
+	defer func() { endSpan(span, apiErr) }()
+
+	creds, apiErr := s.resolveCredentials(ctx, req)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
-	resp, callErr := s.client.GetStateInstance(ctx, strings.TrimSpace(req.IDInstance), strings.TrimSpace(req.APITokenInstance))
+	resp, callErr := s.client.GetStateInstance(ctx, creds.IDInstance, creds.APITokenInstance)
 	if callErr != nil {
 		return greenapi.Response{}, mapUpstreamError(callErr)
 	}
 	return resp, nil
 }
 
-func (s *Service) SendMessage(ctx context.Context, req SendMessageRequest) (greenapi.Response, *model.APIError) {
+func (s *Service) SendMessage(ctx context.Context, req SendMessageRequest) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "SendMessage")
+	defer func() { endSpan(span, apiErr) }()
+
 	if err := s.validate.Struct(req); err != nil {
 		return greenapi.Response{}, validationError(err)
 	}
-	if err := s.validateCredentials(req.CredentialsRequest); err != nil {
-		return greenapi.Response{}, err
+	creds, apiErr := s.resolveCredentials(ctx, req.CredentialsRequest)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
-	normalizedChatID, err := NormalizeChatID(req.ChatID)
-	if err != nil {
-		return greenapi.Response{}, invalidInput("chatId", err.Error())
+	params, apiErr := messageParams(req)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
+	}
+	if apiErr = s.requireWhatsapp(ctx, creds, params.ChatID); apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
-	resp, callErr := s.client.SendMessage(
-		ctx,
-		strings.TrimSpace(req.IDInstance),
-		strings.TrimSpace(req.APITokenInstance),
-		normalizedChatID,
-		strings.TrimSpace(req.Message),
-	)
+	resp, callErr := s.client.SendMessage(ctx, creds.IDInstance, creds.APITokenInstance, params)
 	if callErr != nil {
 		return greenapi.Response{}, mapUpstreamError(callErr)
 	}
 	return resp, nil
 }
 
-func (s *Service) SendFileByURL(ctx context.Context, req SendFileByURLRequest) (greenapi.Response, *model.APIError) {
+// messageParams normalizes the chat and mention IDs of req. Mentions are only
+// accepted in group chats and must name personal chats.
+func messageParams(req SendMessageRequest) (greenapi.SendMessageParams, *model.APIError) {
+	chatID, err := chatid.Parse(req.ChatID)
+	if err != nil {
+		return greenapi.SendMessageParams{}, invalidInput("chatId", err.Error())
+	}
+
+	quoted := strings.TrimSpace(req.QuotedMessageID)
+	if strings.ContainsAny(quoted, " \t\r\n") {
+		return greenapi.SendMessageParams{}, invalidInput("quotedMessageId", "quotedMessageId must not contain whitespace")
+	}
+
+	var mentions []string
+	if len(req.Mentions) > 0 {
+		if chatID.Kind() != chatid.Group {
+			return greenapi.SendMessageParams{}, invalidInput("mentions", "mentions are only supported in group chats")
+		}
+		seen := make(map[string]bool, len(req.Mentions))
+		for _, raw := range req.Mentions {
+			mention, err := chatid.Parse(raw)
+			if err != nil {
+				return greenapi.SendMessageParams{}, invalidInput("mentions", fmt.Sprintf("%q: %s", raw, err.Error()))
+			}
+			if mention.Kind() != chatid.Personal {
+				return greenapi.SendMessageParams{}, invalidInput("mentions", fmt.Sprintf("%q is not a personal chat", raw))
+			}
+			if !seen[mention.String()] {
+				seen[mention.String()] = true
+				mentions = append(mentions, mention.String())
+			}
+		}
+	}
+
+	return greenapi.SendMessageParams{
+		ChatID:          chatID.String(),
+		Message:         strings.TrimSpace(req.Message),
+		QuotedMessageID: quoted,
+		LinkPreview:     req.LinkPreview,
+		Mentions:        mentions,
+	}, nil
+}
+
+func (s *Service) SendFileByURL(ctx context.Context, req SendFileByURLRequest) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "SendFileByURL")
+	defer func() { endSpan(span, apiErr) }()
+
 	if err := s.validate.Struct(req); err != nil {
 		return greenapi.Response{}, validationError(err)
 	}
-	if err := s.validateCredentials(req.CredentialsRequest); err != nil {
-		return greenapi.Response{}, err
+	creds, apiErr := s.resolveCredentials(ctx, req.CredentialsRequest)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
-	normalizedChatID, err := NormalizeChatID(req.ChatID)
+	chatID, err := chatid.Parse(req.ChatID)
 	if err != nil {
 		return greenapi.Response{}, invalidInput("chatId", err.Error())
 	}
@@ -119,16 +251,16 @@ func (s *Service) SendFileByURL(ctx context.Context, req SendFileByURLRequest) (
 		return greenapi.Response{}, invalidInput("urlFile", err.Error())
 	}
 
-	fileName, err := ExtractFileName(urlFile)
-	if err != nil {
-		return greenapi.Response{}, invalidInput("urlFile", err.Error())
+	fileName, apiErr := s.urlFileName(ctx, urlFile)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
 	}
 
 	resp, callErr := s.client.SendFileByURL(
 		ctx,
-		strings.TrimSpace(req.IDInstance),
-		strings.TrimSpace(req.APITokenInstance),
-		normalizedChatID,
+		creds.IDInstance,
+		creds.APITokenInstance,
+		chatID.String(),
 		urlFile,
 		fileName,
 	)
@@ -138,32 +270,46 @@ func (s *Service) SendFileByURL(ctx context.Context, req SendFileByURLRequest) (
 	return resp, nil
 }
 
-func (s *Service) validateCredentials(req CredentialsRequest) *model.APIError {
+// resolveCredentials returns the credentials of the named instance or the raw
+// credentials carried by the request.
+func (s *Service) resolveCredentials(ctx context.Context, req CredentialsRequest) (instance.Credentials, *model.APIError) {
 	if err := s.validate.Struct(req); err != nil {
-		return validationError(err)
-	}
-	return nil
-}
-
-func NormalizeChatID(raw string) (string, error) {
-	candidate := strings.TrimSpace(raw)
-	if candidate == "" {
-		return "", fmt.Errorf("chatId is required")
+		return instance.Credentials{}, validationError(err)
 	}
 
-	if strings.HasSuffix(candidate, "@c.us") {
-		number := strings.TrimSuffix(candidate, "@c.us")
-		if number == "" || !digitsOnly.MatchString(number) {
-			return "", fmt.Errorf("chatId must contain only digits before @c.us")
+	name := strings.TrimSpace(req.Instance)
+	if name == "" {
+		if s.registeredOnly {
+			return instance.Credentials{}, invalidInput("instance", "raw idInstance/apiTokenInstance are disabled, use a registered instance name")
 		}
-		return candidate, nil
+		creds := instance.Credentials{
+			IDInstance:       strings.TrimSpace(req.IDInstance),
+			APITokenInstance: strings.TrimSpace(req.APITokenInstance),
+		}
+		annotateCredentials(ctx, "", creds)
+		return creds, nil
 	}
 
-	if !digitsOnly.MatchString(candidate) {
-		return "", fmt.Errorf("chatId must contain only digits")
+	if req.IDInstance != "" || req.APITokenInstance != "" {
+		return instance.Credentials{}, invalidInput("instance", "use either instance or idInstance/apiTokenInstance, not both")
+	}
+	if s.instances == nil {
+		return instance.Credentials{}, invalidInput("instance", "instance registry is not configured")
 	}
 
-	return candidate + "@c.us", nil
+	creds, err := s.instances.Resolve(ctx, name)
+	if errors.Is(err, instance.ErrNotFound) {
+		return instance.Credentials{}, &model.APIError{
+			StatusCode: 404,
+			Code:       "instance_not_found",
+			Message:    fmt.Sprintf("instance %q is not registered", name),
+		}
+	}
+	if err != nil {
+		return instance.Credentials{}, internalError("resolve instance", err)
+	}
+	annotateCredentials(ctx, name, creds)
+	return creds, nil
 }
 
 func validateURLFile(raw string) error {
@@ -187,14 +333,15 @@ func ExtractFileName(rawURL string) (string, error) {
 	if fileName == "." || fileName == "/" || fileName == "" {
 		return "", fmt.Errorf("cannot extract fileName from urlFile")
 	}
-	if strings.Contains(fileName, "/") || strings.Contains(fileName, "\\") {
-		return "", fmt.Errorf("invalid fileName extracted from urlFile")
-	}
 
 	decoded, unescapeErr := url.PathUnescape(fileName)
 	if unescapeErr == nil {
 		fileName = decoded
 	}
+	// Checked after decoding, so %2F or %5C cannot smuggle in a path.
+	if strings.ContainsAny(fileName, "/\\") || strings.ContainsFunc(fileName, isControl) {
+		return "", fmt.Errorf("invalid fileName extracted from urlFile")
+	}
 
 	if len(fileName) > 255 {
 		return "", fmt.Errorf("fileName is too long")
@@ -223,7 +370,30 @@ func invalidInput(field, message string) *model.APIError {
 	}
 }
 
+func internalError(operation string, err error) *model.APIError {
+	return &model.APIError{
+		StatusCode: 500,
+		Code:       "internal_error",
+		Message:    fmt.Sprintf("%s failed: %s", operation, greenapi.Redact(err.Error())),
+	}
+}
+
 func mapUpstreamError(err error) *model.APIError {
+	var limitErr *greenapi.RateLimitError
+	if errors.As(err, &limitErr) {
+		return &model.APIError{
+			StatusCode: 429,
+			Code:       "rate_limited",
+			Message:    fmt.Sprintf("too many %s calls for instance %s", limitErr.Class, limitErr.IDInstance),
+			Details: map[string]any{
+				"idInstance":   limitErr.IDInstance,
+				"class":        limitErr.Class,
+				"retryAfterMs": limitErr.RetryAfter.Milliseconds(),
+			},
+			Retryable: true,
+		}
+	}
+
 	statusCode := 502
 	if errors.Is(err, context.DeadlineExceeded) {
 		statusCode = 504
@@ -232,9 +402,20 @@ func mapUpstreamError(err error) *model.APIError {
 		statusCode = 503
 	}
 
-	return &model.APIError{
+	apiErr := &model.APIError{
 		StatusCode: statusCode,
 		Code:       "upstream_error",
-		Message:    err.Error(),
+		Message:    greenapi.Redact(err.Error()),
+		Retryable:  greenapi.NotSent(err),
+	}
+
+	var openErr *greenapi.BreakerOpenError
+	if errors.As(err, &openErr) {
+		details := map[string]string{"idInstance": openErr.IDInstance}
+		if openErr.Method != "" {
+			details["method"] = openErr.Method
+		}
+		apiErr.Details = details
 	}
+	return apiErr
 }
@@ -2,28 +2,55 @@ package service
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"green-api/internal/greenapi"
+	"green-api/internal/instance"
 )
 
 type mockClient struct {
+	sendMessageFn   func(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.SendMessageParams) (greenapi.Response, error)
 	sendFileByURLFn func(ctx context.Context, idInstance, apiTokenInstance, chatID, urlFile, fileName string) (greenapi.Response, error)
+	uploadFn        func(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.UploadParams, file io.Reader) (greenapi.Response, error)
+	settingsFn      func(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	setSettingsFn   func(ctx context.Context, idInstance, apiTokenInstance string, settings greenapi.InstanceSettings) (greenapi.Response, error)
+	stateFn         func(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	qrFn            func(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error)
+	authCodeFn      func(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error)
+	checkFn         func(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error)
 }
 
-func (m *mockClient) GetSettings(context.Context, string, string) (greenapi.Response, error) {
-	return greenapi.Response{}, nil
+func (m *mockClient) GetSettings(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error) {
+	if m.settingsFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.settingsFn(ctx, idInstance, apiTokenInstance)
 }
 
-func (m *mockClient) GetStateInstance(context.Context, string, string) (greenapi.Response, error) {
-	return greenapi.Response{}, nil
+func (m *mockClient) SetSettings(ctx context.Context, idInstance, apiTokenInstance string, settings greenapi.InstanceSettings) (greenapi.Response, error) {
+	if m.setSettingsFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.setSettingsFn(ctx, idInstance, apiTokenInstance, settings)
 }
 
-func (m *mockClient) SendMessage(context.Context, string, string, string, string) (greenapi.Response, error) {
-	return greenapi.Response{}, nil
+func (m *mockClient) GetStateInstance(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error) {
+	if m.stateFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.stateFn(ctx, idInstance, apiTokenInstance)
+}
+
+func (m *mockClient) SendMessage(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.SendMessageParams) (greenapi.Response, error) {
+	if m.sendMessageFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.sendMessageFn(ctx, idInstance, apiTokenInstance, params)
 }
 
 func (m *mockClient) SendFileByURL(ctx context.Context, idInstance, apiTokenInstance, chatID, urlFile, fileName string) (greenapi.Response, error) {
@@ -33,27 +60,48 @@ func (m *mockClient) SendFileByURL(ctx context.Context, idInstance, apiTokenInst
 	return m.sendFileByURLFn(ctx, idInstance, apiTokenInstance, chatID, urlFile, fileName)
 }
 
-func TestNormalizeChatID_AddSuffix(t *testing.T) {
-	t.Parallel()
+func (m *mockClient) SendFileByUpload(ctx context.Context, idInstance, apiTokenInstance string, params greenapi.UploadParams, file io.Reader) (greenapi.Response, error) {
+	if m.uploadFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.uploadFn(ctx, idInstance, apiTokenInstance, params, file)
+}
 
-	chatID, err := NormalizeChatID("77771234567")
-	require.NoError(t, err)
-	require.Equal(t, "77771234567@c.us", chatID)
+func (m *mockClient) Reboot(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{}, nil
 }
 
-func TestNormalizeChatID_PreserveSuffix(t *testing.T) {
-	t.Parallel()
+func (m *mockClient) Logout(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{}, nil
+}
 
-	chatID, err := NormalizeChatID("77771234567@c.us")
-	require.NoError(t, err)
-	require.Equal(t, "77771234567@c.us", chatID)
+func (m *mockClient) QR(ctx context.Context, idInstance, apiTokenInstance string) (greenapi.Response, error) {
+	if m.qrFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.qrFn(ctx, idInstance, apiTokenInstance)
 }
 
-func TestNormalizeChatID_Invalid(t *testing.T) {
-	t.Parallel()
+func (m *mockClient) GetAuthorizationCode(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error) {
+	if m.authCodeFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.authCodeFn(ctx, idInstance, apiTokenInstance, phoneNumber)
+}
 
-	_, err := NormalizeChatID("abc")
-	require.Error(t, err)
+func (m *mockClient) CheckWhatsapp(ctx context.Context, idInstance, apiTokenInstance string, phoneNumber int64) (greenapi.Response, error) {
+	if m.checkFn == nil {
+		return greenapi.Response{}, nil
+	}
+	return m.checkFn(ctx, idInstance, apiTokenInstance, phoneNumber)
+}
+
+func (m *mockClient) GetContacts(context.Context, string, string) (greenapi.Response, error) {
+	return greenapi.Response{}, nil
+}
+
+func (m *mockClient) GetContactInfo(context.Context, string, string, string) (greenapi.Response, error) {
+	return greenapi.Response{}, nil
 }
 
 func TestExtractFileName(t *testing.T) {
@@ -94,3 +142,188 @@ func TestSendFileByURL_ComputesFileNameAndNormalizesChatID(t *testing.T) {
 	require.True(t, called)
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 }
+
+func TestSendMessage_AcceptsGroupAndFormattedPhone(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	client := &mockClient{
+		sendMessageFn: func(_ context.Context, _, _ string, params greenapi.SendMessageParams) (greenapi.Response, error) {
+			sent = append(sent, params.ChatID)
+			return greenapi.Response{StatusCode: http.StatusOK}, nil
+		},
+	}
+
+	svc := New(client)
+	creds := CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"}
+	for _, chatID := range []string{"120363043968066561@g.us", "+7 (999) 123-45-67"} {
+		_, apiErr := svc.SendMessage(context.Background(), SendMessageRequest{CredentialsRequest: creds, ChatID: chatID, Message: "hi"})
+		require.Nil(t, apiErr)
+	}
+	require.Equal(t, []string{"120363043968066561@g.us", "79991234567@c.us"}, sent)
+
+	_, apiErr := svc.SendMessage(context.Background(), SendMessageRequest{CredentialsRequest: creds, ChatID: "+999 123", Message: "hi"})
+	require.NotNil(t, apiErr)
+	require.Equal(t, "validation_error", apiErr.Code)
+	require.Len(t, sent, 2)
+}
+
+func TestSendMessage_PassesReplyPreviewAndMentions(t *testing.T) {
+	t.Parallel()
+
+	var got greenapi.SendMessageParams
+	client := &mockClient{
+		sendMessageFn: func(_ context.Context, _, _ string, params greenapi.SendMessageParams) (greenapi.Response, error) {
+			got = params
+			return greenapi.Response{StatusCode: http.StatusOK}, nil
+		},
+	}
+
+	noPreview := false
+	_, apiErr := New(client).SendMessage(context.Background(), SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"},
+		ChatID:             "120363043968066561@g.us",
+		Message:            " @79991234567 see https://green-api.com ",
+		QuotedMessageID:    " BAE5F4886F6F2D05 ",
+		LinkPreview:        &noPreview,
+		Mentions:           []string{"+7 999 123-45-67", "79991234567@c.us", "77771234567"},
+	})
+	require.Nil(t, apiErr)
+	require.Equal(t, greenapi.SendMessageParams{
+		ChatID:          "120363043968066561@g.us",
+		Message:         "@79991234567 see https://green-api.com",
+		QuotedMessageID: "BAE5F4886F6F2D05",
+		LinkPreview:     &noPreview,
+		Mentions:        []string{"79991234567@c.us", "77771234567@c.us"},
+	}, got)
+}
+
+func TestSendMessage_RejectsInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	svc := New(&mockClient{})
+	creds := CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"}
+	cases := []struct {
+		name  string
+		req   SendMessageRequest
+		field string
+	}{
+		{name: "mentions outside group", req: SendMessageRequest{ChatID: "79991234567", Mentions: []string{"77771234567"}}, field: "mentions"},
+		{name: "invalid mention", req: SendMessageRequest{ChatID: "120363043968066561@g.us", Mentions: []string{"abc"}}, field: "mentions"},
+		{name: "group mention", req: SendMessageRequest{ChatID: "120363043968066561@g.us", Mentions: []string{"120363043968066562@g.us"}}, field: "mentions"},
+		{name: "quoted id with spaces", req: SendMessageRequest{ChatID: "79991234567", QuotedMessageID: "BAE5 F488"}, field: "quotedMessageId"},
+	}
+
+	for _, tc := range cases {
+		tc.req.CredentialsRequest = creds
+		tc.req.Message = "hi"
+		_, apiErr := svc.SendMessage(context.Background(), tc.req)
+		require.NotNil(t, apiErr, tc.name)
+		require.Equal(t, "validation_error", apiErr.Code, tc.name)
+		require.Equal(t, tc.field, apiErr.Details.(map[string]string)["field"], tc.name)
+	}
+}
+
+func TestMapUpstreamError_BreakerOpenReportsInstance(t *testing.T) {
+	t.Parallel()
+
+	err := &greenapi.UpstreamError{
+		Message: "green-api circuit breaker is open",
+		Cause:   &greenapi.BreakerOpenError{IDInstance: "1101000001", Method: "sendMessage"},
+	}
+
+	apiErr := mapUpstreamError(err)
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	require.Equal(t, map[string]string{"idInstance": "1101000001", "method": "sendMessage"}, apiErr.Details)
+	require.Contains(t, apiErr.Message, "instance 1101000001")
+	require.True(t, apiErr.Retryable, "a rejected call never reached GREEN-API")
+}
+
+func TestMapUpstreamError_RateLimited(t *testing.T) {
+	t.Parallel()
+
+	err := &greenapi.UpstreamError{
+		Message: "green-api call not made",
+		Cause:   &greenapi.RateLimitError{IDInstance: "1101000001", Class: greenapi.ClassSend, RetryAfter: 1500 * time.Millisecond},
+	}
+
+	apiErr := mapUpstreamError(err)
+	require.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	require.Equal(t, "rate_limited", apiErr.Code)
+	require.Equal(t, int64(1500), apiErr.Details.(map[string]any)["retryAfterMs"])
+	require.True(t, apiErr.Retryable)
+}
+
+func testRegistry(t *testing.T) *instance.Registry {
+	t.Helper()
+
+	registry, err := instance.NewRegistry([]instance.Instance{
+		{Name: "sales", Credentials: instance.Credentials{IDInstance: "1101000001", APITokenInstance: "server-token"}},
+	})
+	require.NoError(t, err)
+	return registry
+}
+
+func TestSendFileByURL_ResolvesRegisteredInstance(t *testing.T) {
+	t.Parallel()
+
+	client := &mockClient{
+		sendFileByURLFn: func(_ context.Context, idInstance, apiTokenInstance, _, _, _ string) (greenapi.Response, error) {
+			require.Equal(t, "1101000001", idInstance)
+			require.Equal(t, "server-token", apiTokenInstance)
+			return greenapi.Response{StatusCode: http.StatusOK}, nil
+		},
+	}
+
+	svc := New(client, WithInstanceResolver(testRegistry(t)))
+	_, apiErr := svc.SendFileByURL(context.Background(), SendFileByURLRequest{
+		CredentialsRequest: CredentialsRequest{Instance: "sales"},
+		ChatID:             "77771234567",
+		URLFile:            "https://my.site.com/img/horse.png",
+	})
+	require.Nil(t, apiErr)
+}
+
+func TestResolveCredentials_Errors(t *testing.T) {
+	t.Parallel()
+
+	svc := New(&mockClient{}, WithInstanceResolver(testRegistry(t)), WithRegisteredInstancesOnly())
+
+	_, apiErr := svc.GetSettings(context.Background(), CredentialsRequest{Instance: "unknown"})
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	require.Equal(t, "instance_not_found", apiErr.Code)
+
+	_, apiErr = svc.GetSettings(context.Background(), CredentialsRequest{Instance: "sales", APITokenInstance: "token"})
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+
+	_, apiErr = svc.GetSettings(context.Background(), CredentialsRequest{IDInstance: "1", APITokenInstance: "token"})
+	require.NotNil(t, apiErr)
+	require.Equal(t, "validation_error", apiErr.Code)
+
+	_, apiErr = svc.GetSettings(context.Background(), CredentialsRequest{})
+	require.NotNil(t, apiErr)
+	require.Equal(t, "validation_error", apiErr.Code)
+
+	_, apiErr = svc.GetSettings(context.Background(), CredentialsRequest{Instance: "sales"})
+	require.Nil(t, apiErr)
+}
+
+func TestListInstances_HidesTokens(t *testing.T) {
+	t.Parallel()
+
+	svc := New(&mockClient{}, WithInstanceResolver(testRegistry(t)))
+	instances, apiErr := svc.ListInstances(context.Background())
+	require.Nil(t, apiErr)
+	require.Equal(t, []instance.Summary{{Name: "sales", IDInstance: "1101000001"}}, instances)
+}
+
+func TestExtractFileName_RejectsEncodedSeparators(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{"https://example.com/a%252F..%252Fetc.png", "https://example.com/a%5Cb.png", "https://example.com/a%0Ab.png"} {
+		_, err := ExtractFileName(raw)
+		require.Error(t, err, raw)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"net/url"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/model"
+)
+
+// minSendDelay is the smallest delaySendMessagesMilliseconds GREEN-API takes.
+const minSendDelay = 500
+
+// SetSettingsRequest changes the settings of an instance. With DryRun the
+// changes are only computed against the current settings.
+type SetSettingsRequest struct {
+	CredentialsRequest
+	Settings greenapi.InstanceSettings `json:"settings"`
+	DryRun   bool                      `json:"dryRun,omitempty"`
+}
+
+// SetSettingsResult lists what the request changes. Applied is false for a
+// dry run and when nothing differs from the current settings, in which case
+// setSettings is not called.
+type SetSettingsResult struct {
+	DryRun  bool                     `json:"dryRun"`
+	Applied bool                     `json:"applied"`
+	Changes []greenapi.SettingChange `json:"changes"`
+}
+
+// SetSettings reads the current settings, diffs them against req.Settings and,
+// unless this is a dry run, sends the changed fields to setSettings.
+func (s *Service) SetSettings(ctx context.Context, req SetSettingsRequest) (result SetSettingsResult, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "SetSettings")
+	defer func() { endSpan(span, apiErr) }()
+
+	if apiErr = validateSettings(req.Settings); apiErr != nil {
+		return SetSettingsResult{}, apiErr
+	}
+
+	creds, apiErr := s.resolveCredentials(ctx, req.CredentialsRequest)
+	if apiErr != nil {
+		return SetSettingsResult{}, apiErr
+	}
+
+	resp, callErr := s.client.GetSettings(ctx, creds.IDInstance, creds.APITokenInstance)
+	if callErr != nil {
+		return SetSettingsResult{}, mapUpstreamError(callErr)
+	}
+	current, apiErr := decodeResponse[greenapi.Settings](resp, nil, s.decodeMode)
+	if apiErr != nil {
+		return SetSettingsResult{}, apiErr
+	}
+	result = SetSettingsResult{DryRun: req.DryRun, Changes: req.Settings.Diff(current)}
+	if req.DryRun || len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	resp, callErr = s.client.SetSettings(ctx, creds.IDInstance, creds.APITokenInstance, req.Settings.Only(result.Changes))
+	if callErr != nil {
+		return SetSettingsResult{}, mapUpstreamError(callErr)
+	}
+	saved, apiErr := decodeResponse[greenapi.SaveSettingsResult](resp, nil, s.decodeMode)
+	if apiErr != nil {
+		return SetSettingsResult{}, apiErr
+	}
+	if !saved.SaveSettings {
+		return SetSettingsResult{}, &model.APIError{
+			StatusCode: 502,
+			Code:       "upstream_error",
+			Message:    "green-api did not save the settings",
+		}
+	}
+	result.Applied = true
+	return result, nil
+}
+
+func validateSettings(settings greenapi.InstanceSettings) *model.APIError {
+	if settings == (greenapi.InstanceSettings{}) {
+		return invalidInput("settings", "settings must change at least one field")
+	}
+	// An empty webhookUrl turns webhooks off.
+	if settings.WebhookURL != nil && *settings.WebhookURL != "" {
+		parsed, err := url.ParseRequestURI(*settings.WebhookURL)
+		if err != nil || parsed.Host == "" {
+			return invalidInput("webhookUrl", "webhookUrl must be an absolute URL")
+		}
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return invalidInput("webhookUrl", "webhookUrl must use http or https")
+		}
+	}
+	if settings.DelaySendMessagesMilliseconds != nil && *settings.DelaySendMessagesMilliseconds < minSendDelay {
+		return invalidInput("delaySendMessagesMilliseconds", "delaySendMessagesMilliseconds must be at least 500")
+	}
+	return nil
+}
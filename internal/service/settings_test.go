@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+)
+
+const currentSettings = `{"wid":"79990000000@c.us","webhookUrl":"","webhookUrlToken":"","delaySendMessagesMilliseconds":1000,"incomingWebhook":"no","outgoingWebhook":"yes"}`
+
+func settingsRequest(t *testing.T, settings string, dryRun bool) SetSettingsRequest {
+	t.Helper()
+
+	req := SetSettingsRequest{CredentialsRequest: testCredentials, DryRun: dryRun}
+	require.NoError(t, json.Unmarshal([]byte(settings), &req.Settings))
+	return req
+}
+
+func settingsClient(saved *[]greenapi.InstanceSettings) *mockClient {
+	return &mockClient{
+		settingsFn: func(context.Context, string, string) (greenapi.Response, error) {
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(currentSettings)}, nil
+		},
+		setSettingsFn: func(_ context.Context, _, _ string, settings greenapi.InstanceSettings) (greenapi.Response, error) {
+			*saved = append(*saved, settings)
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"saveSettings":true}`)}, nil
+		},
+	}
+}
+
+func TestSetSettings_DryRunOnlyDiffs(t *testing.T) {
+	t.Parallel()
+
+	var saved []greenapi.InstanceSettings
+	result, apiErr := New(settingsClient(&saved)).SetSettings(context.Background(), settingsRequest(t, `{"incomingWebhook":"yes","outgoingWebhook":"yes"}`, true))
+	require.Nil(t, apiErr)
+	require.True(t, result.DryRun)
+	require.False(t, result.Applied)
+	require.Equal(t, []greenapi.SettingChange{{Field: "incomingWebhook", From: false, To: true}}, result.Changes)
+	require.Empty(t, saved)
+}
+
+func TestSetSettings_AppliesChangedFields(t *testing.T) {
+	t.Parallel()
+
+	var saved []greenapi.InstanceSettings
+	svc := New(settingsClient(&saved))
+
+	result, apiErr := svc.SetSettings(context.Background(), settingsRequest(t, `{"webhookUrl":"https://example.com/hook","delaySendMessagesMilliseconds":1000}`, false))
+	require.Nil(t, apiErr)
+	require.True(t, result.Applied)
+	require.Len(t, result.Changes, 1)
+	require.Len(t, saved, 1)
+	require.Equal(t, "https://example.com/hook", *saved[0].WebhookURL)
+	require.Nil(t, saved[0].DelaySendMessagesMilliseconds, "unchanged field is not sent")
+
+	result, apiErr = svc.SetSettings(context.Background(), settingsRequest(t, `{"outgoingWebhook":true}`, false))
+	require.Nil(t, apiErr)
+	require.False(t, result.Applied, "nothing to change")
+	require.Empty(t, result.Changes)
+	require.Len(t, saved, 1)
+}
+
+func TestSetSettings_Validation(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		settings string
+		field    string
+	}{
+		{name: "nothing to set", settings: `{}`, field: "settings"},
+		{name: "relative webhook", settings: `{"webhookUrl":"/hook"}`, field: "webhookUrl"},
+		{name: "ftp webhook", settings: `{"webhookUrl":"ftp://example.com/hook"}`, field: "webhookUrl"},
+		{name: "short delay", settings: `{"delaySendMessagesMilliseconds":100}`, field: "delaySendMessagesMilliseconds"},
+	}
+	for _, tc := range cases {
+		var saved []greenapi.InstanceSettings
+		_, apiErr := New(settingsClient(&saved)).SetSettings(context.Background(), settingsRequest(t, tc.settings, false))
+		require.NotNil(t, apiErr, tc.name)
+		require.Equal(t, http.StatusBadRequest, apiErr.StatusCode, tc.name)
+		require.Equal(t, tc.field, apiErr.Details.(map[string]string)["field"], tc.name)
+		require.Empty(t, saved, tc.name)
+	}
+
+	var saved []greenapi.InstanceSettings
+	_, apiErr := New(settingsClient(&saved)).SetSettings(context.Background(), settingsRequest(t, `{"webhookUrl":""}`, true))
+	require.Nil(t, apiErr, "empty webhookUrl turns webhooks off")
+}
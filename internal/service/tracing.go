@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/instance"
+	"green-api/internal/model"
+)
+
+const tracerName = "green-api/internal/service"
+
+// Span attributes set by Service in addition to those of greenapi.
+const (
+	AttributeInstance   = attribute.Key("greenapi.instance")
+	AttributeJobID      = attribute.Key("queue.job.id")
+	AttributeScheduleID = attribute.Key("scheduler.schedule.id")
+)
+
+// WithTracerProvider records a span for every Service operation.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(s *Service) {
+		s.tracer = provider.Tracer(tracerName)
+	}
+}
+
+func (s *Service) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "service."+operation, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, recording the code of apiErr. Only server-side failures
+// mark the span as failed; Details are left out as they may echo the request.
+func endSpan(span trace.Span, apiErr *model.APIError) {
+	if apiErr != nil {
+		span.SetAttributes(semconv.ErrorTypeKey.String(apiErr.Code))
+		if apiErr.StatusCode == 0 || apiErr.StatusCode >= 500 {
+			span.SetStatus(codes.Error, apiErr.Message)
+		}
+	}
+	span.End()
+}
+
+// annotateCredentials adds the resolved instance to the current span.
+func annotateCredentials(ctx context.Context, name string, creds instance.Credentials) {
+	span := trace.SpanFromContext(ctx)
+	if name != "" {
+		span.SetAttributes(AttributeInstance.String(name))
+	}
+	span.SetAttributes(greenapi.AttributeIDInstance.String(creds.IDInstance))
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"green-api/internal/greenapi"
+	"green-api/internal/model"
+)
+
+// WithDecodeMode sets how strictly the typed methods check GREEN-API
+// responses against the greenapi models. The default is lenient.
+func WithDecodeMode(mode greenapi.DecodeMode) Option {
+	return func(s *Service) {
+		s.decodeMode = mode
+	}
+}
+
+// GetSettingsTyped is GetSettings decoded into greenapi.Settings.
+func (s *Service) GetSettingsTyped(ctx context.Context, req CredentialsRequest) (greenapi.Settings, *model.APIError) {
+	resp, apiErr := s.GetSettings(ctx, req)
+	return decodeResponse[greenapi.Settings](resp, apiErr, s.decodeMode)
+}
+
+// GetStateTyped is GetState decoded into greenapi.State.
+func (s *Service) GetStateTyped(ctx context.Context, req CredentialsRequest) (greenapi.State, *model.APIError) {
+	resp, apiErr := s.GetState(ctx, req)
+	return decodeResponse[greenapi.State](resp, apiErr, s.decodeMode)
+}
+
+// SendMessageTyped is SendMessage decoded into greenapi.SendResult.
+func (s *Service) SendMessageTyped(ctx context.Context, req SendMessageRequest) (greenapi.SendResult, *model.APIError) {
+	resp, apiErr := s.SendMessage(ctx, req)
+	return s.decodeSent(resp, apiErr)
+}
+
+// SendFileByURLTyped is SendFileByURL decoded into greenapi.SendResult.
+func (s *Service) SendFileByURLTyped(ctx context.Context, req SendFileByURLRequest) (greenapi.SendResult, *model.APIError) {
+	resp, apiErr := s.SendFileByURL(ctx, req)
+	return s.decodeSent(resp, apiErr)
+}
+
+// decodeSent decodes the response to a send. Once GREEN-API accepted the send
+// with a 2xx, the strict mode no longer applies: failing the request would
+// invite a retry that sends the message twice. A 2xx body that cannot be read
+// at all stays 502 upstream_invalid_response, with details.status telling the
+// client that the send was accepted; it is never Retryable.
+func (s *Service) decodeSent(resp greenapi.Response, apiErr *model.APIError) (greenapi.SendResult, *model.APIError) {
+	result, apiErr := decodeResponse[greenapi.SendResult](resp, apiErr, s.decodeMode)
+	if apiErr == nil || apiErr.Code != "upstream_invalid_response" {
+		return result, apiErr
+	}
+	if s.decodeMode == greenapi.DecodeStrict {
+		if lenient, err := decodeResponse[greenapi.SendResult](resp, nil, greenapi.DecodeLenient); err == nil {
+			return lenient, nil
+		}
+	}
+	apiErr.Details = map[string]any{"status": resp.StatusCode}
+	return greenapi.SendResult{}, apiErr
+}
+
+// decodeResponse turns a raw GREEN-API response into T. Non-2xx responses
+// become upstream_error: 4xx keep their status, since the request was at
+// fault, and 5xx are reported as 502.
+func decodeResponse[T any](resp greenapi.Response, apiErr *model.APIError, mode greenapi.DecodeMode) (T, *model.APIError) {
+	var result T
+	if apiErr != nil {
+		return result, apiErr
+	}
+
+	err := greenapi.Decode(resp, mode, &result)
+	if err == nil {
+		return result, nil
+	}
+
+	var statusErr *greenapi.StatusError
+	if errors.As(err, &statusErr) {
+		statusCode := statusErr.StatusCode
+		if statusCode < 400 || statusCode > 499 {
+			statusCode = 502
+		}
+		details := map[string]any{"status": statusErr.StatusCode}
+		if statusErr.Body.Code != 0 {
+			details["code"] = statusErr.Body.Code
+		}
+		return result, &model.APIError{
+			StatusCode: statusCode,
+			Code:       "upstream_error",
+			Message:    greenapi.Redact(statusErr.Error()),
+			Details:    details,
+		}
+	}
+	return result, &model.APIError{
+		StatusCode: 502,
+		Code:       "upstream_invalid_response",
+		Message:    greenapi.Redact(err.Error()),
+	}
+}
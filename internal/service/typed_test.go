@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+)
+
+func sendMessageRequest() SendMessageRequest {
+	return SendMessageRequest{
+		CredentialsRequest: CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"},
+		ChatID:             "77771234567",
+		Message:            "hi",
+	}
+}
+
+func TestSendMessageTyped_DecodesResult(t *testing.T) {
+	t.Parallel()
+
+	client := &mockClient{
+		sendMessageFn: func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"BAE5"}`)}, nil
+		},
+	}
+
+	result, apiErr := New(client).SendMessageTyped(context.Background(), sendMessageRequest())
+	require.Nil(t, apiErr)
+	require.Equal(t, "BAE5", result.IDMessage)
+}
+
+func TestSendMessageTyped_UpstreamFailures(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		mode   greenapi.DecodeMode
+		resp   greenapi.Response
+		status int
+		code   string
+	}{
+		{name: "4xx kept", resp: greenapi.Response{StatusCode: http.StatusBadRequest, Body: []byte(`{"code":400,"message":"chatId is invalid"}`)}, status: http.StatusBadRequest, code: "upstream_error"},
+		{name: "5xx as 502", resp: greenapi.Response{StatusCode: http.StatusInternalServerError}, status: http.StatusBadGateway, code: "upstream_error"},
+		{name: "invalid body", resp: greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`ok`)}, status: http.StatusBadGateway, code: "upstream_invalid_response"},
+		{name: "lenient unknown field", resp: greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"1","extra":1}`)}},
+		{name: "strict unknown field on a send", mode: greenapi.DecodeStrict, resp: greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"1","extra":1}`)}},
+		{name: "strict invalid body", mode: greenapi.DecodeStrict, resp: greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`ok`)}, status: http.StatusBadGateway, code: "upstream_invalid_response"},
+	}
+
+	for _, tc := range cases {
+		client := &mockClient{
+			sendMessageFn: func(context.Context, string, string, greenapi.SendMessageParams) (greenapi.Response, error) {
+				return tc.resp, nil
+			},
+		}
+
+		_, apiErr := New(client, WithDecodeMode(tc.mode)).SendMessageTyped(context.Background(), sendMessageRequest())
+		if tc.code == "" {
+			require.Nil(t, apiErr, tc.name)
+			continue
+		}
+		require.NotNil(t, apiErr, tc.name)
+		require.Equal(t, tc.status, apiErr.StatusCode, tc.name)
+		require.Equal(t, tc.code, apiErr.Code, tc.name)
+		require.False(t, apiErr.Retryable, tc.name)
+	}
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"green-api/internal/chatid"
+	"green-api/internal/greenapi"
+	"green-api/internal/model"
+)
+
+// sniffLen is the number of leading bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+var errFileTooLarge = errors.New("file exceeds the upload size limit")
+
+// SendFileByUploadRequest carries the form fields of an upload; the file
+// itself is streamed separately.
+type SendFileByUploadRequest struct {
+	CredentialsRequest
+	ChatID          string `json:"chatId" validate:"required"`
+	FileName        string `json:"fileName" validate:"required,max=255"`
+	Caption         string `json:"caption,omitempty" validate:"max=20000"`
+	QuotedMessageID string `json:"quotedMessageId,omitempty" validate:"omitempty,max=128,printascii"`
+}
+
+// WithUploadLimits caps the size of uploaded files and, when allowedTypes is
+// not empty, only accepts files whose sniffed MIME type equals one of them or
+// starts with one ending in "/", such as "image/". The same limits apply to
+// urlFile when the pre-flight check is enabled.
+func WithUploadLimits(maxBytes int64, allowedTypes []string) Option {
+	return func(s *Service) {
+		s.maxUploadBytes = maxBytes
+		s.allowedTypes = allowedTypes
+	}
+}
+
+// SendFileByUpload streams file to the chat. The MIME type is sniffed from
+// the first bytes instead of trusting the client, and the file is cut off
+// with 413 file_too_large once it exceeds the upload limit.
+func (s *Service) SendFileByUpload(ctx context.Context, req SendFileByUploadRequest, file io.Reader) (resp greenapi.Response, apiErr *model.APIError) {
+	ctx, span := s.startSpan(ctx, "SendFileByUpload")
+	defer func() { endSpan(span, apiErr) }()
+
+	if err := s.validate.Struct(req); err != nil {
+		return greenapi.Response{}, validationError(err)
+	}
+	creds, apiErr := s.resolveCredentials(ctx, req.CredentialsRequest)
+	if apiErr != nil {
+		return greenapi.Response{}, apiErr
+	}
+
+	chatID, err := chatid.Parse(req.ChatID)
+	if err != nil {
+		return greenapi.Response{}, invalidInput("chatId", err.Error())
+	}
+	fileName, err := uploadFileName(req.FileName)
+	if err != nil {
+		return greenapi.Response{}, invalidInput("fileName", err.Error())
+	}
+	quoted := strings.TrimSpace(req.QuotedMessageID)
+	if strings.ContainsAny(quoted, " \t\r\n") {
+		return greenapi.Response{}, invalidInput("quotedMessageId", "quotedMessageId must not contain whitespace")
+	}
+
+	buffered := bufio.NewReaderSize(file, sniffLen)
+	head, err := buffered.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return greenapi.Response{}, uploadFailed(err)
+	}
+	if len(head) == 0 {
+		return greenapi.Response{}, invalidInput("file", "file is empty")
+	}
+	contentType := http.DetectContentType(head)
+	if !s.allowsType(contentType) {
+		return greenapi.Response{}, unsupportedMediaType(contentType)
+	}
+
+	var body io.Reader = buffered
+	if s.maxUploadBytes > 0 {
+		body = &limitedReader{reader: buffered, remaining: s.maxUploadBytes}
+	}
+
+	resp, callErr := s.client.SendFileByUpload(ctx, creds.IDInstance, creds.APITokenInstance, greenapi.UploadParams{
+		ChatID:          chatID.String(),
+		FileName:        fileName,
+		Caption:         strings.TrimSpace(req.Caption),
+		QuotedMessageID: quoted,
+		ContentType:     contentType,
+	}, body)
+	if errors.Is(callErr, errFileTooLarge) {
+		return greenapi.Response{}, fileTooLarge(s.maxUploadBytes)
+	}
+	var upstreamErr *greenapi.UpstreamError
+	if callErr != nil && !errors.As(callErr, &upstreamErr) {
+		return greenapi.Response{}, uploadFailed(callErr)
+	}
+	if callErr != nil {
+		return greenapi.Response{}, mapUpstreamError(callErr)
+	}
+	return resp, nil
+}
+
+// uploadFileName keeps the name GREEN-API shows to the recipient. It needs an
+// extension, which GREEN-API uses to pick the message type.
+func uploadFileName(raw string) (string, error) {
+	fileName := strings.TrimSpace(raw)
+	if strings.ContainsAny(fileName, "/\\") || strings.ContainsFunc(fileName, isControl) {
+		return "", errors.New("fileName must not contain path separators or control characters")
+	}
+	if ext := path.Ext(fileName); ext == "" || ext == fileName {
+		return "", errors.New("fileName must have an extension, e.g. report.pdf")
+	}
+	return fileName, nil
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func (s *Service) allowsType(contentType string) bool {
+	if len(s.allowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range s.allowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileTooLarge(maxBytes int64) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Code:       "file_too_large",
+		Message:    fmt.Sprintf("file must not exceed %d bytes", maxBytes),
+	}
+}
+
+func unsupportedMediaType(contentType string) *model.APIError {
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	return &model.APIError{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Code:       "unsupported_media_type",
+		Message:    fmt.Sprintf("files of type %s are not accepted", contentType),
+	}
+}
+
+func uploadFailed(err error) *model.APIError {
+	return &model.APIError{
+		StatusCode: http.StatusBadRequest,
+		Code:       "upload_failed",
+		Message:    fmt.Sprintf("read uploaded file: %v", err),
+	}
+}
+
+// limitedReader fails with errFileTooLarge instead of silently truncating the
+// file like io.LimitReader would.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// One more byte tells a file of exactly the limit from a larger one.
+		var probe [1]byte
+		n, err := l.reader.Read(probe[:])
+		if n > 0 {
+			return 0, errFileTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
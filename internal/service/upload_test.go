@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"green-api/internal/greenapi"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func uploadRequest() SendFileByUploadRequest {
+	return SendFileByUploadRequest{
+		CredentialsRequest: CredentialsRequest{IDInstance: "1101000001", APITokenInstance: "token"},
+		ChatID:             "+7 999 123-45-67",
+		FileName:           "horse.png",
+		Caption:            " Look ",
+	}
+}
+
+// readingClient reads the whole file like the real client streams it.
+func readingClient(got *greenapi.UploadParams, body *[]byte) *mockClient {
+	return &mockClient{
+		uploadFn: func(_ context.Context, _, _ string, params greenapi.UploadParams, file io.Reader) (greenapi.Response, error) {
+			*got = params
+			data, err := io.ReadAll(file)
+			if err != nil {
+				return greenapi.Response{}, err
+			}
+			*body = data
+			return greenapi.Response{StatusCode: http.StatusOK, Body: []byte(`{"idMessage":"U1"}`)}, nil
+		},
+	}
+}
+
+func TestSendFileByUpload_SniffsTypeAndStreamsFile(t *testing.T) {
+	t.Parallel()
+
+	var params greenapi.UploadParams
+	var body []byte
+	svc := New(readingClient(&params, &body), WithUploadLimits(1024, []string{"image/"}))
+
+	file := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{1}, 600)...)
+	resp, apiErr := svc.SendFileByUpload(context.Background(), uploadRequest(), bytes.NewReader(file))
+	require.Nil(t, apiErr)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, file, body)
+	require.Equal(t, greenapi.UploadParams{
+		ChatID:      "79991234567@c.us",
+		FileName:    "horse.png",
+		Caption:     "Look",
+		ContentType: "image/png",
+	}, params)
+}
+
+func TestSendFileByUpload_AcceptsFileOfExactlyTheLimit(t *testing.T) {
+	t.Parallel()
+
+	var params greenapi.UploadParams
+	var body []byte
+	svc := New(readingClient(&params, &body), WithUploadLimits(int64(len(pngHeader)), nil))
+
+	_, apiErr := svc.SendFileByUpload(context.Background(), uploadRequest(), bytes.NewReader(pngHeader))
+	require.Nil(t, apiErr)
+	require.Equal(t, pngHeader, body)
+}
+
+func TestSendFileByUpload_Rejections(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name   string
+		mutate func(*SendFileByUploadRequest)
+		file   []byte
+		status int
+		code   string
+	}{
+		{name: "too large", file: bytes.Repeat(pngHeader, 100), status: http.StatusRequestEntityTooLarge, code: "file_too_large"},
+		{name: "type not allowed", file: []byte("%PDF-1.7\n"), status: http.StatusUnsupportedMediaType, code: "unsupported_media_type"},
+		{name: "empty file", file: nil, status: http.StatusBadRequest, code: "validation_error"},
+		{name: "no extension", mutate: func(r *SendFileByUploadRequest) { r.FileName = "horse" }, file: pngHeader, status: http.StatusBadRequest, code: "validation_error"},
+		{name: "path in name", mutate: func(r *SendFileByUploadRequest) { r.FileName = "../horse.png" }, file: pngHeader, status: http.StatusBadRequest, code: "validation_error"},
+		{name: "bad chat", mutate: func(r *SendFileByUploadRequest) { r.ChatID = "abc" }, file: pngHeader, status: http.StatusBadRequest, code: "validation_error"},
+	}
+
+	for _, tc := range cases {
+		var params greenapi.UploadParams
+		var body []byte
+		svc := New(readingClient(&params, &body), WithUploadLimits(256, []string{"image/png", "video/"}))
+
+		req := uploadRequest()
+		if tc.mutate != nil {
+			tc.mutate(&req)
+		}
+		_, apiErr := svc.SendFileByUpload(context.Background(), req, bytes.NewReader(tc.file))
+		require.NotNil(t, apiErr, tc.name)
+		require.Equal(t, tc.status, apiErr.StatusCode, tc.name)
+		require.Equal(t, tc.code, apiErr.Code, tc.name)
+	}
+}
+
+func TestSendFileByUpload_ReadErrorIsNotAnUpstreamError(t *testing.T) {
+	t.Parallel()
+
+	var params greenapi.UploadParams
+	var body []byte
+	svc := New(readingClient(&params, &body))
+
+	file := io.MultiReader(bytes.NewReader(bytes.Repeat(pngHeader, 64)), failingReader{})
+	_, apiErr := svc.SendFileByUpload(context.Background(), uploadRequest(), file)
+	require.NotNil(t, apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, "upload_failed", apiErr.Code)
+	require.Contains(t, apiErr.Message, "unexpected EOF")
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.uber.org/zap"
+
+	"green-api/internal/config"
+)
+
+const (
+	// tracesPath is appended to an endpoint given without a path.
+	tracesPath = "/v1/traces"
+	// shutdownTimeout bounds the final flush of buffered spans on Close.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Provider is a tracer provider that batches spans to an OTLP/HTTP collector.
+type Provider struct {
+	*sdktrace.TracerProvider
+}
+
+// New builds a Provider exporting to cfg.Endpoint. Export failures are logged
+// and never affect requests.
+func New(cfg config.TracingConfig, logger *zap.Logger) (*Provider, error) {
+	endpoint, err := tracesURL(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("otlp trace exporter: %w", err)
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("tracing_export_failed", zap.Error(err))
+	}))
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.Service()))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Sampling()))),
+	)
+	return &Provider{TracerProvider: provider}, nil
+}
+
+// Close flushes buffered spans and stops the exporter.
+func (p *Provider) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return p.Shutdown(ctx)
+}
+
+func tracesURL(endpoint string) (string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("tracing endpoint: %w", err)
+	}
+	if strings.Trim(parsed.Path, "/") == "" {
+		parsed.Path = tracesPath
+	}
+	return parsed.String(), nil
+}
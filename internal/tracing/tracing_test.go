@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"green-api/internal/config"
+)
+
+// collector is an in-process stand-in for an OTLP/HTTP trace receiver.
+type collector struct {
+	mu       sync.Mutex
+	requests []*collectortrace.ExportTraceServiceRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, &req)
+	c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(nil)
+}
+
+func TestProvider_ExportsSpansOnClose(t *testing.T) {
+	t.Parallel()
+
+	receiver := &collector{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	provider, err := New(config.TracingConfig{Enabled: true, Endpoint: server.URL, ServiceName: "backend-test"}, zap.NewNop())
+	require.NoError(t, err)
+
+	_, span := provider.Tracer("test").Start(context.Background(), "unit-of-work")
+	span.End()
+	require.NoError(t, provider.Close())
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	require.Len(t, receiver.requests, 1)
+
+	resourceSpans := receiver.requests[0].GetResourceSpans()
+	require.Len(t, resourceSpans, 1)
+
+	var serviceName string
+	for _, attr := range resourceSpans[0].GetResource().GetAttributes() {
+		if attr.GetKey() == "service.name" {
+			serviceName = attr.GetValue().GetStringValue()
+		}
+	}
+	require.Equal(t, "backend-test", serviceName)
+
+	spans := resourceSpans[0].GetScopeSpans()[0].GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "unit-of-work", spans[0].GetName())
+}
@@ -0,0 +1,240 @@
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const maxRedirects = 3
+
+// Error explains why a URL was rejected. The message is safe to show to the
+// caller: it never contains resolved addresses.
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Result describes the file a URL points to.
+type Result struct {
+	ContentType string
+	// ContentLength is -1 when the server did not report a size.
+	ContentLength int64
+	// FileName comes from Content-Disposition, else from the URL path; it gets
+	// an extension from ContentType when neither has one.
+	FileName string
+}
+
+// Checker performs pre-flight checks of user supplied file URLs. Hosts must
+// resolve to public addresses only; the address is checked again when the
+// connection is made, so DNS rebinding and redirects cannot reach internal
+// services either.
+type Checker struct {
+	client  *http.Client
+	blocked func(netip.Addr) bool
+}
+
+func New(timeout time.Duration) *Checker {
+	c := &Checker{blocked: isBlocked}
+	dialer := &net.Dialer{Timeout: timeout, Control: c.control}
+	c.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// A proxy would hide the real destination from the dialer.
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return &Error{Message: fmt.Sprintf("urlFile redirects more than %d times", maxRedirects)}
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return &Error{Message: "urlFile redirects to a non-http URL"}
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+// Check resolves the host of rawURL and sends a HEAD request, falling back to
+// GET without reading the body when HEAD is not supported.
+func (c *Checker) Check(ctx context.Context, rawURL string) (Result, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return Result{}, &Error{Message: "urlFile must be a valid URL"}
+	}
+	if err := c.checkHost(ctx, parsed.Hostname()); err != nil {
+		return Result{}, err
+	}
+
+	resp, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+	if err != nil {
+		var checkErr *Error
+		if errors.As(err, &checkErr) {
+			return Result{}, checkErr
+		}
+		return Result{}, &Error{Message: "urlFile is not reachable"}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Result{}, &Error{Message: fmt.Sprintf("urlFile is not reachable: status %d", resp.StatusCode)}
+	}
+
+	result := Result{ContentLength: -1}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		result.ContentType = mediaType
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && size >= 0 {
+		result.ContentLength = size
+	}
+	result.FileName = fileName(resp.Header.Get("Content-Disposition"), resp.Request.URL, result.ContentType)
+	return result, nil
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, &Error{Message: "urlFile must be a valid URL"}
+	}
+	return c.client.Do(req)
+}
+
+// checkHost rejects a host when any of its addresses is not public, so the
+// answer cannot depend on which record is picked.
+func (c *Checker) checkHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if c.blocked(addr) {
+			return &Error{Message: "urlFile must not point to a private or local address"}
+		}
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &Error{Message: "urlFile must not point to a private or local address"}
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return &Error{Message: fmt.Sprintf("urlFile host %q cannot be resolved", host)}
+	}
+	for _, addr := range addrs {
+		if c.blocked(addr) {
+			return &Error{Message: "urlFile must not point to a private or local address"}
+		}
+	}
+	return nil
+}
+
+func (c *Checker) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return &Error{Message: "urlFile must not point to a private or local address"}
+	}
+	if c.blocked(addrPort.Addr()) {
+		return &Error{Message: "urlFile must not point to a private or local address"}
+	}
+	return nil
+}
+
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can map to any IPv4 address
+}
+
+// isBlocked reports whether addr is loopback, private, link-local (including
+// cloud metadata at 169.254.169.254), multicast or otherwise not public.
+func isBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// preferredExtensions covers the types mime.ExtensionsByType lists in an
+// unhelpful order, e.g. ".jfif" before ".jpg".
+var preferredExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"video/mp4":       ".mp4",
+	"audio/mpeg":      ".mp3",
+	"audio/ogg":       ".ogg",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+func fileName(disposition string, final *url.URL, contentType string) string {
+	if _, params, err := mime.ParseMediaType(disposition); err == nil {
+		if name := cleanName(params["filename"]); name != "" {
+			return name
+		}
+	}
+
+	name := cleanName(path.Base(final.Path))
+	if path.Ext(name) != "" {
+		return name
+	}
+	if name == "" {
+		name = "file"
+	}
+	if ext, ok := preferredExtensions[contentType]; ok {
+		return name + ext
+	}
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		return name + exts[0]
+	}
+	return name
+}
+
+// cleanName keeps the last path element of name and drops names that are
+// empty, dot-only or contain control characters.
+func cleanName(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || strings.Trim(name, ".") == "" || len(name) > 255 {
+		return ""
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return ""
+		}
+	}
+	return name
+}
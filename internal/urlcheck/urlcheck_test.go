@@ -0,0 +1,168 @@
+package urlcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackChecker lets tests reach httptest servers on 127.0.0.1.
+func loopbackChecker() *Checker {
+	c := New(2 * time.Second)
+	c.blocked = func(addr netip.Addr) bool {
+		return !addr.IsLoopback() && isBlocked(addr)
+	}
+	return c
+}
+
+func TestIsBlocked(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:10.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+		{"8.8.8.8", false},
+		{"2a00:1450:4010:c05::64", false},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.blocked, isBlocked(netip.MustParseAddr(tc.addr)), tc.addr)
+	}
+}
+
+func TestCheck_RejectsLocalTargetsWithoutConnecting(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	c := New(time.Second)
+	for _, raw := range []string{server.URL + "/a.png", "http://localhost/a.png", "http://169.254.169.254/latest/meta-data/"} {
+		_, err := c.Check(context.Background(), raw)
+		var checkErr *Error
+		require.ErrorAs(t, err, &checkErr, raw)
+		require.Contains(t, checkErr.Message, "private or local address", raw)
+	}
+	require.Zero(t, atomic.LoadInt32(&requests))
+}
+
+func TestCheck_RechecksAddressWhenConnecting(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The host check passes but the dial is refused, as with DNS rebinding.
+	var calls int32
+	c := New(time.Second)
+	c.blocked = func(netip.Addr) bool {
+		return atomic.AddInt32(&calls, 1) > 1
+	}
+
+	_, err := c.Check(context.Background(), server.URL+"/a.png")
+	var checkErr *Error
+	require.ErrorAs(t, err, &checkErr)
+	require.Contains(t, checkErr.Message, "private or local address")
+}
+
+func TestCheck_ReportsFileAndDerivesName(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			require.Equal(t, http.MethodHead, r.Method)
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Length", "1234")
+			w.Header().Set("Content-Disposition", `attachment; filename="../Q3 report.pdf"`)
+		case "/photo":
+			w.Header().Set("Content-Type", "image/jpeg; charset=binary")
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("hello"))
+		case "/moved":
+			http.Redirect(w, r, "/photo", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		path string
+		want Result
+	}{
+		{"/download", Result{ContentType: "application/pdf", ContentLength: 1234, FileName: "Q3 report.pdf"}},
+		{"/photo", Result{ContentType: "image/jpeg", ContentLength: -1, FileName: "photo.jpg"}},
+		{"/no-head", Result{ContentType: "text/plain", ContentLength: 5, FileName: "no-head.txt"}},
+		{"/moved", Result{ContentType: "image/jpeg", ContentLength: -1, FileName: "photo.jpg"}},
+	}
+
+	c := loopbackChecker()
+	for _, tc := range cases {
+		got, err := c.Check(context.Background(), server.URL+tc.path)
+		require.NoError(t, err, tc.path)
+		require.Equal(t, tc.want, got, tc.path)
+	}
+
+	_, err := c.Check(context.Background(), server.URL+"/missing")
+	require.EqualError(t, err, "urlFile is not reachable: status 404")
+}
+
+func TestCheck_LimitsRedirects(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := loopbackChecker().Check(context.Background(), server.URL+"/a")
+	require.EqualError(t, err, "urlFile redirects more than 3 times")
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		disposition, url, contentType, want string
+	}{
+		{`attachment; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82.pdf`, "https://example.com/x", "application/pdf", "отчёт.pdf"},
+		{"", "https://example.com/files/horse.png", "image/png", "horse.png"},
+		{"", "https://example.com/", "image/png", "file.png"},
+		{`attachment; filename=".."`, "https://example.com/get", "video/mp4", "get.mp4"},
+		{"", "https://example.com/get", "application/x-unknown", "get"},
+	}
+	for _, tc := range cases {
+		parsed, err := url.Parse(tc.url)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, fileName(tc.disposition, parsed, tc.contentType), tc.url)
+	}
+}